@@ -27,8 +27,14 @@ func main() {
 		"env", cfg.Env,
 		"addr", cfg.AnalysisServer.Address,
 		"storage_path", cfg.StoragePath,
+		"storage_url", cfg.Analysis.StorageBaseURL,
 	)
 
+	if lsh := cfg.Analysis.LSH; lsh.Bands <= 0 || lsh.Rows <= 0 || lsh.Bands*lsh.Rows > lsh.NumHashes {
+		slog.Error("invalid lsh config: bands*rows must not exceed num_hashes", "bands", lsh.Bands, "rows", lsh.Rows, "num_hashes", lsh.NumHashes)
+		os.Exit(1)
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
@@ -41,7 +47,30 @@ func main() {
 	slog.Info("connected to analysis db")
 
 	repo := analysis.NewRepository(dbAnalysis)
-	handler := analysis.NewHandler(repo)
+	storageClient := analysis.NewStorageClient(cfg.Analysis.StorageBaseURL, cfg.Analysis.StorageTimeout)
+	index := analysis.NewCandidateIndex(storageClient, repo, cfg.Analysis)
+	codeIndex := analysis.NewCodeIndex(storageClient, repo, cfg.Analysis)
+	detectors := analysis.NewRegistry()
+	for _, d := range []analysis.Detector{
+		analysis.NewShingleDetector(storageClient, index),
+		analysis.NewWinnowDetector(storageClient, repo, cfg.Analysis.Winnowing),
+		analysis.NewCodeDetector(repo, codeIndex, cfg.Analysis.Code),
+		analysis.NewASTDetector(storageClient, repo, codeIndex, cfg.Analysis.AST),
+	} {
+		if err := detectors.Register(d); err != nil {
+			slog.Error("failed to register detector", "error", err)
+			os.Exit(1)
+		}
+	}
+	queue := analysis.NewQueue(repo, storageClient, detectors, cfg.Analysis.Queue, cfg.Analysis.Winnowing)
+	handler := analysis.NewHandler(repo, queue, storageClient, detectors, cfg.Analysis.LSH)
+
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		slog.Info("starting analysis workers", "workers", cfg.Analysis.Queue.Workers)
+		queue.Run(ctx)
+	}()
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
@@ -58,8 +87,16 @@ func main() {
 	r.Route("/reports", func(r chi.Router) {
 		r.Post("/", handler.CreateReport)
 		r.Get("/{id}", handler.GetReport)
+		r.Get("/{id}/matches", handler.GetReportMatches)
 		r.Get("/work/{work_id}", handler.GetReportByWorkID)
+		r.Delete("/work/{work_id}", handler.HideReportsByWorkID)
 	})
+	r.Get("/tasks/{task_id}/lsh", handler.GetTaskLSH)
+	r.Put("/tasks/{task_id}/lsh", handler.UpdateTaskLSH)
+	r.Get("/tasks/{task_id}/detectors", handler.GetTaskDetectors)
+	r.Put("/tasks/{task_id}/detectors", handler.UpdateTaskDetectors)
+	r.Get("/tasks/{task_id}/clusters", handler.GetTaskClusters)
+	r.Get("/tasks/{task_id}/matrix", handler.GetTaskMatrix)
 
 	server := &http.Server{
 		Addr:    cfg.AnalysisServer.Address,
@@ -79,4 +116,5 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("server shutdown error", "err", err)
 	}
+	<-queueDone
 }
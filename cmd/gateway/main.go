@@ -38,9 +38,9 @@ func main() {
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Range", "If-None-Match", "If-Range", "X-Actor"},
+		ExposedHeaders:   []string{"Link", "Content-Disposition", "Content-Range", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -49,7 +49,39 @@ func main() {
 	r.Use(middleware.Recoverer)
 
 	r.Post("/works", gw.CreateWorkAndReport)
+	r.Get("/works", gw.ListWorksProxy)
+	r.Get("/works/latest", gw.GetLatestWorkProxy)
 	r.Get("/works/{id}", gw.GetWorkProxy)
+	r.Patch("/works/{id}", gw.UpdateWorkProxy)
+	r.Delete("/works/{id}", gw.DeleteWork)
+	r.Get("/works/{id}/file", gw.GetWorkFileProxy)
+	r.Get("/works/{id}/text", gw.WorkTextProxy)
+	r.Post("/works/{id}/text", gw.WorkTextProxy)
+	r.Get("/works/{id}/versions", gw.GetWorkVersions)
+	r.Get("/works/{id}/wordcloud.png", gw.WorkWordCloudProxy)
+	r.Get("/works/{id}/wordcloud.svg", gw.WorkWordCloudProxy)
+	r.Get("/reports/{id}/matches", gw.ReportMatchesProxy)
+	r.Get("/compare/{workA}/{workB}/view", gw.CompareView)
+
+	r.Post("/students", gw.StudentsProxy)
+	r.Get("/students", gw.StudentsProxy)
+	r.Get("/students/{id}", gw.StudentProxy)
+	r.Patch("/students/{id}", gw.StudentProxy)
+	r.Delete("/students/{id}", gw.StudentProxy)
+	r.Post("/tasks", gw.TasksProxy)
+	r.Get("/tasks", gw.TasksProxy)
+	r.Get("/tasks/{id}", gw.TaskProxy)
+	r.Patch("/tasks/{id}", gw.TaskProxy)
+	r.Delete("/tasks/{id}", gw.TaskProxy)
+	r.Get("/tasks/{id}/lsh", gw.TaskLSHProxy)
+	r.Put("/tasks/{id}/lsh", gw.TaskLSHProxy)
+	r.Get("/tasks/{id}/detectors", gw.TaskDetectorsProxy)
+	r.Put("/tasks/{id}/detectors", gw.TaskDetectorsProxy)
+	r.Get("/tasks/{id}/clusters", gw.TaskClustersProxy)
+	r.Get("/tasks/{id}/matrix", gw.TaskMatrixProxy)
+	r.Get("/tasks/{id}/templates", gw.TaskTemplatesProxy)
+	r.Post("/tasks/{id}/templates", gw.TaskTemplatesProxy)
+	r.Delete("/tasks/{id}/templates/{template_id}", gw.TaskTemplateProxy)
 
 	srv := &http.Server{
 		Addr:    cfg.Gateway.Address,
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -25,6 +26,8 @@ func main() {
 		"env", cfg.Env,
 		"addr", cfg.HTTPServer.Address,
 		"storage_path", cfg.StoragePath,
+		"blob_backend", cfg.BlobStorage.Backend,
+		"reject_late", cfg.Submissions.RejectLate,
 		"dsn", cfg.StorageDB.DSN,
 	)
 
@@ -39,8 +42,17 @@ func main() {
 	slog.Info("connected to storage db")
 	defer db.Close()
 
+	blobs, err := storage.OpenBlobStore(ctx, cfg.BlobStorage, cfg.StoragePath)
+	if err != nil {
+		slog.Error("failed to open blob store", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("blob store ready", "backend", cfg.BlobStorage.Backend)
+
 	repo := storage.NewRepository(db)
-	handler := storage.NewHandler(repo)
+	handler := storage.NewHandler(repo, blobs, cfg.Submissions)
+	go handler.RunExtraction(ctx)
+	go handler.RunBlobGC(ctx, cfg.BlobStorage)
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
@@ -48,15 +60,43 @@ func main() {
 	r.Use(middleware.Logger)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Actor"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 	r.Route("/works", func(rt chi.Router) {
 		rt.Post("/", handler.CreateWork)
+		rt.Get("/", handler.ListWorks)
+		rt.Get("/latest", handler.GetLatestWork)
 		rt.Get("/{id}", handler.GetWork)
+		rt.Patch("/{id}", handler.UpdateWork)
+		rt.Delete("/{id}", handler.DeleteWork)
+		rt.Get("/{id}/audit", handler.GetWorkAudit)
+		rt.Get("/{id}/versions", handler.GetWorkVersions)
+		rt.Get("/{id}/file", handler.GetWorkFile)
+		rt.Get("/{id}/text", handler.GetWorkText)
+		rt.Get("/{id}/wordcloud.png", handler.GetWorkWordCloudPNG)
+		rt.Get("/{id}/wordcloud.svg", handler.GetWorkWordCloudSVG)
+		rt.Post("/{id}/text", handler.ExtractWorkText)
+	})
+	r.Route("/students", func(rt chi.Router) {
+		rt.Post("/", handler.CreateStudent)
+		rt.Get("/", handler.ListStudents)
+		rt.Get("/{id}", handler.GetStudent)
+		rt.Patch("/{id}", handler.UpdateStudent)
+		rt.Delete("/{id}", handler.DeleteStudent)
+	})
+	r.Route("/tasks", func(rt chi.Router) {
+		rt.Post("/", handler.CreateTask)
+		rt.Get("/", handler.ListTasks)
+		rt.Get("/{id}", handler.GetTask)
+		rt.Patch("/{id}", handler.UpdateTask)
+		rt.Delete("/{id}", handler.DeleteTask)
+		rt.Get("/{id}/templates", handler.ListTemplates)
+		rt.Post("/{id}/templates", handler.UploadTemplates)
+		rt.Delete("/{id}/templates/{template_id}", handler.DeleteTemplate)
 	})
 
 	server := http.Server{
@@ -0,0 +1,6 @@
+\connect antiplag_storage;
+
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS file_name TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS file_size BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mime_type TEXT   NOT NULL DEFAULT '';
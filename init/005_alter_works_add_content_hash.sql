@@ -0,0 +1,6 @@
+\connect antiplag_storage;
+
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS works_content_hash_idx ON works (content_hash) WHERE content_hash <> '';
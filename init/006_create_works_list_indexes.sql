@@ -0,0 +1,8 @@
+\connect antiplag_storage;
+
+-- Индексы под выборки GET /works: фильтр по студенту/заданию + keyset-пагинация (sort key, id)
+CREATE INDEX IF NOT EXISTS works_uploaded_at_id_idx ON works (uploaded_at, id);
+CREATE INDEX IF NOT EXISTS works_task_uploaded_at_id_idx ON works (task, uploaded_at, id);
+CREATE INDEX IF NOT EXISTS works_student_uploaded_at_id_idx ON works (student, uploaded_at, id);
+CREATE INDEX IF NOT EXISTS works_student_id_idx ON works (student, id);
+CREATE INDEX IF NOT EXISTS works_task_id_idx ON works (task, id);
@@ -0,0 +1,15 @@
+\connect antiplag_storage;
+
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS works_audit (
+                                           id         SERIAL PRIMARY KEY,
+                                           work_id    INT       NOT NULL REFERENCES works (id),
+                                           action     TEXT      NOT NULL,
+                                           actor      TEXT      NOT NULL,
+                                           changes    JSONB     NOT NULL DEFAULT '{}',
+                                           created_at TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX IF NOT EXISTS works_audit_work_id_idx ON works_audit (work_id, created_at);
@@ -0,0 +1,7 @@
+\connect antiplag_analysis;
+
+-- Отчёты по удалённым работам скрываются, а не удаляются
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS reports_work_id_idx ON reports (work_id, created_at);
@@ -0,0 +1,13 @@
+\connect antiplag_storage;
+
+-- Пара (student, task) — одна логическая сдача, каждая загрузка — её очередная версия
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+UPDATE works w
+SET version = v.version
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY student, task ORDER BY uploaded_at, id) AS version
+      FROM works) v
+WHERE w.id = v.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS works_student_task_version_uidx ON works (student, task, version);
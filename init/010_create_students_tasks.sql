@@ -0,0 +1,77 @@
+\connect antiplag_storage;
+
+-- Студенты и задания — отдельные сущности, works ссылается на них по id
+CREATE TABLE IF NOT EXISTS students (
+                                        id         SERIAL PRIMARY KEY,
+                                        name       TEXT      NOT NULL,
+                                        email      TEXT      NOT NULL DEFAULT '',
+                                        created_at TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS students_email_uidx ON students (LOWER(email)) WHERE email <> '';
+
+CREATE TABLE IF NOT EXISTS tasks (
+                                     id          SERIAL PRIMARY KEY,
+                                     title       TEXT      NOT NULL,
+                                     description TEXT      NOT NULL DEFAULT '',
+                                     created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_title_uidx ON tasks (LOWER(title));
+
+-- Перенос существующих строк: каждое различное значение student/task становится записью.
+-- Имена студентов сравниваются без учёта регистра и лишних пробелов («Иванов  Иван» и «иванов иван» —
+-- один студент); запись получает написание из самой ранней работы с пробелами, схлопнутыми до одного.
+-- Задания так же сравниваются без учёта регистра и получают название из самой ранней работы
+CREATE FUNCTION pg_temp.student_name(name TEXT) RETURNS TEXT AS $$
+    SELECT BTRIM(REGEXP_REPLACE(name, '\s+', ' ', 'g'))
+$$ LANGUAGE sql IMMUTABLE;
+
+INSERT INTO students (name)
+SELECT DISTINCT ON (LOWER(pg_temp.student_name(student))) pg_temp.student_name(student) FROM works
+WHERE NOT EXISTS (SELECT 1 FROM students s WHERE LOWER(s.name) = LOWER(pg_temp.student_name(works.student)))
+ORDER BY LOWER(pg_temp.student_name(student)), uploaded_at, id;
+
+INSERT INTO tasks (title)
+SELECT DISTINCT ON (LOWER(TRIM(task))) TRIM(task) FROM works
+WHERE NOT EXISTS (SELECT 1 FROM tasks t WHERE LOWER(t.title) = LOWER(TRIM(works.task)))
+ORDER BY LOWER(TRIM(task)), uploaded_at, id;
+
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS student_id INT REFERENCES students (id),
+    ADD COLUMN IF NOT EXISTS task_id    INT REFERENCES tasks (id);
+
+UPDATE works w
+SET student_id = s.id
+FROM students s
+WHERE w.student_id IS NULL AND LOWER(s.name) = LOWER(pg_temp.student_name(w.student));
+
+UPDATE works w
+SET task_id = t.id
+FROM tasks t
+WHERE w.task_id IS NULL AND LOWER(t.title) = LOWER(TRIM(w.task));
+
+ALTER TABLE works
+    ALTER COLUMN student_id SET NOT NULL,
+    ALTER COLUMN task_id SET NOT NULL;
+
+-- Старые текстовые колонки и индексы по ним больше не нужны
+DROP INDEX IF EXISTS works_task_uploaded_at_id_idx;
+DROP INDEX IF EXISTS works_student_uploaded_at_id_idx;
+DROP INDEX IF EXISTS works_student_id_idx;
+DROP INDEX IF EXISTS works_task_id_idx;
+DROP INDEX IF EXISTS works_student_task_version_uidx;
+
+-- У слившихся написаний одного студента версии могли повториться: нумеруем заново по времени загрузки
+UPDATE works w
+SET version = v.version
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY student_id, task_id ORDER BY uploaded_at, id) AS version FROM works) v
+WHERE w.id = v.id AND w.version <> v.version;
+
+ALTER TABLE works
+    DROP COLUMN IF EXISTS student,
+    DROP COLUMN IF EXISTS task;
+
+CREATE INDEX IF NOT EXISTS works_task_ref_uploaded_at_id_idx ON works (task_id, uploaded_at, id);
+CREATE INDEX IF NOT EXISTS works_student_ref_uploaded_at_id_idx ON works (student_id, uploaded_at, id);
+CREATE UNIQUE INDEX IF NOT EXISTS works_student_task_version_uidx ON works (student_id, task_id, version);
@@ -0,0 +1,12 @@
+\connect antiplag_storage;
+
+-- Дедлайн задания хранится как местное время в часовом поясе задания
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS deadline             TIMESTAMP NULL,
+    ADD COLUMN IF NOT EXISTS grace_period_seconds BIGINT    NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS timezone             TEXT      NOT NULL DEFAULT 'UTC';
+
+-- Опоздание вычисляется при сохранении работы
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS is_late         BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS late_by_seconds BIGINT  NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+\connect antiplag_storage;
+
+-- Результат извлечения текста: статус у работы, сам текст — в отдельной таблице
+ALTER TABLE works
+    ADD COLUMN IF NOT EXISTS text_status TEXT NOT NULL DEFAULT 'pending',
+    ADD COLUMN IF NOT EXISTS text_error  TEXT NOT NULL DEFAULT '';
+
+-- У работ без загруженного файла извлекать нечего
+UPDATE works SET text_status = 'skipped', text_error = 'work has no uploaded file'
+WHERE content_hash = '' AND text_status = 'pending';
+
+CREATE TABLE IF NOT EXISTS work_texts (
+                                          work_id      INT       PRIMARY KEY REFERENCES works (id),
+                                          content      TEXT      NOT NULL DEFAULT '',
+                                          extracted_at TIMESTAMP NOT NULL DEFAULT NOW()
+    );
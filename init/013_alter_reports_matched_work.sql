@@ -0,0 +1,5 @@
+\connect antiplag_analysis;
+
+-- Работа, с которой найдено наибольшее совпадение
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS matched_work_id INT NULL;
@@ -0,0 +1,13 @@
+\connect antiplag_analysis;
+
+-- Очередь проверок: отчёт создаётся в статусе pending, воркер переводит его в processing,
+-- затем в done или failed (текст ошибки — в error)
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS error       TEXT      NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS attempts    INT       NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS started_at  TIMESTAMP NULL,
+    ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS reports_queue_idx
+    ON reports (status, id)
+    WHERE hidden_at IS NULL AND status IN ('pending', 'processing');
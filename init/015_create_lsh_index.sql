@@ -0,0 +1,33 @@
+\connect antiplag_analysis;
+
+-- MinHash-сигнатуры текстов работ (параметры, с которыми они посчитаны, — для переиндексации)
+CREATE TABLE IF NOT EXISTS work_signatures (
+                                               work_id      INT       PRIMARY KEY,
+                                               task_id      INT       NOT NULL,
+                                               student_id   INT       NOT NULL,
+                                               shingle_size INT       NOT NULL,
+                                               num_hashes   INT       NOT NULL,
+                                               signature    BIGINT[]  NOT NULL,
+                                               created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX IF NOT EXISTS work_signatures_task_idx ON work_signatures (task_id);
+
+-- LSH-индекс: ключ корзины для каждой полосы сигнатуры
+CREATE TABLE IF NOT EXISTS lsh_buckets (
+                                           task_id INT    NOT NULL,
+                                           band    INT    NOT NULL,
+                                           bucket  BIGINT NOT NULL,
+                                           work_id INT    NOT NULL,
+                                           PRIMARY KEY (task_id, band, bucket, work_id)
+    );
+
+CREATE INDEX IF NOT EXISTS lsh_buckets_work_idx ON lsh_buckets (work_id);
+
+-- Настройки полос и строк для отдельных заданий (по умолчанию — из конфига)
+CREATE TABLE IF NOT EXISTS task_lsh_settings (
+                                                 task_id    INT       PRIMARY KEY,
+                                                 bands      INT       NOT NULL CHECK (bands > 0),
+                                                 rows       INT       NOT NULL CHECK (rows > 0),
+                                                 updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+    );
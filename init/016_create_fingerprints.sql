@@ -0,0 +1,36 @@
+\connect antiplag_analysis;
+
+-- Отпечатки (winnowing) работ: параметры, с которыми они посчитаны
+CREATE TABLE IF NOT EXISTS fingerprint_sets (
+                                                work_id     INT       PRIMARY KEY,
+                                                task_id     INT       NOT NULL,
+                                                student_id  INT       NOT NULL,
+                                                k           INT       NOT NULL,
+                                                window_size INT       NOT NULL,
+                                                text_length INT       NOT NULL,
+                                                created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+-- Хеши k-грамм с позициями (в символах) в извлечённом тексте
+CREATE TABLE IF NOT EXISTS work_fingerprints (
+                                                 work_id   INT    NOT NULL,
+                                                 task_id   INT    NOT NULL,
+                                                 hash      BIGINT NOT NULL,
+                                                 start_pos INT    NOT NULL,
+                                                 end_pos   INT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS work_fingerprints_task_hash_idx ON work_fingerprints (task_id, hash);
+CREATE INDEX IF NOT EXISTS work_fingerprints_work_idx ON work_fingerprints (work_id);
+
+-- Совпавшие фрагменты отчёта: позиции в проверяемой работе и в работе matched_work_id
+CREATE TABLE IF NOT EXISTS report_fragments (
+                                                report_id       INT NOT NULL REFERENCES reports (id),
+                                                matched_work_id INT NOT NULL,
+                                                start_pos       INT NOT NULL,
+                                                end_pos         INT NOT NULL,
+                                                matched_start   INT NOT NULL,
+                                                matched_end     INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS report_fragments_report_idx ON report_fragments (report_id);
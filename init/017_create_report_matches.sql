@@ -0,0 +1,18 @@
+\connect antiplag_analysis;
+
+-- Совпадения отчёта: с какой работой, насколько похоже, сколько слов совпало
+CREATE TABLE IF NOT EXISTS report_matches (
+                                              report_id       INT              NOT NULL REFERENCES reports (id),
+                                              matched_work_id INT              NOT NULL,
+                                              student_id      INT              NOT NULL DEFAULT 0,
+                                              student         TEXT             NOT NULL DEFAULT '',
+                                              similarity      DOUBLE PRECISION NOT NULL,
+                                              matched_tokens  INT              NOT NULL DEFAULT 0,
+                                              PRIMARY KEY (report_id, matched_work_id)
+    );
+
+CREATE INDEX IF NOT EXISTS report_matches_similarity_idx ON report_matches (report_id, similarity DESC);
+
+-- Фрагменты теперь читаются по совпадению
+DROP INDEX IF EXISTS report_fragments_report_idx;
+CREATE INDEX IF NOT EXISTS report_fragments_match_idx ON report_fragments (report_id, matched_work_id);
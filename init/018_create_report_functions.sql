@@ -0,0 +1,16 @@
+\connect antiplag_analysis;
+
+-- Совпавшие по структуре синтаксического дерева функции Go: имена, сходство и позиции в символах текста
+CREATE TABLE IF NOT EXISTS report_functions (
+                                                report_id        INT              NOT NULL REFERENCES reports (id),
+                                                matched_work_id  INT              NOT NULL,
+                                                function_name    TEXT             NOT NULL,
+                                                matched_function TEXT             NOT NULL,
+                                                similarity       DOUBLE PRECISION NOT NULL,
+                                                start_pos        INT              NOT NULL,
+                                                end_pos          INT              NOT NULL,
+                                                matched_start    INT              NOT NULL,
+                                                matched_end      INT              NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS report_functions_match_idx ON report_functions (report_id, matched_work_id);
@@ -0,0 +1,16 @@
+\connect antiplag_analysis;
+
+-- Какие детекторы запускаются для задания, их веса и пороги (по умолчанию — все, вес 1, порог 0)
+CREATE TABLE IF NOT EXISTS task_detectors (
+                                              task_id    INT              NOT NULL,
+                                              detector   TEXT             NOT NULL,
+                                              enabled    BOOLEAN          NOT NULL DEFAULT TRUE,
+                                              weight     DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (weight >= 0),
+                                              threshold  DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (threshold BETWEEN 0 AND 100),
+                                              updated_at TIMESTAMP        NOT NULL DEFAULT NOW(),
+                                              PRIMARY KEY (task_id, detector)
+    );
+
+-- Оценки отдельных детекторов: для отчёта в целом и для каждого совпадения
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS scores JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE report_matches ADD COLUMN IF NOT EXISTS scores JSONB NOT NULL DEFAULT '{}';
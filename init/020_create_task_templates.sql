@@ -0,0 +1,17 @@
+\connect antiplag_storage;
+
+-- Шаблоны задания (заготовка кода, текст условия): их содержимое не считается списыванием
+CREATE TABLE IF NOT EXISTS task_templates (
+                                              id           SERIAL PRIMARY KEY,
+                                              task_id      INT       NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+                                              file_name    TEXT      NOT NULL,
+                                              file_size    BIGINT    NOT NULL DEFAULT 0,
+                                              mime_type    TEXT      NOT NULL DEFAULT '',
+                                              content_hash TEXT      NOT NULL,
+                                              text_status  TEXT      NOT NULL,
+                                              text_error   TEXT      NOT NULL DEFAULT '',
+                                              content      TEXT      NOT NULL DEFAULT '',
+                                              created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX IF NOT EXISTS task_templates_task_idx ON task_templates (task_id);
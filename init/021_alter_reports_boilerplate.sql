@@ -0,0 +1,6 @@
+\connect antiplag_analysis;
+
+-- Сколько текста работы занимает содержимое шаблонов задания
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS boilerplate_chars INT              NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS boilerplate_share DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+\connect antiplag_analysis;
+
+-- Цитаты, блочные цитаты и список литературы, исключённые из сравнения текста
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS citations      JSONB            NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS citation_chars INT              NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS citation_share DOUBLE PRECISION NOT NULL DEFAULT 0;
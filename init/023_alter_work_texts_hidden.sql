@@ -0,0 +1,6 @@
+\connect antiplag_storage;
+
+-- Текст, скрытый в файле от читателя (белый, невидимый или микроскопический),
+-- хранится отдельно и в сравнении не участвует
+ALTER TABLE work_texts
+    ADD COLUMN IF NOT EXISTS hidden_content TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+\connect antiplag_analysis;
+
+-- Признаки обфускации: подмена букв похожими из другого алфавита,
+-- невидимые символы, скрытый текст
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS findings JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,33 @@
+\connect antiplag_analysis;
+
+-- Потоки токенов исходного кода работ: коды классов токенов и их позиции (в символах) в извлечённом тексте.
+-- Сохраняются при проверке, чтобы не загружать и не разбирать заново тексты работ-кандидатов.
+-- signature — MinHash-сигнатура шинглов из shingle_size токенов, по ней работа попадает в индекс кода
+CREATE TABLE IF NOT EXISTS work_code_streams (
+                                                 work_id      INT       PRIMARY KEY,
+                                                 task_id      INT       NOT NULL,
+                                                 student_id   INT       NOT NULL,
+                                                 language     TEXT      NOT NULL,
+                                                 codes        BIGINT[]  NOT NULL,
+                                                 starts       INT[]     NOT NULL,
+                                                 ends         INT[]     NOT NULL,
+                                                 shingle_size INT       NOT NULL DEFAULT 0,
+                                                 num_hashes   INT       NOT NULL DEFAULT 0,
+                                                 signature    BIGINT[]  NOT NULL DEFAULT '{}',
+                                                 created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS work_code_streams_task_idx ON work_code_streams (task_id, language);
+
+-- LSH-индекс кода: корзины полос сигнатур потоков токенов. Классы токенов не меняются при переименовании
+-- и переформатировании, поэтому такая копия попадает в корзины оригинала, даже если слова в ней другие
+CREATE TABLE IF NOT EXISTS code_lsh_buckets (
+                                                task_id  INT    NOT NULL,
+                                                language TEXT   NOT NULL,
+                                                band     INT    NOT NULL,
+                                                bucket   BIGINT NOT NULL,
+                                                work_id  INT    NOT NULL,
+                                                PRIMARY KEY (task_id, language, band, bucket, work_id)
+    );
+
+CREATE INDEX IF NOT EXISTS code_lsh_buckets_work_idx ON code_lsh_buckets (work_id);
@@ -0,0 +1,13 @@
+\connect antiplag_analysis;
+
+-- Формы синтаксических деревьев функций Go по работам: хеши и размеры поддеревьев каждой функции.
+-- Сохраняются при проверке, чтобы не загружать и не разбирать заново тексты работ-кандидатов;
+-- parse_error — ошибка разбора, если исходный код не разбирается
+CREATE TABLE IF NOT EXISTS work_go_shapes (
+                                              work_id     INT       PRIMARY KEY,
+                                              task_id     INT       NOT NULL,
+                                              student_id  INT       NOT NULL,
+                                              parse_error TEXT      NOT NULL DEFAULT '',
+                                              shapes      JSONB     NOT NULL DEFAULT '[]',
+                                              created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+\connect antiplag_storage;
+
+-- Индекс для фонового извлечения текста: работы, текст которых ещё не извлечён
+CREATE INDEX IF NOT EXISTS works_text_pending_idx ON works (id) WHERE text_status = 'pending' AND deleted_at IS NULL;
@@ -0,0 +1,489 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"unicode/utf8"
+
+	"HW_KPO3/internal/config"
+)
+
+const (
+	DefaultASTMinNodes  = 15
+	DefaultASTThreshold = 60
+
+	// minTemplateSubtree is the smallest template subtree that is excluded
+	// from works. Smaller ones, like `i++`, occur in any code.
+	minTemplateSubtree = 8
+)
+
+// predeclared names keep their identity in shapes: a call to len or a
+// comparison with nil says something about structure, a variable name does
+// not.
+var predeclared = keywordSet(`nil true false append cap clear close complex copy delete imag len
+	make max min new panic print println real recover`)
+
+// FuncShape is the normalized syntax tree of one Go function: the multiset of
+// hashes of its subtrees, where names and literal values are erased and
+// equivalent constructs share a kind. Start/End is the span of the function
+// in the source, in characters.
+type FuncShape struct {
+	Name     string
+	Start    int
+	End      int
+	Nodes    int
+	subtrees map[uint64]int
+	sizes    map[uint64]int
+	total    int
+}
+
+// GoFuncShapes parses Go source and returns the shapes of its functions and
+// methods.
+func GoFuncShapes(src string) ([]FuncShape, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var shapes []FuncShape
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		s := FuncShape{
+			Name:     funcName(fn),
+			Start:    utf8.RuneCountInString(src[:fset.Position(fn.Pos()).Offset]),
+			End:      utf8.RuneCountInString(src[:fset.Position(fn.End()).Offset]),
+			subtrees: map[uint64]int{},
+			sizes:    map[uint64]int{},
+		}
+		_, s.Nodes = s.add(fn.Type)
+		_, body := s.add(fn.Body)
+		s.Nodes += body
+		shapes = append(shapes, s)
+	}
+	return shapes, nil
+}
+
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	for {
+		switch t := recv.(type) {
+		case *ast.StarExpr:
+			recv = t.X
+			continue
+		case *ast.IndexExpr:
+			recv = t.X
+			continue
+		case *ast.IndexListExpr:
+			recv = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
+
+// add hashes the subtree of n bottom-up, records every subtree of more than
+// one node and returns the hash and the size of n's subtree.
+func (s *FuncShape) add(n ast.Node) (uint64, int) {
+	kids := childNodes(n)
+	if len(kids) == 1 && transparent(n) {
+		return s.add(kids[0])
+	}
+	hashes := make([]uint64, len(kids))
+	size := 1
+	for i, c := range kids {
+		var kidSize int
+		hashes[i], kidSize = s.add(c)
+		size += kidSize
+	}
+	kind, unordered := nodeKind(n)
+	if unordered {
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(kind))
+	var buf [8]byte
+	for _, c := range hashes {
+		for i := range buf {
+			buf[i] = byte(c >> (8 * i))
+		}
+		_, _ = h.Write(buf[:])
+	}
+	sum := h.Sum64()
+	if size > 1 {
+		s.subtrees[sum]++
+		s.sizes[sum] = size
+		s.total++
+	}
+	return sum, size
+}
+
+func childNodes(n ast.Node) []ast.Node {
+	var kids []ast.Node
+	ast.Inspect(n, func(c ast.Node) bool {
+		if c == nil {
+			return false
+		}
+		if c == n {
+			return true
+		}
+		kids = append(kids, c)
+		return false
+	})
+	return kids
+}
+
+// transparent nodes only wrap their single child, so `(x)`, `var x = y` and
+// `x := y` all come out alike.
+func transparent(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.ParenExpr, *ast.ExprStmt, *ast.DeclStmt, *ast.GenDecl:
+		return true
+	}
+	return false
+}
+
+// nodeKind names the node without its names and values and tells whether
+// the order of its children is irrelevant. Constructs that are rewritten
+// into one another to hide copying share a kind: all loops, all branches,
+// all assignments, comparisons regardless of direction.
+func nodeKind(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.Ident:
+		if predeclared[n.Name] {
+			return n.Name, false
+		}
+		return tokIdent, false
+	case *ast.BasicLit:
+		return "LIT", false
+	case *ast.ForStmt, *ast.RangeStmt:
+		return "Loop", false
+	case *ast.IfStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return "Branch", false
+	case *ast.AssignStmt, *ast.IncDecStmt, *ast.ValueSpec:
+		return "Assign", false
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+			return "Cmp", true
+		case token.LAND, token.LOR, token.ADD, token.MUL, token.AND, token.OR, token.XOR:
+			return n.Op.String(), true
+		}
+		return n.Op.String(), false
+	}
+	return fmt.Sprintf("%T", n), false
+}
+
+// subtract removes the subtrees that also occur in template, so that code a
+// function shares with the task's skeleton is not compared. It returns the
+// number of subtrees removed.
+func (s *FuncShape) subtract(template map[uint64]bool) int {
+	removed := 0
+	for h, n := range s.subtrees {
+		if template[h] {
+			removed += n
+			delete(s.subtrees, h)
+		}
+	}
+	s.total -= removed
+	return removed
+}
+
+// ShapeSimilarity is the Dice coefficient of the subtree multisets of two
+// functions, in percent.
+func ShapeSimilarity(a, b *FuncShape) float64 {
+	if a.total+b.total == 0 {
+		return 0
+	}
+	common := 0
+	for h, n := range a.subtrees {
+		common += min(n, b.subtrees[h])
+	}
+	return 2 * float64(common) / float64(a.total+b.total) * 100
+}
+
+// FuncMatch is a pair of functions with matching shapes: Function in the
+// checked work and MatchedFunction in the other one, with their spans in
+// characters of the extracted text.
+type FuncMatch struct {
+	Function        string  `json:"function"`
+	MatchedFunction string  `json:"matched_function"`
+	Similarity      float64 `json:"similarity"`
+	Start           int     `json:"start"`
+	End             int     `json:"end"`
+	MatchedStart    int     `json:"matched_start"`
+	MatchedEnd      int     `json:"matched_end"`
+}
+
+// ASTMatch is the function pairs of the checked work with one other work.
+// Similarity is the share of the checked work's function nodes that lie in
+// matched functions, each weighted by how closely it matches, in percent.
+type ASTMatch struct {
+	WorkID     int64
+	Similarity float64
+	Functions  []FuncMatch
+}
+
+// ASTResult is the function pairs with the Go candidates. Excluded
+// is the share of the work's subtrees that also occur in the task's Go
+// templates, in percent.
+type ASTResult struct {
+	Go        bool
+	ParseErr  string
+	Functions int
+	Compared  int
+	Excluded  float64
+	Matches   []ASTMatch
+}
+
+// ASTDetector compares Go sources by the shape of their syntax trees. Unlike
+// the token stream the shapes do not depend on the order of functions or on
+// how statements are split, so it also catches code that was refactored on
+// the surface. Candidates come from the code index shared with the code
+// detector. Shapes are stored per work, so a counterpart is parsed once
+// rather than on every check.
+type ASTDetector struct {
+	storage   *StorageClient
+	repo      *Repository
+	index     *CodeIndex
+	minNodes  int
+	threshold float64
+}
+
+func NewASTDetector(storage *StorageClient, repo *Repository, index *CodeIndex, cfg config.AST) *ASTDetector {
+	d := &ASTDetector{storage: storage, repo: repo, index: index, minNodes: cfg.MinNodes, threshold: cfg.Threshold}
+	if d.minNodes <= 0 {
+		d.minNodes = DefaultASTMinNodes
+	}
+	if d.threshold <= 0 {
+		d.threshold = DefaultASTThreshold
+	}
+	return d
+}
+
+// Check pairs the functions of a Go work with those of the Go candidates
+// from the code index. Other works give an empty result, and so
+// does source that does not parse: the token comparison still covers it.
+func (d *ASTDetector) Check(ctx context.Context, sub *Submission) (*ASTResult, error) {
+	result := &ASTResult{Go: DetectLanguage(sub.Work.FileName) == LangGo}
+	if !result.Go {
+		return result, nil
+	}
+	template := map[uint64]bool{}
+	for _, text := range sub.templateTexts(LangGo) {
+		// A template that does not parse simply excludes nothing.
+		shapes, _ := GoFuncShapes(text)
+		for _, s := range shapes {
+			for h, size := range s.sizes {
+				if size >= minTemplateSubtree {
+					template[h] = true
+				}
+			}
+		}
+	}
+
+	parsed, err := d.parse(ctx, sub.Work, sub.Text)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.ParseErr != "" {
+		result.ParseErr = parsed.ParseErr
+		return result, nil
+	}
+	own, excluded := d.shapes(parsed.Shapes, template)
+	result.Functions, result.Excluded = len(own), excluded
+	if len(own) == 0 {
+		return result, nil
+	}
+
+	others, err := d.candidateShapes(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range others {
+		if other.ParseErr != "" {
+			continue
+		}
+		theirs, _ := d.shapes(other.Shapes, template)
+		result.Compared++
+		if match := d.pair(own, theirs); len(match.Functions) > 0 {
+			match.WorkID = other.WorkID
+			result.Matches = append(result.Matches, match)
+		}
+	}
+	return result, nil
+}
+
+// parse takes the shapes of the work's functions and stores them.
+func (d *ASTDetector) parse(ctx context.Context, work *Work, src string) (*GoShapes, error) {
+	parsed := &GoShapes{WorkID: work.ID}
+	shapes, err := GoFuncShapes(src)
+	if err != nil {
+		parsed.ParseErr = err.Error()
+	}
+	parsed.Shapes = shapes
+	if err := d.repo.SaveGoShapes(ctx, work, parsed.Shapes, parsed.ParseErr); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// candidateShapes returns the function shapes of the Go candidates, ordered
+// by work. Candidates without stored shapes are parsed and stored now.
+func (d *ASTDetector) candidateShapes(ctx context.Context, sub *Submission) ([]*GoShapes, error) {
+	_, ids, err := d.index.Candidates(ctx, sub, LangGo)
+	if err != nil {
+		return nil, err
+	}
+	works := map[int64]*Work{}
+	for i := range sub.TaskWorks {
+		works[sub.TaskWorks[i].ID] = &sub.TaskWorks[i]
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	stored, err := d.repo.GetGoShapes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*GoShapes, 0, len(ids))
+	for _, id := range ids {
+		parsed, ok := stored[id]
+		if !ok {
+			text, err := d.storage.GetWorkText(ctx, id)
+			if err != nil {
+				slog.Warn("failed to get text of work to compare", "work_id", id, "err", err)
+				continue
+			}
+			if parsed, err = d.parse(ctx, works[id], text.Text); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+// shapes returns the functions big enough to compare, with template subtrees
+// removed, and the share of subtrees removed in percent. Functions that are
+// entirely template code are dropped. The shapes passed in are modified.
+func (d *ASTDetector) shapes(all []FuncShape, template map[uint64]bool) ([]FuncShape, float64) {
+	var shapes []FuncShape
+	total, removed := 0, 0
+	for _, s := range all {
+		if s.Nodes < d.minNodes {
+			continue
+		}
+		total += s.total
+		removed += s.subtract(template)
+		if s.total > 0 {
+			shapes = append(shapes, s)
+		}
+	}
+	if total == 0 {
+		return shapes, 0
+	}
+	return shapes, float64(removed) / float64(total) * 100
+}
+
+// pair matches functions one to one, most similar pairs first, and keeps
+// pairs at or above the threshold.
+func (d *ASTDetector) pair(own, theirs []FuncShape) ASTMatch {
+	type candidate struct {
+		i, j int
+		sim  float64
+	}
+	var candidates []candidate
+	for i := range own {
+		for j := range theirs {
+			if sim := ShapeSimilarity(&own[i], &theirs[j]); sim >= d.threshold {
+				candidates = append(candidates, candidate{i, j, sim})
+			}
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].sim != candidates[b].sim {
+			return candidates[a].sim > candidates[b].sim
+		}
+		if candidates[a].i != candidates[b].i {
+			return candidates[a].i < candidates[b].i
+		}
+		return candidates[a].j < candidates[b].j
+	})
+
+	var match ASTMatch
+	usedOwn, usedTheirs := map[int]bool{}, map[int]bool{}
+	matched := 0.0
+	for _, c := range candidates {
+		if usedOwn[c.i] || usedTheirs[c.j] {
+			continue
+		}
+		usedOwn[c.i], usedTheirs[c.j] = true, true
+		a, b := own[c.i], theirs[c.j]
+		match.Functions = append(match.Functions, FuncMatch{
+			Function:        a.Name,
+			MatchedFunction: b.Name,
+			Similarity:      c.sim,
+			Start:           a.Start,
+			End:             a.End,
+			MatchedStart:    b.Start,
+			MatchedEnd:      b.End,
+		})
+		matched += float64(a.Nodes) * c.sim
+	}
+	total := 0
+	for _, s := range own {
+		total += s.Nodes
+	}
+	match.Similarity = matched / float64(total)
+	sort.Slice(match.Functions, func(i, j int) bool { return match.Functions[i].Start < match.Functions[j].Start })
+	return match
+}
+
+func (d *ASTDetector) Name() string {
+	return DetectorAST
+}
+
+// Detect leaves source that does not parse out of the combined score rather
+// than scoring it zero.
+func (d *ASTDetector) Detect(ctx context.Context, sub *Submission) (*Detection, error) {
+	result, err := d.Check(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	detection := &Detection{Applies: result.Go && result.ParseErr == "", Details: result.Details(), Excluded: result.Excluded}
+	for _, m := range result.Matches {
+		detection.Matches = append(detection.Matches, Evidence{WorkID: m.WorkID, Similarity: m.Similarity, Functions: m.Functions})
+	}
+	return detection, nil
+}
+
+func (r *ASTResult) Details() string {
+	switch {
+	case !r.Go:
+		return ""
+	case r.ParseErr != "":
+		return "Go source does not parse, structure not compared"
+	}
+	best, pairs := 0.0, 0
+	for _, m := range r.Matches {
+		best = max(best, m.Similarity)
+		pairs += len(m.Functions)
+	}
+	return fmt.Sprintf("%d Go functions, compared with %d works, %d matching function pairs, best structural similarity %.1f%%",
+		r.Functions, r.Compared, pairs, best)
+}
@@ -0,0 +1,325 @@
+package analysis
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func mustShapes(t *testing.T, src string) []FuncShape {
+	t.Helper()
+	shapes, err := GoFuncShapes(src)
+	if err != nil {
+		t.Fatalf("GoFuncShapes: %v", err)
+	}
+	return shapes
+}
+
+// onlyShape returns the shape of the single function in src.
+func onlyShape(t *testing.T, src string) FuncShape {
+	t.Helper()
+	shapes := mustShapes(t, src)
+	if len(shapes) != 1 {
+		t.Fatalf("got %d functions, want 1", len(shapes))
+	}
+	return shapes[0]
+}
+
+func TestGoFuncShapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty file", src: "", wantErr: true},
+		{name: "syntax error", src: "package p\nfunc f( {", wantErr: true},
+		{name: "no functions", src: "package p\nvar x = 1\ntype T struct{}", want: nil},
+		{name: "declaration without body", src: "package p\nfunc asm(x int) int", want: nil},
+		{
+			name: "functions and methods in order",
+			src:  "package p\nfunc a() {}\ntype T struct{}\nfunc (T) b() {}\nfunc (t *T) c() {}",
+			want: []string{"a", "T.b", "T.c"},
+		},
+		{
+			name: "generic receivers",
+			src:  "package p\ntype L[T any] []T\ntype M[K comparable, V any] map[K]V\nfunc (l *L[T]) Len() int { return len(l) }\nfunc (m M[K, V]) Get(k K) V { return m[k] }",
+			want: []string{"L.Len", "M.Get"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shapes, err := GoFuncShapes(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GoFuncShapes succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GoFuncShapes: %v", err)
+			}
+			if len(shapes) != len(tt.want) {
+				t.Fatalf("got %d functions, want %v", len(shapes), tt.want)
+			}
+			for i, s := range shapes {
+				if s.Name != tt.want[i] {
+					t.Errorf("function %d = %q, want %q", i, s.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGoFuncShapesSpansCountRunes(t *testing.T) {
+	const fn = "func привет() { println(\"мир\") }"
+	src := "package p\n\n// Комментарий с кириллицей и эмодзи 🙂\n" + fn + "\n"
+	s := onlyShape(t, src)
+	runes := []rune(src)
+	if got := string(runes[s.Start:s.End]); got != fn {
+		t.Errorf("span [%d, %d) = %q, want %q", s.Start, s.End, got, fn)
+	}
+	if s.End > utf8.RuneCountInString(src) {
+		t.Errorf("End %d is past the text", s.End)
+	}
+}
+
+func TestShapeSimilarity(t *testing.T) {
+	const sum = `package p
+func sum(xs []int) int {
+	total := 0
+	for i := 0; i < len(xs); i++ {
+		if xs[i] > 0 {
+			total += xs[i]
+		}
+	}
+	return total
+}`
+
+	tests := []struct {
+		name    string
+		a, b    string
+		want    float64
+		atLeast bool
+	}{
+		{
+			name: "renamed identifiers and literals",
+			a:    sum,
+			b: `package p
+func add(values []int) int {
+	acc := 10
+	for j := 1; j < len(values); j++ {
+		if values[j] > 5 {
+			acc += values[j]
+		}
+	}
+	return acc
+}`,
+			want: 100,
+		},
+		{
+			name: "mirrored comparison and var declaration",
+			a: `package p
+func f(a, b int) int {
+	x := a
+	if a < b {
+		x = b
+	}
+	return x
+}`,
+			b: `package p
+func g(p, q int) int {
+	var y = p
+	if q > p {
+		y = q
+	}
+	return y
+}`,
+			want: 100,
+		},
+		{
+			name: "extra parentheses",
+			a:    "package p\nfunc f(a, b int) bool { return a+b > 2 }",
+			b:    "package p\nfunc f(a, b int) bool { return ((a + b) > 2) }",
+			want: 100,
+		},
+		{
+			name: "unrelated bodies",
+			a:    sum,
+			b: `package p
+func greet(name string) {
+	println("hello", name)
+}`,
+			want: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := onlyShape(t, tt.a), onlyShape(t, tt.b)
+			got := ShapeSimilarity(&a, &b)
+			if tt.want == 100 && got != 100 {
+				t.Errorf("ShapeSimilarity = %.1f, want 100", got)
+			}
+			if tt.want < 100 && got >= tt.want {
+				t.Errorf("ShapeSimilarity = %.1f, want below %.0f", got, tt.want)
+			}
+			if back := ShapeSimilarity(&b, &a); back != got {
+				t.Errorf("ShapeSimilarity is not symmetric: %.1f and %.1f", got, back)
+			}
+		})
+	}
+}
+
+func TestShapeSimilarityOfEmptyShapes(t *testing.T) {
+	empty := FuncShape{subtrees: map[uint64]int{}, sizes: map[uint64]int{}}
+	if got := ShapeSimilarity(&empty, &empty); got != 0 {
+		t.Errorf("ShapeSimilarity of empty shapes = %.1f, want 0", got)
+	}
+}
+
+func TestFuncShapeSubtract(t *testing.T) {
+	const src = `package p
+func solve(xs []int) int {
+	best := 0
+	for _, x := range xs {
+		if x > best {
+			best = x
+		}
+	}
+	return best
+}`
+	skeleton := onlyShape(t, src)
+	everything := map[uint64]bool{}
+	for h := range skeleton.subtrees {
+		everything[h] = true
+	}
+	loopOnly := map[uint64]bool{}
+	largest, largestSize := uint64(0), 0
+	for h, size := range skeleton.sizes {
+		if size > largestSize && size < skeleton.Nodes {
+			largest, largestSize = h, size
+		}
+	}
+	loopOnly[largest] = true
+
+	tests := []struct {
+		name      string
+		template  map[uint64]bool
+		wantEmpty bool
+		wantNone  bool
+	}{
+		{name: "nil template", template: nil, wantNone: true},
+		{name: "unrelated template", template: map[uint64]bool{1: true, 2: true}, wantNone: true},
+		{name: "part of the function", template: loopOnly},
+		{name: "the whole function", template: everything, wantEmpty: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := onlyShape(t, src)
+			before := s.total
+			removed := s.subtract(tt.template)
+			if s.total != before-removed {
+				t.Errorf("total = %d after removing %d of %d", s.total, removed, before)
+			}
+			switch {
+			case tt.wantNone && removed != 0:
+				t.Errorf("removed %d subtrees, want none", removed)
+			case tt.wantEmpty && (s.total != 0 || len(s.subtrees) != 0):
+				t.Errorf("%d subtrees left, want none", s.total)
+			case !tt.wantNone && !tt.wantEmpty && (removed == 0 || s.total == 0):
+				t.Errorf("removed %d of %d subtrees, want some but not all", removed, before)
+			}
+			for h := range s.subtrees {
+				if tt.template[h] {
+					t.Errorf("template subtree %x is left", h)
+				}
+			}
+		})
+	}
+}
+
+func TestASTDetectorShapesAndPair(t *testing.T) {
+	d := &ASTDetector{minNodes: 5, threshold: DefaultASTThreshold}
+	own := mustShapes(t, `package p
+func tiny() {}
+func max2(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+func count(xs []string, s string) int {
+	n := 0
+	for _, x := range xs {
+		if x == s {
+			n++
+		}
+	}
+	return n
+}`)
+	theirs := mustShapes(t, `package q
+func occurrences(items []string, item string) int {
+	c := 0
+	for _, it := range items {
+		if item == it {
+			c++
+		}
+	}
+	return c
+}
+func bigger(x, y int) int {
+	if y < x {
+		return x
+	}
+	return y
+}`)
+
+	ownShapes, excluded := d.shapes(own, nil)
+	if len(ownShapes) != 2 || excluded != 0 {
+		t.Fatalf("shapes kept %d functions with %.1f%% excluded, want 2 and 0", len(ownShapes), excluded)
+	}
+	theirShapes, _ := d.shapes(theirs, nil)
+	match := d.pair(ownShapes, theirShapes)
+	if len(match.Functions) != 2 {
+		t.Fatalf("paired %d functions, want 2: %+v", len(match.Functions), match.Functions)
+	}
+	want := map[string]string{"max2": "bigger", "count": "occurrences"}
+	for _, f := range match.Functions {
+		if want[f.Function] != f.MatchedFunction {
+			t.Errorf("%s paired with %s, want %s", f.Function, f.MatchedFunction, want[f.Function])
+		}
+		if f.Similarity != 100 {
+			t.Errorf("%s similarity = %.1f, want 100", f.Function, f.Similarity)
+		}
+	}
+	if match.Similarity != 100 {
+		t.Errorf("match similarity = %.1f, want 100", match.Similarity)
+	}
+	if match.Functions[0].Start > match.Functions[1].Start {
+		t.Error("pairs are not ordered by position in the checked work")
+	}
+}
+
+// TestASTDetectorPairsRenamedCopy pairs the functions of the renamed copy
+// the code index finds (see TestCodeIndexFindsRenamedCopy) with the
+// original's.
+func TestASTDetectorPairsRenamedCopy(t *testing.T) {
+	d := &ASTDetector{minNodes: DefaultASTMinNodes, threshold: DefaultASTThreshold}
+	own, _ := d.shapes(mustShapes(t, indexedCopy), nil)
+	theirs, _ := d.shapes(mustShapes(t, indexedOriginal), nil)
+
+	match := d.pair(own, theirs)
+	want := map[string]string{"accumulate": "sumPositive", "frequencies": "countWords"}
+	if len(match.Functions) != len(want) {
+		t.Fatalf("pair() = %+v, want %d pairs", match.Functions, len(want))
+	}
+	for _, f := range match.Functions {
+		if want[f.Function] != f.MatchedFunction {
+			t.Errorf("%s paired with %s, want %s", f.Function, f.MatchedFunction, want[f.Function])
+		}
+	}
+	// main got an extra call and falls below the threshold, but it still
+	// counts in the work's nodes.
+	if match.Similarity < 70 {
+		t.Errorf("similarity %.1f%%, want at least 70%%", match.Similarity)
+	}
+}
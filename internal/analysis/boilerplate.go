@@ -0,0 +1,54 @@
+package analysis
+
+// templateTexts returns the texts of the submission's templates. With a
+// language, only templates in that language are returned, since skeleton
+// code only says something about code in the same language.
+func (s *Submission) templateTexts(lang string) []string {
+	var texts []string
+	for _, t := range s.Templates {
+		if lang == "" || DetectLanguage(t.FileName) == lang {
+			texts = append(texts, t.Text)
+		}
+	}
+	return texts
+}
+
+// templateKGrams hashes every k-gram of the templates, not only the winnowed
+// ones: a template passage has to be recognised whatever fingerprints the
+// surrounding text of a work selects.
+func templateKGrams(texts []string, k int) map[uint64]bool {
+	kgrams := map[uint64]bool{}
+	for _, text := range texts {
+		for _, fp := range Fingerprints(text, k, 1) {
+			kgrams[fp.Hash] = true
+		}
+	}
+	return kgrams
+}
+
+// boilerplateSpans returns the spans of text made of template k-grams.
+func boilerplateSpans(text string, k int, kgrams map[uint64]bool) []Fragment {
+	if len(kgrams) == 0 {
+		return nil
+	}
+	var spans []Fragment
+	for _, fp := range Fingerprints(text, k, 1) {
+		if kgrams[fp.Hash] {
+			spans = append(spans, Fragment{Start: fp.Start, End: fp.End})
+		}
+	}
+	return spans
+}
+
+// measureBoilerplate is how many characters of the submission's text come
+// from the task's templates, and their share of the text in percent. Passages
+// are recognised by k-grams of the winnowing detector's k, so the share
+// matches what that detector excluded.
+func measureBoilerplate(sub *Submission, k int) (int, float64) {
+	textLen := len([]rune(sub.Text))
+	if textLen == 0 || len(sub.Templates) == 0 {
+		return 0, 0
+	}
+	chars := coveredChars(boilerplateSpans(sub.Text, k, templateKGrams(sub.templateTexts(""), k)))
+	return chars, float64(chars) / float64(textLen) * 100
+}
@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateTexts(t *testing.T) {
+	sub := &Submission{Templates: []Template{
+		{FileName: "task.pdf", Text: "условие"},
+		{FileName: "main.go", Text: "package main"},
+		{FileName: "solution.py", Text: "def solve(): pass"},
+	}}
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{lang: "", want: []string{"условие", "package main", "def solve(): pass"}},
+		{lang: LangGo, want: []string{"package main"}},
+		{lang: LangJava, want: nil},
+	}
+	for _, tt := range tests {
+		if got := sub.templateTexts(tt.lang); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("templateTexts(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestBoilerplateSpans(t *testing.T) {
+	const k = 5
+	template := "Вариант 1. Напишите функцию"
+	tests := []struct {
+		name      string
+		text      string
+		templates []string
+		want      int // characters covered
+	}{
+		{name: "no templates", text: "Вариант 1. Напишите функцию сортировки", templates: nil, want: 0},
+		{name: "nothing in common", text: "совсем другой текст работы", templates: []string{template}, want: 0},
+		{
+			name:      "template passage",
+			text:      "Вариант 1. Напишите функцию сортировки",
+			templates: []string{template},
+			want:      len([]rune("Вариант 1. Напишите функцию")),
+		},
+		{
+			// Case, spacing and punctuation do not hide a template passage.
+			name:      "reformatted template passage",
+			text:      "ВАРИАНТ 1 напишите   функцию: готово",
+			templates: []string{template},
+			want:      len([]rune("ВАРИАНТ 1 напишите   функцию")),
+		},
+		{
+			name:      "passages of several templates",
+			text:      "первый шаблон; ответ; второй шаблон",
+			templates: []string{"первый шаблон", "второй шаблон"},
+			want:      2 * len([]rune("первый шаблон")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := boilerplateSpans(tt.text, k, templateKGrams(tt.templates, k))
+			if got := coveredChars(spans); got != tt.want {
+				t.Errorf("covered %d characters, want %d (spans %v)", got, tt.want, spans)
+			}
+		})
+	}
+}
+
+func TestMeasureBoilerplate(t *testing.T) {
+	template := strings.Repeat("шаблон задания ", 4)
+	tests := []struct {
+		name      string
+		text      string
+		templates []Template
+		wantChars int
+		wantShare float64
+	}{
+		{name: "no templates", text: template, templates: nil},
+		{name: "empty text", text: "", templates: []Template{{Text: template}}},
+		{
+			name:      "whole text is template",
+			text:      strings.TrimSpace(template),
+			templates: []Template{{Text: template}},
+			wantChars: len([]rune(strings.TrimSpace(template))),
+			wantShare: 100,
+		},
+		{
+			name:      "part of the text",
+			text:      "шаблон задания xxxxxxxxxxxxxx",
+			templates: []Template{{Text: "шаблон задания"}},
+			wantChars: 14,
+			wantShare: 14.0 / 29 * 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chars, share := measureBoilerplate(&Submission{Text: tt.text, Templates: tt.templates}, 5)
+			if chars != tt.wantChars || math.Abs(share-tt.wantShare) > 1e-9 {
+				t.Errorf("measureBoilerplate() = %d, %.2f; want %d, %.2f", chars, share, tt.wantChars, tt.wantShare)
+			}
+		})
+	}
+}
+
+func TestShingleSetWithout(t *testing.T) {
+	tests := []struct {
+		name        string
+		s, template ShingleSet
+		want        ShingleSet
+	}{
+		{name: "no template", s: shingleRange(0, 3), template: nil, want: shingleRange(0, 3)},
+		{name: "overlap removed", s: shingleRange(0, 5), template: shingleRange(3, 10), want: shingleRange(0, 3)},
+		{name: "all template", s: shingleRange(0, 3), template: shingleRange(0, 3), want: ShingleSet{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.without(tt.template); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("without() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"context"
+	"log/slog"
+
+	"HW_KPO3/internal/config"
+)
+
+// candidateSet is the outcome of the index lookup for a submission.
+type candidateSet struct {
+	ids     []int64
+	indexed int
+}
+
+// CandidateIndex is the MinHash/LSH index of the works' word shingles. Every
+// detector compares a submission only with the candidates it returns, so a
+// check reads a handful of works however many the task has.
+type CandidateIndex struct {
+	storage *StorageClient
+	repo    *Repository
+	hasher  *MinHasher
+	size    int
+	lsh     config.LSH
+}
+
+func NewCandidateIndex(storage *StorageClient, repo *Repository, cfg config.Analysis) *CandidateIndex {
+	size := cfg.ShingleSize
+	if size <= 0 {
+		size = DefaultShingleSize
+	}
+	return &CandidateIndex{
+		storage: storage,
+		repo:    repo,
+		hasher:  NewMinHasher(cfg.LSH.NumHashes),
+		size:    size,
+		lsh:     cfg.LSH,
+	}
+}
+
+// Candidates indexes the work, adds the task's works missing from the index
+// and returns the live works of other students that share a bucket with it,
+// with the number of works in the index. Earlier versions of the same
+// student's submission are never candidates. Students and tasks are taken
+// from the live works rather than the index, which keeps those of the time a
+// work was indexed. The lookup is done once per submission and shared by the
+// detectors.
+func (x *CandidateIndex) Candidates(ctx context.Context, sub *Submission) ([]int64, int, error) {
+	if sub.candidates != nil {
+		return sub.candidates.ids, sub.candidates.indexed, nil
+	}
+	work := sub.Work
+	settings, err := x.repo.GetLSHSettings(ctx, work.TaskID, x.lsh)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := x.index(ctx, work, Shingles(Words(sub.Text), x.size), settings); err != nil {
+		return nil, 0, err
+	}
+	indexed, err := x.indexTask(ctx, work.TaskID, sub.TaskWorks, settings)
+	if err != nil {
+		return nil, 0, err
+	}
+	found, err := x.repo.FindCandidates(ctx, work.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	set := &candidateSet{indexed: indexed}
+	for _, w := range otherStudents(sub, found) {
+		set.ids = append(set.ids, w.ID)
+	}
+	sub.candidates = set
+	return set.ids, set.indexed, nil
+}
+
+// otherStudents returns the live works among ids that belong to students
+// other than the submission's, in the order of ids.
+func otherStudents(sub *Submission, ids []int64) []*Work {
+	live := map[int64]*Work{}
+	for i := range sub.TaskWorks {
+		live[sub.TaskWorks[i].ID] = &sub.TaskWorks[i]
+	}
+	var works []*Work
+	for _, id := range ids {
+		if w := live[id]; w != nil && w.StudentID != sub.Work.StudentID {
+			works = append(works, w)
+		}
+	}
+	return works
+}
+
+func (x *CandidateIndex) index(ctx context.Context, work *Work, shingles ShingleSet, settings *LSHSettings) error {
+	// An empty text is stored without buckets so it is not re-indexed on
+	// every check, but it never becomes a candidate.
+	sig := x.hasher.Signature(shingles)
+	return x.repo.SaveSignature(ctx, &WorkSignature{
+		WorkID:      work.ID,
+		TaskID:      work.TaskID,
+		StudentID:   work.StudentID,
+		ShingleSize: x.size,
+		NumHashes:   x.hasher.Size(),
+		Signature:   sig,
+	}, sig.Buckets(settings.Bands, settings.Rows))
+}
+
+// indexTask adds the task's works that are not in the index yet, e.g. ones
+// stored before it existed or indexed with other parameters. It returns the
+// number of works in the index.
+func (x *CandidateIndex) indexTask(ctx context.Context, taskID int64, works []Work, settings *LSHSettings) (int, error) {
+	indexed, err := x.repo.IndexedWorks(ctx, taskID, x.size, x.hasher.Size())
+	if err != nil {
+		return 0, err
+	}
+	for i := range works {
+		w := &works[i]
+		if indexed[w.ID] {
+			continue
+		}
+		text, err := x.storage.GetWorkText(ctx, w.ID)
+		if err != nil {
+			slog.Warn("failed to get text of work to index", "work_id", w.ID, "err", err)
+			continue
+		}
+		if err := x.index(ctx, w, Shingles(Words(text.Text), x.size), settings); err != nil {
+			return 0, err
+		}
+		indexed[w.ID] = true
+	}
+	return len(indexed), nil
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOtherStudents(t *testing.T) {
+	own := Work{ID: 1, TaskID: 7, StudentID: 10}
+	tests := []struct {
+		name      string
+		taskWorks []Work
+		ids       []int64
+		want      []int64
+	}{
+		{name: "no candidates", taskWorks: []Work{own, {ID: 2, StudentID: 20}}, ids: nil, want: nil},
+		{
+			name:      "other students in the order found",
+			taskWorks: []Work{own, {ID: 2, StudentID: 20}, {ID: 3, StudentID: 30}},
+			ids:       []int64{3, 2},
+			want:      []int64{3, 2},
+		},
+		{
+			name:      "earlier versions of the same student",
+			taskWorks: []Work{own, {ID: 2, StudentID: 10}, {ID: 3, StudentID: 30}},
+			ids:       []int64{2, 3},
+			want:      []int64{3},
+		},
+		{
+			name:      "work moved to another task is not live",
+			taskWorks: []Work{own, {ID: 3, StudentID: 30}},
+			ids:       []int64{2, 3},
+			want:      []int64{3},
+		},
+		{
+			// The index still has student 20 for work 2, but it was
+			// reassigned to the checked work's student.
+			name:      "student changed after indexing",
+			taskWorks: []Work{own, {ID: 2, StudentID: 10}},
+			ids:       []int64{2},
+			want:      nil,
+		},
+		{
+			// And the other way round: indexed as the same student, now
+			// someone else's.
+			name:      "reassigned to another student",
+			taskWorks: []Work{own, {ID: 2, StudentID: 20}},
+			ids:       []int64{2},
+			want:      []int64{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Submission{Work: &own, TaskWorks: tt.taskWorks}
+			var got []int64
+			for _, w := range otherStudents(sub, tt.ids) {
+				got = append(got, w.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("otherStudents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
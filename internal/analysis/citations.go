@@ -0,0 +1,261 @@
+package analysis
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of cited passages.
+const (
+	CitationQuote        = "quote"
+	CitationBlockQuote   = "block_quote"
+	CitationBibliography = "bibliography"
+)
+
+const (
+	// minQuoteWords keeps quoted titles and terms in the comparison: only
+	// quotations of a few words are citations worth leaving out.
+	minQuoteWords = 3
+
+	// maxQuoteChars stops a stray quote mark from swallowing the text.
+	maxQuoteChars = 3000
+)
+
+var (
+	bibliographyHeading = regexp.MustCompile(`(?i)^(?:\d+(?:\.\d+)*\.?\s*)?(?:` +
+		`список\s+(?:использованн\S*\s+)?(?:литературы|источников)(?:\s+и\s+(?:литературы|источников))?|` +
+		`библиографический\s+список|библиография|литература|источники|` +
+		`references|bibliography|works\s+cited|literature)\s*[:.]?$`)
+	appendixHeading = regexp.MustCompile(`(?i)^(?:приложени[ея]|appendix|appendices)(?:\s+\S+)?\s*[:.]?$`)
+)
+
+// Citation is a passage the author attributes to a source: a quotation, a
+// block quote or the list of references. Start and End count characters
+// (runes) of the work's text, End is exclusive.
+type Citation struct {
+	Kind  string `json:"kind"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// citationsOf finds the citations of a work's text. Source code has none:
+// its string literals are not quotations.
+func citationsOf(fileName, text string) []Citation {
+	if DetectLanguage(fileName) != "" {
+		return nil
+	}
+	return FindCitations(text)
+}
+
+// FindCitations finds the passages of text that cite sources: the reference
+// list (from a "Список литературы" or "References" heading to the end of the
+// text or the appendices), block quotes (lines starting with ">") and
+// quotations in «», „“, “” or straight double quotes. Quotations do not span
+// paragraphs. The citations do not overlap and are sorted by position.
+func FindCitations(text string) []Citation {
+	runes := []rune(text)
+	lines := splitLines(runes)
+
+	var blocks []Citation
+	if bib, ok := findBibliography(runes, lines); ok {
+		blocks = append(blocks, bib)
+	}
+	for _, q := range findBlockQuotes(runes, lines) {
+		if !overlapsAny(q, blocks) {
+			blocks = append(blocks, q)
+		}
+	}
+	citations := blocks
+	for _, q := range findQuotes(runes) {
+		if !overlapsAny(q, blocks) {
+			citations = append(citations, q)
+		}
+	}
+	sort.Slice(citations, func(i, j int) bool { return citations[i].Start < citations[j].Start })
+	return citations
+}
+
+// line is a line of the text as rune offsets, End excluding the line break.
+type line struct {
+	Start int
+	End   int
+}
+
+func splitLines(runes []rune) []line {
+	var lines []line
+	start := 0
+	for i, r := range runes {
+		if r == '\n' {
+			lines = append(lines, line{Start: start, End: i})
+			start = i + 1
+		}
+	}
+	return append(lines, line{Start: start, End: len(runes)})
+}
+
+func lineText(runes []rune, l line) string {
+	return strings.TrimSpace(string(runes[l.Start:l.End]))
+}
+
+// findBibliography takes the last reference list heading, so that a table
+// of contents listing it does not count, and runs to the next appendix
+// heading or the end of the text.
+func findBibliography(runes []rune, lines []line) (Citation, bool) {
+	heading := -1
+	for i, l := range lines {
+		if bibliographyHeading.MatchString(lineText(runes, l)) {
+			heading = i
+		}
+	}
+	if heading < 0 {
+		return Citation{}, false
+	}
+	end, entries := len(runes), 0
+	for _, l := range lines[heading+1:] {
+		text := lineText(runes, l)
+		if appendixHeading.MatchString(text) {
+			end = l.Start
+			break
+		}
+		if text != "" {
+			entries++
+		}
+	}
+	if entries == 0 {
+		return Citation{}, false
+	}
+	return Citation{Kind: CitationBibliography, Start: lines[heading].Start, End: trimEnd(runes, lines[heading].Start, end)}, true
+}
+
+// findBlockQuotes joins consecutive lines starting with ">".
+func findBlockQuotes(runes []rune, lines []line) []Citation {
+	var quotes []Citation
+	var cur *Citation
+	for _, l := range lines {
+		if !strings.HasPrefix(lineText(runes, l), ">") {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			quotes = append(quotes, Citation{Kind: CitationBlockQuote, Start: l.Start})
+			cur = &quotes[len(quotes)-1]
+		}
+		cur.End = l.End
+	}
+	return quotes
+}
+
+// findQuotes pairs quote marks. Guillemets nest; other marks inside an open
+// quotation are part of it. A paragraph break or maxQuoteChars without a
+// closing mark drops the quotation.
+func findQuotes(runes []rune) []Citation {
+	var quotes []Citation
+	var open, close rune
+	openAt, depth := 0, 0
+	for i, r := range runes {
+		if open == 0 {
+			switch r {
+			case '«':
+				open, close = r, '»'
+			case '„':
+				open, close = r, '“'
+			case '“':
+				open, close = r, '”'
+			case '"':
+				open, close = r, '"'
+			default:
+				continue
+			}
+			openAt, depth = i, 1
+			continue
+		}
+		switch {
+		case r == '\n' && i+1 < len(runes) && runes[i+1] == '\n', i-openAt > maxQuoteChars:
+			open = 0
+		case r == close:
+			if depth--; depth == 0 {
+				if len(Words(string(runes[openAt+1:i]))) >= minQuoteWords {
+					quotes = append(quotes, Citation{Kind: CitationQuote, Start: openAt, End: i + 1})
+				}
+				open = 0
+			}
+		case r == open:
+			depth++
+		}
+	}
+	return quotes
+}
+
+func trimEnd(runes []rune, start, end int) int {
+	for end > start && strings.TrimSpace(string(runes[end-1])) == "" {
+		end--
+	}
+	return end
+}
+
+func overlapsAny(c Citation, others []Citation) bool {
+	for _, o := range others {
+		if c.Start < o.End && o.Start < c.End {
+			return true
+		}
+	}
+	return false
+}
+
+// citedChars is the number of characters the citations cover.
+func citedChars(citations []Citation) int {
+	chars := 0
+	for _, c := range citations {
+		chars += c.End - c.Start
+	}
+	return chars
+}
+
+// citationSpans returns the citations as fragments of the checked text, to be
+// combined with other spans left out of the comparison.
+func citationSpans(citations []Citation) []Fragment {
+	spans := make([]Fragment, len(citations))
+	for i, c := range citations {
+		spans[i] = Fragment{Start: c.Start, End: c.End}
+	}
+	return spans
+}
+
+// cited reports whether the span [start, end) overlaps a citation. The
+// citations must be sorted and not overlap.
+func cited(citations []Citation, start, end int) bool {
+	i := sort.Search(len(citations), func(i int) bool { return citations[i].End > start })
+	return i < len(citations) && citations[i].Start < end
+}
+
+// uncitedParts splits text into the passages between its citations.
+func uncitedParts(text string, citations []Citation) []string {
+	if len(citations) == 0 {
+		return []string{text}
+	}
+	runes := []rune(text)
+	var parts []string
+	pos := 0
+	for _, c := range citations {
+		if c.Start > pos {
+			parts = append(parts, string(runes[pos:c.Start]))
+		}
+		pos = max(pos, c.End)
+	}
+	if pos < len(runes) {
+		parts = append(parts, string(runes[pos:]))
+	}
+	return parts
+}
+
+// measureCitations returns the submission's citations with how many
+// characters of its text they cover and their share of the text in percent.
+func measureCitations(sub *Submission) ([]Citation, int, float64) {
+	textLen := len([]rune(sub.Text))
+	if textLen == 0 || len(sub.Citations) == 0 {
+		return sub.Citations, 0, 0
+	}
+	chars := citedChars(sub.Citations)
+	return sub.Citations, chars, float64(chars) / float64(textLen) * 100
+}
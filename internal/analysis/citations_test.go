@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+// citedTexts renders citations as "kind: text" so expectations read like the
+// passages they cover and check the rune offsets at the same time.
+func citedTexts(text string, citations []Citation) []string {
+	runes := []rune(text)
+	var out []string
+	for _, c := range citations {
+		out = append(out, c.Kind+": "+string(runes[c.Start:c.End]))
+	}
+	return out
+}
+
+func TestFindCitations(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "no citations", text: "Обычный текст без цитат.", want: nil},
+		{
+			name: "guillemets",
+			text: "Как писал автор, «всё течёт и всё меняется» — и это так.",
+			want: []string{"quote: «всё течёт и всё меняется»"},
+		},
+		{
+			name: "short quotations are titles, not citations",
+			text: "Роман «Идиот», повесть «Белые ночи» и слово «дом».",
+			want: nil,
+		},
+		{
+			name: "nested guillemets",
+			text: "Он ответил: «Я прочёл «Войну и мир» целиком за неделю».",
+			want: []string{"quote: «Я прочёл «Войну и мир» целиком за неделю»"},
+		},
+		{
+			name: "other quote marks",
+			text: "„немецкие кавычки тоже бывают“ и “English style quotes here” and \"plain straight quotes too\".",
+			want: []string{
+				"quote: „немецкие кавычки тоже бывают“",
+				"quote: “English style quotes here”",
+				"quote: \"plain straight quotes too\"",
+			},
+		},
+		{
+			name: "other marks inside a quotation belong to it",
+			text: "«Он сказал \"нет\" и ушёл домой»",
+			want: []string{"quote: «Он сказал \"нет\" и ушёл домой»"},
+		},
+		{
+			name: "unclosed quotation",
+			text: "Здесь «кавычка открыта и так и не закрыта",
+			want: nil,
+		},
+		{
+			name: "quotations do not span paragraphs",
+			text: "«Первый абзац цитаты\n\nвторой абзац» после.",
+			want: nil,
+		},
+		{
+			name: "a single line break is fine",
+			text: "«первая строка\nвторая строка»",
+			want: []string{"quote: «первая строка\nвторая строка»"},
+		},
+		{
+			name: "offsets count runes after multi-byte characters",
+			text: "🙂🙂 日本語 «три слова здесь» 🙂",
+			want: []string{"quote: «три слова здесь»"},
+		},
+		{
+			name: "block quote lines are joined",
+			text: "Вступление.\n> первая строка цитаты\n  > вторая строка\nПродолжение.\n> ещё одна",
+			want: []string{
+				"block_quote: > первая строка цитаты\n  > вторая строка",
+				"block_quote: > ещё одна",
+			},
+		},
+		{
+			name: "bibliography runs to the end",
+			text: "Текст работы.\n\nСписок использованной литературы:\n1. Кнут Д. «Искусство программирования».\n2. Вирт Н. Алгоритмы.\n\n",
+			want: []string{"bibliography: Список использованной литературы:\n1. Кнут Д. «Искусство программирования».\n2. Вирт Н. Алгоритмы."},
+		},
+		{
+			name: "bibliography stops at an appendix",
+			text: "Текст.\nReferences\nKnuth D. TAOCP.\nAppendix A\nКод программы.",
+			want: []string{"bibliography: References\nKnuth D. TAOCP."},
+		},
+		{
+			name: "the last heading wins over the table of contents",
+			text: "Содержание\n1. Введение\n2. Литература\n\nТекст работы.\n\n2. Литература\nИсточник один.",
+			want: []string{"bibliography: 2. Литература\nИсточник один."},
+		},
+		{
+			name: "heading without entries",
+			text: "Текст.\nБиблиография\n\n",
+			want: nil,
+		},
+		{
+			name: "block quotes in the bibliography are part of it",
+			text: "Начало «цитаты из трёх слов» тут.\nИсточники\n> цитата в списке\nКнига.",
+			want: []string{
+				"quote: «цитаты из трёх слов»",
+				"bibliography: Источники\n> цитата в списке\nКнига.",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := citedTexts(tt.text, FindCitations(tt.text))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindCitations =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCitationsOfSourceCode(t *testing.T) {
+	const src = `fmt.Println("three words here")`
+	if got := citationsOf("main.go", src); got != nil {
+		t.Errorf("citationsOf(main.go) = %v, want none", got)
+	}
+	if got := citationsOf("essay.txt", src); len(got) != 1 {
+		t.Errorf("citationsOf(essay.txt) = %v, want one quote", got)
+	}
+}
+
+func TestCited(t *testing.T) {
+	citations := []Citation{{Start: 10, End: 20}, {Start: 30, End: 40}}
+	tests := []struct {
+		start, end int
+		want       bool
+	}{
+		{0, 10, false},
+		{0, 11, true},
+		{15, 16, true},
+		{19, 25, true},
+		{20, 30, false},
+		{35, 50, true},
+		{40, 45, false},
+	}
+	for _, tt := range tests {
+		if got := cited(citations, tt.start, tt.end); got != tt.want {
+			t.Errorf("cited([%d, %d)) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+	if cited(nil, 0, 100) {
+		t.Error("cited with no citations")
+	}
+}
+
+func TestUncitedParts(t *testing.T) {
+	const text = "до «цитата» после"
+	tests := []struct {
+		name      string
+		citations []Citation
+		want      []string
+	}{
+		{"no citations", nil, []string{text}},
+		{"middle", []Citation{{Start: 3, End: 11}}, []string{"до ", " после"}},
+		{"whole text", []Citation{{Start: 0, End: 17}}, nil},
+		{"at the start", []Citation{{Start: 0, End: 3}}, []string{"«цитата» после"}},
+		{"adjacent", []Citation{{Start: 0, End: 3}, {Start: 3, End: 11}}, []string{" после"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uncitedParts(text, tt.citations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uncitedParts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// DefaultClusterThreshold is the similarity, in percent, from which two
+// submissions are linked when the request does not give one.
+const DefaultClusterThreshold = 50
+
+// Ways of grouping linked submissions.
+const (
+	ClusterComponents  = "components"
+	ClusterCommunities = "communities"
+)
+
+// maxLabelRounds bounds label propagation; it settles in a few rounds on
+// graphs of a task's size.
+const maxLabelRounds = 100
+
+// WorkPair is a stored match: the latest finished report of WorkID found
+// MatchedWorkID similar.
+type WorkPair struct {
+	WorkID        int64   `json:"work_id"`
+	MatchedWorkID int64   `json:"matched_work_id"`
+	Similarity    float64 `json:"similarity"`
+}
+
+// ListWorkPairs returns the matches of the latest finished, not hidden report
+// of each of the works.
+func (r Repository) ListWorkPairs(ctx context.Context, workIDs []int64) ([]WorkPair, error) {
+	const query = `
+    WITH latest AS (
+        SELECT DISTINCT ON (work_id) id, work_id
+        FROM reports
+        WHERE work_id = ANY($1) AND status = 'done' AND hidden_at IS NULL
+        ORDER BY work_id, created_at DESC
+    )
+    SELECT l.work_id, m.matched_work_id, m.similarity
+    FROM latest l
+    JOIN report_matches m ON m.report_id = l.id;`
+
+	rows, err := r.pool.Query(ctx, query, workIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list work pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []WorkPair
+	for rows.Next() {
+		var p WorkPair
+		if err := rows.Scan(&p.WorkID, &p.MatchedWorkID, &p.Similarity); err != nil {
+			return nil, fmt.Errorf("failed to list work pairs: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list work pairs: %w", err)
+	}
+	return pairs, nil
+}
+
+// ListCheckedWorks returns, for those of the works that have a finished, not
+// hidden report, when their latest such report started. The check compared
+// the work with the works uploaded by then.
+func (r Repository) ListCheckedWorks(ctx context.Context, workIDs []int64) (map[int64]time.Time, error) {
+	const query = `
+    SELECT DISTINCT ON (work_id) work_id, COALESCE(started_at, finished_at, created_at)
+    FROM reports
+    WHERE work_id = ANY($1) AND status = 'done' AND hidden_at IS NULL
+    ORDER BY work_id, created_at DESC;`
+
+	rows, err := r.pool.Query(ctx, query, workIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checked works: %w", err)
+	}
+	defer rows.Close()
+
+	checked := map[int64]time.Time{}
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, fmt.Errorf("failed to list checked works: %w", err)
+		}
+		checked[id] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list checked works: %w", err)
+	}
+	return checked, nil
+}
+
+// ClusterMember is a student's submission in a cluster: its latest version,
+// when that was uploaded and when the student first handed the task in.
+// MaxSimilarity is its strongest link inside the cluster.
+type ClusterMember struct {
+	WorkID          int64   `json:"work_id"`
+	StudentID       int64   `json:"student_id"`
+	Student         string  `json:"student"`
+	Version         int     `json:"version"`
+	UploadedAt      string  `json:"uploaded_at"`
+	FirstUploadedAt string  `json:"first_uploaded_at"`
+	MaxSimilarity   float64 `json:"max_similarity"`
+}
+
+// Cluster is a group of submissions linked by similarity at or above the
+// threshold. Pairs are those links, by the submissions' latest works;
+// Density is the share of member pairs that are linked. The likely original
+// is the submission handed in first.
+type Cluster struct {
+	ID             int             `json:"id"`
+	Size           int             `json:"size"`
+	MaxSimilarity  float64         `json:"max_similarity"`
+	AvgSimilarity  float64         `json:"avg_similarity"`
+	Density        float64         `json:"density"`
+	OriginalWorkID int64           `json:"original_work_id"`
+	Members        []ClusterMember `json:"members"`
+	Pairs          []WorkPair      `json:"pairs"`
+}
+
+// submission is a student's hand-in for a task: the latest version of the
+// work, when the first version was uploaded and when the last check of any
+// version started, empty if none has been checked. Times are in storage's
+// format, which orders as text.
+type submission struct {
+	work            Work
+	firstUploadedAt string
+	checkedAt       string
+}
+
+// compared tells whether a check of one of the submissions could have seen
+// the other, i.e. started after the other was first uploaded.
+func (g *submissionGraph) compared(n, m int) bool {
+	a, b := g.nodes[n], g.nodes[m]
+	return (a.checkedAt != "" && b.firstUploadedAt <= a.checkedAt) ||
+		(b.checkedAt != "" && a.firstUploadedAt <= b.checkedAt)
+}
+
+// submissionGraph links the submissions of a task, one node per student. A
+// match with any version of a student's work counts for the student, and a
+// pair found from both sides keeps the higher score.
+type submissionGraph struct {
+	nodes []submission
+	edges map[[2]int]float64 // node indexes, lower first
+}
+
+func newSubmissionGraph(works []Work, pairs []WorkPair, checked map[int64]time.Time) *submissionGraph {
+	g := &submissionGraph{edges: map[[2]int]float64{}}
+	byStudent := map[int64]int{}
+	for _, w := range works {
+		i, ok := byStudent[w.StudentID]
+		if !ok {
+			i = len(g.nodes)
+			byStudent[w.StudentID] = i
+			g.nodes = append(g.nodes, submission{work: w, firstUploadedAt: w.UploadedAt})
+		}
+		n := &g.nodes[i]
+		if w.Version > n.work.Version {
+			n.work = w
+		}
+		if w.UploadedAt < n.firstUploadedAt {
+			n.firstUploadedAt = w.UploadedAt
+		}
+		if at, ok := checked[w.ID]; ok {
+			n.checkedAt = max(n.checkedAt, at.UTC().Format("2006-01-02 15:04:05"))
+		}
+	}
+	nodeOf := map[int64]int{}
+	for _, w := range works {
+		nodeOf[w.ID] = byStudent[w.StudentID]
+	}
+	for _, p := range pairs {
+		a, okA := nodeOf[p.WorkID]
+		b, okB := nodeOf[p.MatchedWorkID]
+		if !okA || !okB || a == b {
+			continue
+		}
+		key := [2]int{min(a, b), max(a, b)}
+		g.edges[key] = max(g.edges[key], p.Similarity)
+	}
+	return g
+}
+
+// adjacency lists the neighbours of every node over links at or above the
+// threshold.
+func (g *submissionGraph) adjacency(threshold float64) []map[int]float64 {
+	adj := make([]map[int]float64, len(g.nodes))
+	for i := range adj {
+		adj[i] = map[int]float64{}
+	}
+	for key, sim := range g.edges {
+		if sim >= threshold {
+			adj[key[0]][key[1]] = sim
+			adj[key[1]][key[0]] = sim
+		}
+	}
+	return adj
+}
+
+// components labels every node with the smallest node index of its
+// connected component.
+func components(adj []map[int]float64) []int {
+	labels := make([]int, len(adj))
+	for i := range labels {
+		labels[i] = -1
+	}
+	for start := range adj {
+		if labels[start] >= 0 {
+			continue
+		}
+		labels[start] = start
+		stack := []int{start}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for m := range adj[n] {
+				if labels[m] < 0 {
+					labels[m] = start
+					stack = append(stack, m)
+				}
+			}
+		}
+	}
+	return labels
+}
+
+// communities splits the graph by weighted label propagation: every node
+// takes the label with the largest total similarity among its neighbours
+// until nothing changes. Loosely chained components fall apart into their
+// tightly linked groups. Nodes are visited in order and ties go to the
+// smaller label, so the result is the same on every call.
+func communities(adj []map[int]float64) []int {
+	labels := make([]int, len(adj))
+	for i := range labels {
+		labels[i] = i
+	}
+	for round := 0; round < maxLabelRounds; round++ {
+		changed := false
+		for n := range adj {
+			if len(adj[n]) == 0 {
+				continue
+			}
+			weight := map[int]float64{}
+			for m, sim := range adj[n] {
+				weight[labels[m]] += sim
+			}
+			best := labels[n]
+			for label, w := range weight {
+				if w > weight[best] || (w == weight[best] && label < best) {
+					best = label
+				}
+			}
+			if best != labels[n] {
+				labels[n], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return labels
+}
+
+// clusters groups the nodes by label and describes every group of two or
+// more, largest and most similar first.
+func (g *submissionGraph) clusters(adj []map[int]float64, labels []int) []Cluster {
+	groups := map[int][]int{}
+	for n, label := range labels {
+		groups[label] = append(groups[label], n)
+	}
+	var clusters []Cluster
+	for _, nodes := range groups {
+		if len(nodes) < 2 {
+			continue
+		}
+		sort.Ints(nodes)
+		c := Cluster{Size: len(nodes), Members: make([]ClusterMember, 0, len(nodes)), Pairs: []WorkPair{}}
+		in := map[int]bool{}
+		for _, n := range nodes {
+			in[n] = true
+		}
+		total := 0.0
+		for _, n := range nodes {
+			s := g.nodes[n]
+			member := ClusterMember{
+				WorkID:          s.work.ID,
+				StudentID:       s.work.StudentID,
+				Student:         s.work.Student,
+				Version:         s.work.Version,
+				UploadedAt:      s.work.UploadedAt,
+				FirstUploadedAt: s.firstUploadedAt,
+			}
+			for m, sim := range adj[n] {
+				if !in[m] {
+					continue
+				}
+				member.MaxSimilarity = max(member.MaxSimilarity, sim)
+				if n < m {
+					c.Pairs = append(c.Pairs, WorkPair{WorkID: member.WorkID, MatchedWorkID: g.nodes[m].work.ID, Similarity: sim})
+					total += sim
+				}
+			}
+			c.MaxSimilarity = max(c.MaxSimilarity, member.MaxSimilarity)
+			c.Members = append(c.Members, member)
+		}
+		if len(c.Pairs) > 0 {
+			c.AvgSimilarity = total / float64(len(c.Pairs))
+		}
+		c.Density = float64(len(c.Pairs)) / float64(c.Size*(c.Size-1)/2)
+		sort.Slice(c.Pairs, func(i, j int) bool {
+			if c.Pairs[i].Similarity != c.Pairs[j].Similarity {
+				return c.Pairs[i].Similarity > c.Pairs[j].Similarity
+			}
+			return c.Pairs[i].WorkID < c.Pairs[j].WorkID
+		})
+		sort.Slice(c.Members, func(i, j int) bool {
+			a, b := c.Members[i], c.Members[j]
+			if a.FirstUploadedAt != b.FirstUploadedAt {
+				return a.FirstUploadedAt < b.FirstUploadedAt
+			}
+			return a.WorkID < b.WorkID
+		})
+		c.OriginalWorkID = c.Members[0].WorkID
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		a, b := clusters[i], clusters[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.MaxSimilarity != b.MaxSimilarity {
+			return a.MaxSimilarity > b.MaxSimilarity
+		}
+		return a.OriginalWorkID < b.OriginalWorkID
+	})
+	for i := range clusters {
+		clusters[i].ID = i + 1
+	}
+	return clusters
+}
+
+type clustersResponse struct {
+	TaskID    int64     `json:"task_id"`
+	Threshold float64   `json:"threshold"`
+	Method    string    `json:"method"`
+	Works     int       `json:"works"`
+	Clusters  []Cluster `json:"clusters"`
+}
+
+// GetTaskClusters groups the task's submissions that are linked, directly or
+// through others, by stored matches at or above the threshold, so teachers
+// can handle a ring of students sharing one source as one case. With
+// method=communities loosely chained groups are split further.
+func (h *Handler) GetTaskClusters(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	threshold := float64(DefaultClusterThreshold)
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		if threshold, err = strconv.ParseFloat(v, 64); err != nil || threshold <= 0 || threshold > 100 {
+			http.Error(w, "threshold must be greater than 0 and at most 100", http.StatusBadRequest)
+			return
+		}
+	}
+	method := r.URL.Query().Get("method")
+	switch method {
+	case "":
+		method = ClusterComponents
+	case ClusterComponents, ClusterCommunities:
+	default:
+		http.Error(w, fmt.Sprintf("method must be %s or %s", ClusterComponents, ClusterCommunities), http.StatusBadRequest)
+		return
+	}
+
+	g, ok := h.loadSubmissionGraph(w, r, taskID)
+	if !ok {
+		return
+	}
+	adj := g.adjacency(threshold)
+	labels := components(adj)
+	if method == ClusterCommunities {
+		labels = communities(adj)
+	}
+	clusters := g.clusters(adj, labels)
+	if clusters == nil {
+		clusters = []Cluster{}
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &clustersResponse{TaskID: taskID, Threshold: threshold, Method: method, Works: len(g.nodes), Clusters: clusters})
+}
+
+// loadSubmissionGraph builds the task's graph from the works in storage and
+// the stored matches. On failure it writes the error response and returns
+// false.
+func (h *Handler) loadSubmissionGraph(w http.ResponseWriter, r *http.Request, taskID int64) (*submissionGraph, bool) {
+	works, err := h.storage.ListTaskWorks(r.Context(), taskID)
+	if err != nil {
+		slog.Error("failed to list task works", "task_id", taskID, "err", err)
+		http.Error(w, "storage service unavailable", http.StatusBadGateway)
+		return nil, false
+	}
+	ids := make([]int64, len(works))
+	for i, work := range works {
+		ids[i] = work.ID
+	}
+	pairs, err := h.repo.ListWorkPairs(r.Context(), ids)
+	if err != nil {
+		slog.Error("failed to list work pairs", "task_id", taskID, "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	checked, err := h.repo.ListCheckedWorks(r.Context(), ids)
+	if err != nil {
+		slog.Error("failed to list checked works", "task_id", taskID, "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return newSubmissionGraph(works, pairs, checked), true
+}
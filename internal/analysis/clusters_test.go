@@ -0,0 +1,265 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func utcTime(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNewSubmissionGraph(t *testing.T) {
+	works := []Work{
+		{ID: 1, StudentID: 10, Student: "Анна", Version: 1, UploadedAt: "2025-12-01 10:00:00"},
+		{ID: 2, StudentID: 20, Student: "Борис", Version: 1, UploadedAt: "2025-12-02 10:00:00"},
+		{ID: 3, StudentID: 10, Student: "Анна", Version: 2, UploadedAt: "2025-12-03 10:00:00"},
+		{ID: 4, StudentID: 30, Student: "Вера", Version: 1, UploadedAt: "2025-12-04 10:00:00"},
+		// Listed out of order: version 3 is still the latest.
+		{ID: 6, StudentID: 10, Student: "Анна", Version: 3, UploadedAt: "2025-12-06 10:00:00"},
+		{ID: 5, StudentID: 20, Student: "Борис", Version: 2, UploadedAt: "2025-12-05 10:00:00"},
+	}
+	pairs := []WorkPair{
+		{WorkID: 3, MatchedWorkID: 2, Similarity: 40},
+		{WorkID: 5, MatchedWorkID: 1, Similarity: 70},  // older versions count for the students
+		{WorkID: 2, MatchedWorkID: 3, Similarity: 55},  // the pair from the other side
+		{WorkID: 6, MatchedWorkID: 1, Similarity: 99},  // versions of one student
+		{WorkID: 4, MatchedWorkID: 99, Similarity: 90}, // not a work of the task
+		{WorkID: 4, MatchedWorkID: 6, Similarity: 30},
+	}
+	checked := map[int64]time.Time{
+		1: utcTime("2025-12-01 11:00:00"),
+		3: utcTime("2025-12-03 11:00:00"),
+		2: utcTime("2025-12-02 11:00:00"),
+	}
+	g := newSubmissionGraph(works, pairs, checked)
+
+	wantNodes := []submission{
+		{work: works[4], firstUploadedAt: "2025-12-01 10:00:00", checkedAt: "2025-12-03 11:00:00"},
+		{work: works[5], firstUploadedAt: "2025-12-02 10:00:00", checkedAt: "2025-12-02 11:00:00"},
+		{work: works[3], firstUploadedAt: "2025-12-04 10:00:00"},
+	}
+	if !reflect.DeepEqual(g.nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", g.nodes, wantNodes)
+	}
+	wantEdges := map[[2]int]float64{{0, 1}: 70, {0, 2}: 30}
+	if !reflect.DeepEqual(g.edges, wantEdges) {
+		t.Errorf("edges = %v, want %v", g.edges, wantEdges)
+	}
+
+	if !g.compared(0, 1) || !g.compared(1, 0) {
+		t.Error("submissions checked after both were uploaded are not compared")
+	}
+	if g.compared(1, 2) {
+		t.Error("Вера uploaded after Борис's only check, but they count as compared")
+	}
+}
+
+// graph builds adjacency lists from weighted edges between n nodes.
+func graph(n int, edges map[[2]int]float64) []map[int]float64 {
+	adj := make([]map[int]float64, n)
+	for i := range adj {
+		adj[i] = map[int]float64{}
+	}
+	for e, sim := range edges {
+		adj[e[0]][e[1]], adj[e[1]][e[0]] = sim, sim
+	}
+	return adj
+}
+
+func TestAdjacency(t *testing.T) {
+	g := &submissionGraph{nodes: make([]submission, 3), edges: map[[2]int]float64{{0, 1}: 50, {1, 2}: 49.9}}
+	want := graph(3, map[[2]int]float64{{0, 1}: 50})
+	if got := g.adjacency(50); !reflect.DeepEqual(got, want) {
+		t.Errorf("adjacency(50) = %v, want %v: the threshold is inclusive", got, want)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		name string
+		adj  []map[int]float64
+		want []int
+	}{
+		{name: "empty", adj: nil, want: []int{}},
+		{name: "isolated nodes", adj: graph(3, nil), want: []int{0, 1, 2}},
+		{name: "chain", adj: graph(4, map[[2]int]float64{{0, 3}: 60, {3, 1}: 60, {1, 2}: 60}), want: []int{0, 0, 0, 0}},
+		{
+			name: "two groups labelled by their smallest node",
+			adj:  graph(5, map[[2]int]float64{{1, 4}: 80, {2, 3}: 55}),
+			want: []int{0, 1, 2, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := components(tt.adj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("components() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// partition renumbers labels by first appearance, so groupings can be
+// compared whatever label each group ends up with.
+func partition(labels []int) []int {
+	ids := map[int]int{}
+	out := make([]int, len(labels))
+	for i, l := range labels {
+		if _, ok := ids[l]; !ok {
+			ids[l] = len(ids)
+		}
+		out[i] = ids[l]
+	}
+	return out
+}
+
+func TestCommunities(t *testing.T) {
+	tests := []struct {
+		name string
+		adj  []map[int]float64
+		want []int
+	}{
+		{name: "isolated nodes keep their own label", adj: graph(2, nil), want: []int{0, 1}},
+		{name: "pair", adj: graph(2, map[[2]int]float64{{0, 1}: 70}), want: []int{0, 0}},
+		{
+			name: "triangles joined by a weak link fall apart",
+			adj: graph(6, map[[2]int]float64{
+				{0, 1}: 90, {1, 2}: 90, {0, 2}: 90,
+				{3, 4}: 80, {4, 5}: 80, {3, 5}: 80,
+				{2, 3}: 51,
+			}),
+			want: []int{0, 0, 0, 1, 1, 1},
+		},
+		{
+			// Node 1 is pulled equally by 0 and 2 and takes the smaller
+			// label, which 2 then follows.
+			name: "ties go to the smaller label",
+			adj:  graph(3, map[[2]int]float64{{0, 1}: 60, {1, 2}: 60}),
+			want: []int{0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := communities(tt.adj)
+			if got := partition(first); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("communities() = %v, want groups %v", first, tt.want)
+			}
+			for i := 0; i < 5; i++ {
+				if again := communities(tt.adj); !reflect.DeepEqual(again, first) {
+					t.Fatalf("communities() = %v, then %v", first, again)
+				}
+			}
+		})
+	}
+}
+
+func TestClustersOriginal(t *testing.T) {
+	tests := []struct {
+		name     string
+		works    []Work
+		pairs    []WorkPair
+		original int64
+		members  []int64
+	}{
+		{
+			name: "earliest hand-in",
+			works: []Work{
+				{ID: 1, StudentID: 10, Version: 1, UploadedAt: "2025-12-02 10:00:00"},
+				{ID: 2, StudentID: 20, Version: 1, UploadedAt: "2025-12-01 10:00:00"},
+			},
+			pairs:    []WorkPair{{WorkID: 1, MatchedWorkID: 2, Similarity: 80}},
+			original: 2,
+			members:  []int64{2, 1},
+		},
+		{
+			// Student 10 resubmitted last, but handed the task in first.
+			name: "first version counts, not the latest",
+			works: []Work{
+				{ID: 1, StudentID: 10, Version: 1, UploadedAt: "2025-12-01 10:00:00"},
+				{ID: 2, StudentID: 20, Version: 1, UploadedAt: "2025-12-02 10:00:00"},
+				{ID: 3, StudentID: 10, Version: 2, UploadedAt: "2025-12-03 10:00:00"},
+			},
+			pairs:    []WorkPair{{WorkID: 2, MatchedWorkID: 3, Similarity: 80}},
+			original: 3,
+			members:  []int64{3, 2},
+		},
+		{
+			name: "same upload time goes to the smaller work",
+			works: []Work{
+				{ID: 7, StudentID: 10, Version: 1, UploadedAt: "2025-12-01 10:00:00"},
+				{ID: 5, StudentID: 20, Version: 1, UploadedAt: "2025-12-01 10:00:00"},
+			},
+			pairs:    []WorkPair{{WorkID: 7, MatchedWorkID: 5, Similarity: 80}},
+			original: 5,
+			members:  []int64{5, 7},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newSubmissionGraph(tt.works, tt.pairs, nil)
+			adj := g.adjacency(50)
+			clusters := g.clusters(adj, components(adj))
+			if len(clusters) != 1 {
+				t.Fatalf("got %d clusters, want 1", len(clusters))
+			}
+			c := clusters[0]
+			if c.OriginalWorkID != tt.original {
+				t.Errorf("original = %d, want %d", c.OriginalWorkID, tt.original)
+			}
+			var members []int64
+			for _, m := range c.Members {
+				members = append(members, m.WorkID)
+			}
+			if !reflect.DeepEqual(members, tt.members) {
+				t.Errorf("members = %v, want %v", members, tt.members)
+			}
+		})
+	}
+}
+
+func TestClusters(t *testing.T) {
+	works := []Work{
+		{ID: 1, StudentID: 1, UploadedAt: "2025-12-01 10:00:00"},
+		{ID: 2, StudentID: 2, UploadedAt: "2025-12-01 11:00:00"},
+		{ID: 3, StudentID: 3, UploadedAt: "2025-12-01 12:00:00"},
+		{ID: 4, StudentID: 4, UploadedAt: "2025-12-01 13:00:00"},
+		{ID: 5, StudentID: 5, UploadedAt: "2025-12-01 14:00:00"},
+		{ID: 6, StudentID: 6, UploadedAt: "2025-12-01 15:00:00"},
+	}
+	pairs := []WorkPair{
+		{WorkID: 4, MatchedWorkID: 5, Similarity: 95},
+		{WorkID: 1, MatchedWorkID: 2, Similarity: 60},
+		{WorkID: 2, MatchedWorkID: 3, Similarity: 80},
+	}
+	g := newSubmissionGraph(works, pairs, nil)
+	adj := g.adjacency(50)
+	clusters := g.clusters(adj, components(adj))
+
+	want := []Cluster{
+		{
+			ID: 1, Size: 3, MaxSimilarity: 80, AvgSimilarity: 70, Density: 2.0 / 3, OriginalWorkID: 1,
+			Members: []ClusterMember{
+				{WorkID: 1, StudentID: 1, UploadedAt: "2025-12-01 10:00:00", FirstUploadedAt: "2025-12-01 10:00:00", MaxSimilarity: 60},
+				{WorkID: 2, StudentID: 2, UploadedAt: "2025-12-01 11:00:00", FirstUploadedAt: "2025-12-01 11:00:00", MaxSimilarity: 80},
+				{WorkID: 3, StudentID: 3, UploadedAt: "2025-12-01 12:00:00", FirstUploadedAt: "2025-12-01 12:00:00", MaxSimilarity: 80},
+			},
+			Pairs: []WorkPair{{WorkID: 2, MatchedWorkID: 3, Similarity: 80}, {WorkID: 1, MatchedWorkID: 2, Similarity: 60}},
+		},
+		{
+			ID: 2, Size: 2, MaxSimilarity: 95, AvgSimilarity: 95, Density: 1, OriginalWorkID: 4,
+			Members: []ClusterMember{
+				{WorkID: 4, StudentID: 4, UploadedAt: "2025-12-01 13:00:00", FirstUploadedAt: "2025-12-01 13:00:00", MaxSimilarity: 95},
+				{WorkID: 5, StudentID: 5, UploadedAt: "2025-12-01 14:00:00", FirstUploadedAt: "2025-12-01 14:00:00", MaxSimilarity: 95},
+			},
+			Pairs: []WorkPair{{WorkID: 4, MatchedWorkID: 5, Similarity: 95}},
+		},
+	}
+	if !reflect.DeepEqual(clusters, want) {
+		t.Errorf("clusters = %+v\nwant %+v", clusters, want)
+	}
+}
@@ -0,0 +1,263 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"HW_KPO3/internal/config"
+)
+
+const DefaultCodeMinMatch = 9
+
+// Tile is a maximal common run of tokens found by Greedy String Tiling: Len
+// tokens starting at A in the first stream and at B in the second.
+type Tile struct {
+	A   int
+	B   int
+	Len int
+}
+
+// GreedyStringTiling covers the two token streams with non-overlapping tiles
+// of at least minMatch tokens, longest first, as in JPlag. Candidate start
+// positions are found through hashes of minMatch-token windows (Karp-Rabin),
+// so only windows that can start a tile are extended.
+func GreedyStringTiling(a, b []uint32, minMatch int) []Tile {
+	return tileMarked(a, b, minMatch, make([]bool, len(a)), make([]bool, len(b)))
+}
+
+// tileMarked is Greedy String Tiling where tokens already marked can not be
+// part of a tile. The tiles found are marked in place.
+func tileMarked(a, b []uint32, minMatch int, markedA, markedB []bool) []Tile {
+	if minMatch <= 0 {
+		minMatch = DefaultCodeMinMatch
+	}
+	if len(a) < minMatch || len(b) < minMatch {
+		return nil
+	}
+	windows := map[uint64][]int{}
+	for q := 0; q+minMatch <= len(b); q++ {
+		h := windowHash(b[q : q+minMatch])
+		windows[h] = append(windows[h], q)
+	}
+
+	var tiles []Tile
+	for {
+		maxMatch := minMatch
+		var found []Tile
+		for p := 0; p+minMatch <= len(a); p++ {
+			if markedA[p] {
+				continue
+			}
+			for _, q := range windows[windowHash(a[p:p+minMatch])] {
+				if markedB[q] {
+					continue
+				}
+				n := 0
+				for p+n < len(a) && q+n < len(b) && a[p+n] == b[q+n] && !markedA[p+n] && !markedB[q+n] {
+					n++
+				}
+				switch {
+				case n > maxMatch:
+					maxMatch, found = n, []Tile{{A: p, B: q, Len: n}}
+				case n == maxMatch:
+					found = append(found, Tile{A: p, B: q, Len: n})
+				}
+			}
+		}
+		for _, t := range found {
+			if occluded(markedA[t.A:t.A+t.Len]) || occluded(markedB[t.B:t.B+t.Len]) {
+				continue
+			}
+			for i := 0; i < t.Len; i++ {
+				markedA[t.A+i], markedB[t.B+i] = true, true
+			}
+			tiles = append(tiles, t)
+		}
+		if len(found) == 0 || maxMatch == minMatch {
+			break
+		}
+	}
+	return tiles
+}
+
+func windowHash(tokens []uint32) uint64 {
+	h := fnv.New64a()
+	var buf [4]byte
+	for _, t := range tokens {
+		buf[0], buf[1], buf[2], buf[3] = byte(t>>24), byte(t>>16), byte(t>>8), byte(t)
+		_, _ = h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func occluded(marked []bool) bool {
+	for _, m := range marked {
+		if m {
+			return true
+		}
+	}
+	return false
+}
+
+// templateMarks marks the tokens of codes that belong to tiles with any of
+// the templates, and returns the marks and their number.
+func templateMarks(codes []uint32, templates [][]uint32, minMatch int) ([]bool, int) {
+	marks := make([]bool, len(codes))
+	for _, t := range templates {
+		tileMarked(codes, t, minMatch, marks, make([]bool, len(t)))
+	}
+	n := 0
+	for _, m := range marks {
+		if m {
+			n++
+		}
+	}
+	return marks, n
+}
+
+func tokenCodes(tokens []CodeToken) []uint32 {
+	codes := make([]uint32, len(tokens))
+	for i, t := range tokens {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(t.Kind))
+		codes[i] = h.Sum32()
+	}
+	return codes
+}
+
+// CodeMatch is the tiling of the checked work with one other work.
+// Similarity is 2·covered/(|A|+|B|) in percent, the JPlag average
+// similarity, where tokens from templates are not counted in |A| and |B|;
+// MatchedTokens is the number of tokens covered.
+type CodeMatch struct {
+	WorkID        int64
+	Similarity    float64
+	MatchedTokens int
+	Fragments     []Fragment
+}
+
+type CodeResult struct {
+	Language string
+	Tokens   int
+	Excluded int
+	Compared int
+	Matches  []CodeMatch
+}
+
+// CodeDetector compares source files by token structure. Names, literals,
+// comments and layout are not part of the token stream, so renaming and
+// reformatting do not hide copied code. Candidates come from the code index
+// of token streams rather than the word index, which a renamed copy hardly
+// shares any buckets with.
+type CodeDetector struct {
+	repo     *Repository
+	index    *CodeIndex
+	minMatch int
+}
+
+func NewCodeDetector(repo *Repository, index *CodeIndex, cfg config.Code) *CodeDetector {
+	d := &CodeDetector{repo: repo, index: index, minMatch: cfg.MinMatch}
+	if d.minMatch <= 0 {
+		d.minMatch = DefaultCodeMinMatch
+	}
+	return d
+}
+
+// Check tiles the work against the candidates from the code index. Tokens
+// that tile with the task's templates in that language are marked on both
+// sides first, so skeleton code never matches. Works that are not source
+// code give an empty result.
+func (d *CodeDetector) Check(ctx context.Context, sub *Submission) (*CodeResult, error) {
+	lang := DetectLanguage(sub.Work.FileName)
+	result := &CodeResult{Language: lang}
+	if lang == "" {
+		return result, nil
+	}
+	var templates [][]uint32
+	for _, text := range sub.templateTexts(lang) {
+		templates = append(templates, tokenCodes(LexCode(text, lang)))
+	}
+	own, ids, err := d.index.Candidates(ctx, sub, lang)
+	if err != nil {
+		return nil, err
+	}
+	ownMarks, ownExcluded := templateMarks(own.Codes, templates, d.minMatch)
+	result.Tokens, result.Excluded = len(own.Codes), ownExcluded
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	stored, err := d.repo.GetCodeStreams(ctx, ids, lang)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		other, ok := stored[id]
+		if !ok {
+			continue
+		}
+		marks, excluded := templateMarks(other.Codes, templates, d.minMatch)
+		tiles := tileMarked(own.Codes, other.Codes, d.minMatch, append([]bool(nil), ownMarks...), marks)
+		result.Compared++
+		if len(tiles) == 0 {
+			continue
+		}
+
+		match := CodeMatch{WorkID: other.WorkID}
+		for _, t := range tiles {
+			match.MatchedTokens += t.Len
+			match.Fragments = append(match.Fragments, Fragment{
+				WorkID:       other.WorkID,
+				Start:        own.Starts[t.A],
+				End:          own.Ends[t.A+t.Len-1],
+				MatchedStart: other.Starts[t.B],
+				MatchedEnd:   other.Ends[t.B+t.Len-1],
+			})
+		}
+		match.Similarity = 2 * float64(match.MatchedTokens) / float64(len(own.Codes)-ownExcluded+len(other.Codes)-excluded) * 100
+		sort.Slice(match.Fragments, func(i, j int) bool { return match.Fragments[i].Start < match.Fragments[j].Start })
+		if len(match.Fragments) > maxMatchFragments {
+			match.Fragments = match.Fragments[:maxMatchFragments]
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	return result, nil
+}
+
+func (d *CodeDetector) Name() string {
+	return DetectorCode
+}
+
+func (d *CodeDetector) Detect(ctx context.Context, sub *Submission) (*Detection, error) {
+	result, err := d.Check(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	detection := &Detection{Applies: result.Language != "", Details: result.Details()}
+	if result.Tokens > 0 {
+		detection.Excluded = float64(result.Excluded) / float64(result.Tokens) * 100
+	}
+	for _, m := range result.Matches {
+		detection.Matches = append(detection.Matches, Evidence{
+			WorkID:        m.WorkID,
+			Similarity:    m.Similarity,
+			MatchedTokens: m.MatchedTokens,
+			Fragments:     m.Fragments,
+		})
+	}
+	return detection, nil
+}
+
+func (r *CodeResult) Details() string {
+	if r.Language == "" {
+		return ""
+	}
+	best := 0.0
+	for _, m := range r.Matches {
+		best = max(best, m.Similarity)
+	}
+	return fmt.Sprintf("%s code, %d tokens, compared with %d works, best token similarity %.1f%%",
+		r.Language, r.Tokens, r.Compared, best)
+}
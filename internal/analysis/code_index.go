@@ -0,0 +1,129 @@
+package analysis
+
+import (
+	"context"
+	"log/slog"
+
+	"HW_KPO3/internal/config"
+)
+
+// codeCandidateSet is the outcome of the code index lookup for a submission.
+type codeCandidateSet struct {
+	own *CodeStream
+	ids []int64
+}
+
+// CodeIndex is the MinHash/LSH index of the works' token streams. Token kinds
+// do not change when identifiers are renamed or code is reformatted, so such
+// a copy shares buckets with its original even when hardly any of its word
+// shingles do. The code and AST detectors compare a submission only with the
+// candidates it returns.
+type CodeIndex struct {
+	storage *StorageClient
+	repo    *Repository
+	hasher  *MinHasher
+	size    int
+	lsh     config.LSH
+}
+
+// NewCodeIndex makes shingles as long as the shortest run Greedy String
+// Tiling counts, so every tile the code detector could find is a common
+// shingle.
+func NewCodeIndex(storage *StorageClient, repo *Repository, cfg config.Analysis) *CodeIndex {
+	size := cfg.Code.MinMatch
+	if size <= 0 {
+		size = DefaultCodeMinMatch
+	}
+	return &CodeIndex{
+		storage: storage,
+		repo:    repo,
+		hasher:  NewMinHasher(cfg.LSH.NumHashes),
+		size:    size,
+		lsh:     cfg.LSH,
+	}
+}
+
+// CodeShingles hashes every run of size consecutive token codes. Streams
+// shorter than size produce a single shingle of all their tokens.
+func CodeShingles(codes []uint32, size int) ShingleSet {
+	set := ShingleSet{}
+	if len(codes) == 0 {
+		return set
+	}
+	if size <= 0 {
+		size = DefaultCodeMinMatch
+	}
+	size = min(size, len(codes))
+	for i := 0; i+size <= len(codes); i++ {
+		set[windowHash(codes[i:i+size])] = struct{}{}
+	}
+	return set
+}
+
+// Candidates lexes and indexes the work, adds the task's works in the
+// language missing from the index and returns the work's token stream with
+// the live works of other students in the language that share a bucket with
+// it. The lookup is done once per submission and shared by the detectors.
+func (x *CodeIndex) Candidates(ctx context.Context, sub *Submission, lang string) (*CodeStream, []int64, error) {
+	if sub.code != nil {
+		return sub.code.own, sub.code.ids, nil
+	}
+	work := sub.Work
+	settings, err := x.repo.GetLSHSettings(ctx, work.TaskID, x.lsh)
+	if err != nil {
+		return nil, nil, err
+	}
+	own := newCodeStream(work, lang, LexCode(sub.Text, lang))
+	if err := x.index(ctx, own, settings); err != nil {
+		return nil, nil, err
+	}
+	if err := x.indexTask(ctx, sub, lang, settings); err != nil {
+		return nil, nil, err
+	}
+	found, err := x.repo.FindCodeCandidates(ctx, work.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	set := &codeCandidateSet{own: own}
+	for _, w := range otherStudents(sub, found) {
+		if DetectLanguage(w.FileName) == lang {
+			set.ids = append(set.ids, w.ID)
+		}
+	}
+	sub.code = set
+	return set.own, set.ids, nil
+}
+
+func (x *CodeIndex) index(ctx context.Context, s *CodeStream, settings *LSHSettings) error {
+	// An empty stream is stored without buckets so it is not lexed again on
+	// every check, but it never becomes a candidate.
+	s.ShingleSize, s.NumHashes = x.size, x.hasher.Size()
+	s.Signature = x.hasher.Signature(CodeShingles(s.Codes, x.size))
+	return x.repo.SaveCodeStream(ctx, s, s.Signature.Buckets(settings.Bands, settings.Rows))
+}
+
+// indexTask lexes and indexes the task's works in the language that are not
+// in the index yet, e.g. ones checked before it existed, indexed with other
+// parameters or moved here from another task.
+func (x *CodeIndex) indexTask(ctx context.Context, sub *Submission, lang string, settings *LSHSettings) error {
+	indexed, err := x.repo.CodeIndexedWorks(ctx, sub.Work.TaskID, lang, x.size, x.hasher.Size())
+	if err != nil {
+		return err
+	}
+	for i := range sub.TaskWorks {
+		w := &sub.TaskWorks[i]
+		if w.ID == sub.Work.ID || indexed[w.ID] || DetectLanguage(w.FileName) != lang {
+			continue
+		}
+		text, err := x.storage.GetWorkText(ctx, w.ID)
+		if err != nil {
+			slog.Warn("failed to get text of work to index", "work_id", w.ID, "err", err)
+			continue
+		}
+		if err := x.index(ctx, newCodeStream(w, lang, LexCode(text.Text, lang)), settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package analysis
+
+import "testing"
+
+const indexedOriginal = `package main
+
+import "fmt"
+
+func sumPositive(values []int) int {
+	total := 0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+func countWords(text string) map[string]int {
+	counts := map[string]int{}
+	word := ""
+	for _, r := range text {
+		if r == ' ' {
+			if word != "" {
+				counts[word]++
+			}
+			word = ""
+			continue
+		}
+		word += string(r)
+	}
+	if word != "" {
+		counts[word]++
+	}
+	return counts
+}
+
+func main() {
+	fmt.Println(sumPositive([]int{1, -2, 3}))
+	fmt.Println(countWords("a b a"))
+}
+`
+
+// indexedCopy is indexedOriginal with every name changed, comments added,
+// literals changed and the layout redone, plus one extra statement.
+const indexedCopy = `package main
+
+import "fmt"
+
+// Accumulate adds up the numbers above zero.
+func accumulate(xs []int) int {
+	acc := 0
+	for _, x := range xs {
+		if x > 0 { acc += x }
+	}
+	return acc
+}
+
+// Frequencies counts how often each token occurs.
+func frequencies(s string) map[string]int {
+	freq := map[string]int{}
+	tok := ""
+	for _, c := range s {
+		if c == '\t' {
+			if tok != "" { freq[tok]++ }
+			tok = ""
+			continue
+		}
+		tok += string(c)
+	}
+	if tok != "" { freq[tok]++ }
+	return freq
+}
+
+func main() {
+	fmt.Println("start")
+	fmt.Println(accumulate([]int{4, 5, -6}))
+	fmt.Println(frequencies("x y x"))
+}
+`
+
+func TestCodeShingles(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []uint32
+		size  int
+		want  int
+	}{
+		{name: "empty", codes: nil, size: 3, want: 0},
+		{name: "shorter than size is one shingle", codes: seq(0, 2), size: 3, want: 1},
+		{name: "every window", codes: seq(0, 10), size: 3, want: 8},
+		{name: "repeated windows count once", codes: []uint32{1, 2, 1, 2, 1, 2}, size: 2, want: 2},
+		{name: "non-positive size uses the default", codes: seq(0, DefaultCodeMinMatch+1), size: 0, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeShingles(tt.codes, tt.size); len(got) != tt.want {
+				t.Errorf("CodeShingles() has %d shingles, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+// TestCodeIndexFindsRenamedCopy runs both works through the code index
+// pipeline with the default settings: the renamed copy shares hardly any
+// word shingles with the original, but it must land in one of its buckets
+// and then tile with it.
+func TestCodeIndexFindsRenamedCopy(t *testing.T) {
+	const numHashes, bands, rows = 128, 64, 2
+	hasher := NewMinHasher(numHashes)
+
+	words := EstimateJaccard(hasher.Signature(Shingles(Words(indexedOriginal), DefaultShingleSize)),
+		hasher.Signature(Shingles(Words(indexedCopy), DefaultShingleSize)))
+	if words > 0.2 {
+		t.Fatalf("word shingle similarity %.2f, the copy is not disguised enough for the test", words)
+	}
+
+	original := tokenCodes(LexCode(indexedOriginal, LangGo))
+	copied := tokenCodes(LexCode(indexedCopy, LangGo))
+	a := hasher.Signature(CodeShingles(original, DefaultCodeMinMatch))
+	b := hasher.Signature(CodeShingles(copied, DefaultCodeMinMatch))
+	if j := EstimateJaccard(a, b); j < 0.5 {
+		t.Errorf("token shingle similarity %.2f, want at least 0.5", j)
+	}
+
+	shared := 0
+	for i, bucket := range a.Buckets(bands, rows) {
+		if b.Buckets(bands, rows)[i] == bucket {
+			shared++
+		}
+	}
+	if shared == 0 {
+		t.Fatal("the renamed copy shares no bucket with the original")
+	}
+
+	covered := 0
+	for _, tile := range GreedyStringTiling(original, copied, DefaultCodeMinMatch) {
+		covered += tile.Len
+	}
+	if sim := 2 * float64(covered) / float64(len(original)+len(copied)) * 100; sim < 90 {
+		t.Errorf("token similarity %.1f%%, want at least 90%%", sim)
+	}
+}
@@ -0,0 +1,259 @@
+package analysis
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Source languages the code detector understands.
+const (
+	LangGo     = "go"
+	LangPython = "python"
+	LangJava   = "java"
+	LangC      = "c"
+	LangCPP    = "cpp"
+)
+
+// Token classes that replace names and literals, so that renaming variables
+// or changing constants does not change the token stream.
+const (
+	tokIdent  = "ID"
+	tokNumber = "NUM"
+	tokString = "STR"
+	tokChar   = "CHR"
+)
+
+var languageByExt = map[string]string{
+	".go":   LangGo,
+	".py":   LangPython,
+	".java": LangJava,
+	".c":    LangC,
+	".h":    LangC,
+	".cpp":  LangCPP,
+	".cc":   LangCPP,
+	".cxx":  LangCPP,
+	".hpp":  LangCPP,
+	".hh":   LangCPP,
+}
+
+// DetectLanguage tells the source language from the file name, or "" when
+// the file is not source code.
+func DetectLanguage(fileName string) string {
+	return languageByExt[strings.ToLower(filepath.Ext(fileName))]
+}
+
+func keywordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+var cKeywords = `auto break case char const continue default do double else enum extern
+	float for goto if inline int long register restrict return short signed sizeof static
+	struct switch typedef union unsigned void volatile while bool true false NULL`
+
+var keywords = map[string]map[string]bool{
+	LangGo: keywordSet(`break case chan const continue default defer else fallthrough for func
+		go goto if import interface map package range return select struct switch type var
+		nil true false append len cap make new panic recover`),
+	LangPython: keywordSet(`False None True and as assert async await break class continue def
+		del elif else except finally for from global if import in is lambda nonlocal not or
+		pass raise return try while with yield print len range self`),
+	LangJava: keywordSet(`abstract assert boolean break byte case catch char class const continue
+		default do double else enum extends final finally float for goto if implements import
+		instanceof int interface long native new package private protected public return short
+		static strictfp super switch synchronized this throw throws transient try void volatile
+		while true false null var record`),
+	LangC: keywordSet(cKeywords),
+	LangCPP: keywordSet(cKeywords + ` class namespace template typename public private protected
+		virtual override new delete this throw try catch using operator friend nullptr
+		constexpr auto explicit mutable static_cast dynamic_cast reinterpret_cast const_cast std`),
+}
+
+// Operators and punctuation, longest first so the lexer takes the longest
+// match.
+var operators = []string{
+	">>>=", "<<=", ">>=", "...", "&^=", "**=", "//=", "->*", "<=>", ">>>",
+	"&&", "||", "==", "!=", "<=", ">=", "++", "--", "+=", "-=", "*=", "/=", "%=", "&=",
+	"|=", "^=", "<<", ">>", "->", "::", ":=", "<-", "&^", "**", "//", "@",
+	"+", "-", "*", "/", "%", "&", "|", "^", "!", "~", "<", ">", "=", "?", ":",
+	";", ",", ".", "(", ")", "[", "]", "{", "}", "#",
+}
+
+// CodeToken is one lexical token with its span in the source, in characters.
+type CodeToken struct {
+	Kind  string
+	Start int
+	End   int
+}
+
+// LexCode turns source into a stream of token classes: keywords and
+// operators stay as they are, identifiers and literals are replaced by their
+// class, comments, whitespace and invisible characters are dropped.
+func LexCode(src, lang string) []CodeToken {
+	text := []rune(src)
+	kw := keywords[lang]
+	var tokens []CodeToken
+	emit := func(kind string, start, end int) {
+		tokens = append(tokens, CodeToken{Kind: kind, Start: start, End: end})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case unicode.IsSpace(c) || isInvisible(c):
+			i++
+
+		// Comments.
+		case lang == LangPython && c == '#':
+			i = skipLine(text, i)
+		case lang != LangPython && c == '/' && at(text, i+1) == '/':
+			i = skipLine(text, i)
+		case lang != LangPython && c == '/' && at(text, i+1) == '*':
+			end := indexFrom(text, i+2, "*/")
+			if end < 0 {
+				i = len(text)
+			} else {
+				i = end + 2
+			}
+
+		// Literals.
+		case lang == LangPython && isStringStart(text, i):
+			start := i
+			for unicode.IsLetter(text[i]) {
+				i++
+			}
+			i = skipPythonString(text, i)
+			emit(tokString, start, i)
+		case c == '"' || (lang == LangGo && c == '`'):
+			start := i
+			i = skipQuoted(text, i, c, c == '"')
+			emit(tokString, start, i)
+		case c == '\'':
+			start := i
+			i = skipQuoted(text, i, '\'', true)
+			if lang == LangPython {
+				emit(tokString, start, i)
+			} else {
+				emit(tokChar, start, i)
+			}
+		case unicode.IsDigit(c) || (c == '.' && unicode.IsDigit(at(text, i+1))):
+			start := i
+			for i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsDigit(text[i]) || text[i] == '.' || text[i] == '_' ||
+				((text[i] == '+' || text[i] == '-') && (text[i-1] == 'e' || text[i-1] == 'E' || text[i-1] == 'p' || text[i-1] == 'P'))) {
+				i++
+			}
+			emit(tokNumber, start, i)
+
+		// Names.
+		case unicode.IsLetter(c) || c == '_' || c == '$':
+			start := i
+			for i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsDigit(text[i]) || text[i] == '_' || text[i] == '$' ||
+				isInvisible(text[i])) {
+				i++
+			}
+			if word := dropInvisible(string(text[start:i])); kw[word] {
+				emit(word, start, i)
+			} else {
+				emit(tokIdent, start, i)
+			}
+
+		default:
+			op := matchOperator(text, i)
+			if op == "" {
+				// Stray characters carry no structure.
+				i++
+				continue
+			}
+			emit(op, i, i+len([]rune(op)))
+			i += len([]rune(op))
+		}
+	}
+	return tokens
+}
+
+func at(text []rune, i int) rune {
+	if i < 0 || i >= len(text) {
+		return 0
+	}
+	return text[i]
+}
+
+func skipLine(text []rune, i int) int {
+	for i < len(text) && text[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+func indexFrom(text []rune, from int, s string) int {
+	if from > len(text) {
+		return -1
+	}
+	idx := strings.Index(string(text[from:]), s)
+	if idx < 0 {
+		return -1
+	}
+	return from + len([]rune(string(text[from:])[:idx]))
+}
+
+// skipQuoted returns the index after the literal opened at i. With escapes,
+// a backslash escapes the next character. An unterminated literal ends at the
+// end of the line.
+func skipQuoted(text []rune, i int, quote rune, escapes bool) int {
+	i++
+	for i < len(text) {
+		switch {
+		case escapes && text[i] == '\\':
+			i += 2
+			continue
+		case text[i] == quote:
+			return i + 1
+		case text[i] == '\n' && quote != '`':
+			return i
+		}
+		i++
+	}
+	return len(text)
+}
+
+// isStringStart reports a Python string literal at i, including prefixed
+// ones such as r"...", b'...' and f"""...""".
+func isStringStart(text []rune, i int) bool {
+	j := i
+	for j < len(text) && j-i < 2 && strings.ContainsRune("rRbBuUfF", text[j]) {
+		j++
+	}
+	if j > i && i > 0 && (unicode.IsLetter(text[i-1]) || unicode.IsDigit(text[i-1]) || text[i-1] == '_') {
+		return false
+	}
+	return j < len(text) && (text[j] == '"' || text[j] == '\'')
+}
+
+func skipPythonString(text []rune, i int) int {
+	q := text[i]
+	if at(text, i+1) == q && at(text, i+2) == q {
+		end := indexFrom(text, i+3, strings.Repeat(string(q), 3))
+		if end < 0 {
+			return len(text)
+		}
+		return end + 3
+	}
+	return skipQuoted(text, i, q, true)
+}
+
+func matchOperator(text []rune, i int) string {
+	for _, op := range operators {
+		n := len(op)
+		if i+n > len(text) {
+			continue
+		}
+		if string(text[i:i+n]) == op {
+			return op
+		}
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CodeStream is the stored token stream of a source file: the code of every
+// token's kind and its span in characters of the extracted text, with the
+// MinHash signature of its token shingles that places it in the code index.
+type CodeStream struct {
+	WorkID      int64
+	TaskID      int64
+	StudentID   int64
+	Language    string
+	Codes       []uint32
+	Starts      []int
+	Ends        []int
+	ShingleSize int
+	NumHashes   int
+	Signature   Signature
+}
+
+func newCodeStream(work *Work, lang string, tokens []CodeToken) *CodeStream {
+	s := &CodeStream{
+		WorkID:    work.ID,
+		TaskID:    work.TaskID,
+		StudentID: work.StudentID,
+		Language:  lang,
+		Codes:     tokenCodes(tokens),
+		Starts:    make([]int, len(tokens)),
+		Ends:      make([]int, len(tokens)),
+	}
+	for i, t := range tokens {
+		s.Starts[i], s.Ends[i] = t.Start, t.End
+	}
+	return s
+}
+
+// SaveCodeStream replaces the token stream of a work and its buckets in the
+// code index.
+func (r Repository) SaveCodeStream(ctx context.Context, s *CodeStream, buckets []int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save code stream: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	codes := make([]int64, len(s.Codes))
+	for i, c := range s.Codes {
+		codes[i] = int64(c)
+	}
+	const upsert = `
+    INSERT INTO work_code_streams (work_id, task_id, student_id, language, codes, starts, ends, shingle_size, num_hashes, signature)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (work_id) DO UPDATE SET task_id = EXCLUDED.task_id, student_id = EXCLUDED.student_id,
+        language = EXCLUDED.language, codes = EXCLUDED.codes, starts = EXCLUDED.starts, ends = EXCLUDED.ends,
+        shingle_size = EXCLUDED.shingle_size, num_hashes = EXCLUDED.num_hashes, signature = EXCLUDED.signature,
+        created_at = NOW();`
+	if _, err := tx.Exec(ctx, upsert, s.WorkID, s.TaskID, s.StudentID, s.Language, codes, s.Starts, s.Ends,
+		s.ShingleSize, s.NumHashes, toInt64s(s.Signature)); err != nil {
+		return fmt.Errorf("failed to save code stream: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM code_lsh_buckets WHERE work_id = $1;`, s.WorkID); err != nil {
+		return fmt.Errorf("failed to clear code lsh buckets: %w", err)
+	}
+	if err := insertCodeBuckets(ctx, tx, s.TaskID, s.Language, s.WorkID, buckets); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save code stream: %w", err)
+	}
+	return nil
+}
+
+func insertCodeBuckets(ctx context.Context, tx pgx.Tx, taskID int64, lang string, workID int64, buckets []int64) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	bands := make([]int32, len(buckets))
+	for i := range bands {
+		bands[i] = int32(i)
+	}
+	const query = `
+    INSERT INTO code_lsh_buckets (task_id, language, band, bucket, work_id)
+    SELECT $1, $2, band, bucket, $3 FROM unnest($4::int[], $5::bigint[]) AS b (band, bucket)
+    ON CONFLICT DO NOTHING;`
+	if _, err := tx.Exec(ctx, query, taskID, lang, workID, bands, buckets); err != nil {
+		return fmt.Errorf("failed to insert code lsh buckets: %w", err)
+	}
+	return nil
+}
+
+// CodeIndexedWorks returns the works of a task in the language whose token
+// stream was indexed with the given parameters.
+func (r Repository) CodeIndexedWorks(ctx context.Context, taskID int64, lang string, shingleSize, numHashes int) (map[int64]bool, error) {
+	const query = `
+    SELECT work_id FROM work_code_streams
+    WHERE task_id = $1 AND language = $2 AND shingle_size = $3 AND num_hashes = $4;`
+
+	rows, err := r.pool.Query(ctx, query, taskID, lang, shingleSize, numHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list code indexed works: %w", err)
+	}
+	defer rows.Close()
+	indexed := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list code indexed works: %w", err)
+		}
+		indexed[id] = true
+	}
+	return indexed, rows.Err()
+}
+
+// FindCodeCandidates returns the works whose token streams share at least one
+// bucket of the code index with the work's, in the same task and language.
+func (r Repository) FindCodeCandidates(ctx context.Context, workID int64) ([]int64, error) {
+	const query = `
+    SELECT DISTINCT other.work_id
+    FROM code_lsh_buckets own
+    JOIN code_lsh_buckets other
+      ON other.task_id = own.task_id AND other.language = own.language
+     AND other.band = own.band AND other.bucket = own.bucket
+    WHERE own.work_id = $1 AND other.work_id <> own.work_id
+    ORDER BY other.work_id;`
+
+	rows, err := r.pool.Query(ctx, query, workID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find code candidates: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to find code candidates: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// rebuildCodeBuckets lays the stored token stream signatures of a task out
+// in the buckets of new settings.
+func rebuildCodeBuckets(ctx context.Context, tx pgx.Tx, s *LSHSettings) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM code_lsh_buckets WHERE task_id = $1;`, s.TaskID); err != nil {
+		return fmt.Errorf("failed to clear code lsh buckets: %w", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT work_id, language, signature FROM work_code_streams WHERE task_id = $1;`, s.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to read code signatures: %w", err)
+	}
+	type entry struct {
+		lang string
+		keys []int64
+	}
+	buckets := map[int64]entry{}
+	for rows.Next() {
+		var workID int64
+		var lang string
+		var values []int64
+		if err := rows.Scan(&workID, &lang, &values); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read code signatures: %w", err)
+		}
+		buckets[workID] = entry{lang: lang, keys: fromInt64s(values).Buckets(s.Bands, s.Rows)}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read code signatures: %w", err)
+	}
+	for workID, e := range buckets {
+		if err := insertCodeBuckets(ctx, tx, s.TaskID, e.lang, workID, e.keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCodeStreams returns the stored token streams of those of the works that
+// have one in the language.
+func (r Repository) GetCodeStreams(ctx context.Context, workIDs []int64, lang string) (map[int64]*CodeStream, error) {
+	const query = `
+    SELECT work_id, task_id, student_id, language, codes, starts, ends
+    FROM work_code_streams
+    WHERE work_id = ANY($1) AND language = $2;`
+
+	rows, err := r.pool.Query(ctx, query, workIDs, lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code streams: %w", err)
+	}
+	defer rows.Close()
+	streams := map[int64]*CodeStream{}
+	for rows.Next() {
+		var s CodeStream
+		var codes []int64
+		if err := rows.Scan(&s.WorkID, &s.TaskID, &s.StudentID, &s.Language, &codes, &s.Starts, &s.Ends); err != nil {
+			return nil, fmt.Errorf("failed to get code streams: %w", err)
+		}
+		s.Codes = make([]uint32, len(codes))
+		for i, c := range codes {
+			s.Codes[i] = uint32(c)
+		}
+		streams[s.WorkID] = &s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get code streams: %w", err)
+	}
+	return streams, nil
+}
+
+// DeleteCodeStream drops a withdrawn work's token stream and takes it out of
+// the code index.
+func (r Repository) DeleteCodeStream(ctx context.Context, workID int64) error {
+	const query = `
+    WITH buckets AS (DELETE FROM code_lsh_buckets WHERE work_id = $1)
+    DELETE FROM work_code_streams WHERE work_id = $1;`
+
+	if _, err := r.pool.Exec(ctx, query, workID); err != nil {
+		return fmt.Errorf("failed to delete code stream: %w", err)
+	}
+	return nil
+}
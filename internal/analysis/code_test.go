@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+// seq is the token stream from, from+1, ..., to-1.
+func seq(from, to uint32) []uint32 {
+	s := make([]uint32, 0, to-from)
+	for t := from; t < to; t++ {
+		s = append(s, t)
+	}
+	return s
+}
+
+func concat(parts ...[]uint32) []uint32 {
+	var out []uint32
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestGreedyStringTiling(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []uint32
+		minMatch int
+		want     []Tile
+	}{
+		{name: "empty", a: nil, b: seq(0, 10), minMatch: 3, want: nil},
+		{name: "shorter than minMatch", a: seq(0, 2), b: seq(0, 2), minMatch: 3, want: nil},
+		{name: "common run below minMatch", a: concat(seq(0, 2), seq(10, 14)), b: concat(seq(0, 2), seq(20, 24)), minMatch: 3, want: nil},
+		{name: "identical", a: seq(0, 12), b: seq(0, 12), minMatch: 3, want: []Tile{{A: 0, B: 0, Len: 12}}},
+		{
+			name:     "shifted copy",
+			a:        concat(seq(100, 104), seq(0, 6)),
+			b:        concat(seq(0, 6), seq(200, 203)),
+			minMatch: 3,
+			want:     []Tile{{A: 4, B: 0, Len: 6}},
+		},
+		{
+			name:     "swapped blocks are tiled longest first",
+			a:        concat(seq(0, 5), seq(50, 58)),
+			b:        concat(seq(50, 58), seq(0, 5)),
+			minMatch: 3,
+			want:     []Tile{{A: 5, B: 0, Len: 8}, {A: 0, B: 8, Len: 5}},
+		},
+		{
+			name:     "repeated tokens are covered once",
+			a:        []uint32{1, 1, 1, 1},
+			b:        []uint32{1, 1, 1, 1, 1, 1},
+			minMatch: 2,
+			want:     []Tile{{A: 0, B: 0, Len: 4}},
+		},
+		{
+			name:     "non-positive minMatch uses the default",
+			a:        seq(0, DefaultCodeMinMatch),
+			b:        seq(0, DefaultCodeMinMatch),
+			minMatch: 0,
+			want:     []Tile{{A: 0, B: 0, Len: DefaultCodeMinMatch}},
+		},
+		{
+			name:     "default minMatch rejects shorter runs",
+			a:        seq(0, DefaultCodeMinMatch-1),
+			b:        seq(0, DefaultCodeMinMatch-1),
+			minMatch: -1,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GreedyStringTiling(tt.a, tt.b, tt.minMatch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GreedyStringTiling = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGreedyStringTilingTilesDoNotOverlap(t *testing.T) {
+	a := concat(seq(0, 6), seq(0, 6), seq(10, 20), seq(3, 9))
+	b := concat(seq(10, 20), seq(0, 9), seq(0, 6))
+	tiles := GreedyStringTiling(a, b, 3)
+	if len(tiles) == 0 {
+		t.Fatal("no tiles")
+	}
+	usedA, usedB := make([]bool, len(a)), make([]bool, len(b))
+	for _, tile := range tiles {
+		if tile.Len < 3 {
+			t.Errorf("tile %+v is shorter than minMatch", tile)
+		}
+		for i := 0; i < tile.Len; i++ {
+			if a[tile.A+i] != b[tile.B+i] {
+				t.Fatalf("tile %+v covers different tokens", tile)
+			}
+			if usedA[tile.A+i] || usedB[tile.B+i] {
+				t.Fatalf("tile %+v overlaps an earlier tile", tile)
+			}
+			usedA[tile.A+i], usedB[tile.B+i] = true, true
+		}
+	}
+	for i := 1; i < len(tiles); i++ {
+		if tiles[i].Len > tiles[i-1].Len {
+			t.Errorf("tile %+v found after the shorter %+v", tiles[i], tiles[i-1])
+		}
+	}
+}
+
+func TestTileMarked(t *testing.T) {
+	a := seq(0, 10)
+	b := seq(0, 10)
+	tests := []struct {
+		name    string
+		markedA []int
+		markedB []int
+		want    []Tile
+	}{
+		{name: "nothing marked", want: []Tile{{A: 0, B: 0, Len: 10}}},
+		{name: "a mark splits the tile", markedA: []int{5}, want: []Tile{{A: 0, B: 0, Len: 5}, {A: 6, B: 6, Len: 4}}},
+		{name: "marks on the other side count too", markedB: []int{0, 1, 2, 3}, want: []Tile{{A: 4, B: 4, Len: 6}}},
+		{name: "runs left below minMatch are dropped", markedA: []int{2, 7}, want: []Tile{{A: 3, B: 3, Len: 4}}},
+		{name: "everything marked", markedA: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markedA, markedB := make([]bool, len(a)), make([]bool, len(b))
+			for _, i := range tt.markedA {
+				markedA[i] = true
+			}
+			for _, i := range tt.markedB {
+				markedB[i] = true
+			}
+			got := tileMarked(a, b, 3, markedA, markedB)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("tileMarked = %+v, want %+v", got, tt.want)
+			}
+			for _, tile := range got {
+				for i := 0; i < tile.Len; i++ {
+					if !markedA[tile.A+i] || !markedB[tile.B+i] {
+						t.Errorf("tile %+v is not marked in place", tile)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateMarks(t *testing.T) {
+	code := concat(seq(0, 5), seq(100, 110), seq(5, 8))
+	tests := []struct {
+		name      string
+		templates [][]uint32
+		want      int
+	}{
+		{name: "no templates", templates: nil, want: 0},
+		{name: "empty template", templates: [][]uint32{nil}, want: 0},
+		{name: "skeleton", templates: [][]uint32{seq(0, 8)}, want: 8},
+		{name: "two templates", templates: [][]uint32{seq(0, 5), seq(100, 104)}, want: 9},
+		{name: "overlapping templates count once", templates: [][]uint32{seq(100, 106), seq(103, 110)}, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marks, n := templateMarks(code, tt.templates, 3)
+			if n != tt.want {
+				t.Errorf("templateMarks marked %d tokens, want %d", n, tt.want)
+			}
+			count := 0
+			for _, m := range marks {
+				if m {
+					count++
+				}
+			}
+			if count != n {
+				t.Errorf("%d marks set, reported %d", count, n)
+			}
+		})
+	}
+}
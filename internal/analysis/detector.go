@@ -0,0 +1,208 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names the built-in detectors are registered and configured under.
+const (
+	DetectorShingles  = "shingles"
+	DetectorWinnowing = "winnowing"
+	DetectorCode      = "code"
+	DetectorAST       = "ast"
+)
+
+// Detector is one plagiarism check. It compares a submission with the other
+// works of its task and returns a score and evidence for every work it found
+// similar. New checks only have to implement it and be registered; the queue
+// and the HTTP handlers do not know about particular detectors.
+type Detector interface {
+	Name() string
+	Detect(ctx context.Context, sub *Submission) (*Detection, error)
+}
+
+// Detection is the outcome of one detector. A detector that has nothing to
+// say about the kind of work, such as a source code check on an essay, leaves
+// Applies false and is left out of the combined score. Excluded is the share
+// of the work, in the detector's own units, that it left out as template
+// content or citations, in percent.
+type Detection struct {
+	Applies  bool
+	Details  string
+	Excluded float64
+	Matches  []Evidence
+}
+
+// Evidence is what a detector found against one other work: a similarity in
+// percent and, where the detector has them, matched tokens, fragments and
+// function pairs.
+type Evidence struct {
+	WorkID        int64
+	Similarity    float64
+	MatchedTokens int
+	Fragments     []Fragment
+	Functions     []FuncMatch
+}
+
+// Registry holds the available detectors in the order they run.
+type Registry struct {
+	detectors []Detector
+	byName    map[string]Detector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]Detector{}}
+}
+
+// Register adds a detector. Names must be unique, since per-task settings
+// refer to detectors by name.
+func (r *Registry) Register(d Detector) error {
+	name := d.Name()
+	if name == "" {
+		return fmt.Errorf("detector has no name")
+	}
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("detector %q is already registered", name)
+	}
+	r.detectors = append(r.detectors, d)
+	r.byName[name] = d
+	return nil
+}
+
+func (r *Registry) Get(name string) (Detector, bool) {
+	d, ok := r.byName[name]
+	return d, ok
+}
+
+func (r *Registry) Detectors() []Detector {
+	return r.detectors
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.detectors))
+	for i, d := range r.detectors {
+		names[i] = d.Name()
+	}
+	return names
+}
+
+// DetectorScore is one detector's part in a report: its settings for the
+// task, its best score and the work it was found with. Applied is false when
+// the detector is disabled for the task or does not apply to the work.
+type DetectorScore struct {
+	Detector      string  `json:"detector"`
+	Enabled       bool    `json:"enabled"`
+	Applied       bool    `json:"applied"`
+	Weight        float64 `json:"weight"`
+	Threshold     float64 `json:"threshold"`
+	Similarity    float64 `json:"similarity"`
+	MatchedWorkID *int64  `json:"matched_work_id"`
+	Excluded      float64 `json:"excluded"`
+	Details       string  `json:"details"`
+}
+
+// detectorRun is a detector's settings for the task and, if it ran, its
+// detection.
+type detectorRun struct {
+	settings  DetectorSettings
+	detection *Detection
+}
+
+func (run *detectorRun) applied() bool {
+	return run.settings.Enabled && run.detection != nil && run.detection.Applies
+}
+
+// combine turns the detections into one match per counterpart, best first,
+// and the per-detector breakdown. The similarity with a counterpart is the
+// weighted mean of the scores of the detectors that applied; a score below
+// its detector's threshold counts as zero. The fragments and matched tokens
+// come from the highest-scoring detector that has fragments, so the evidence
+// shown is the most convincing one.
+func combine(sub *Submission, runs []detectorRun) ([]ReportMatch, []DetectorScore) {
+	scores := make([]DetectorScore, 0, len(runs))
+	totalWeight := 0.0
+	for i := range runs {
+		run := &runs[i]
+		score := DetectorScore{
+			Detector:  run.settings.Detector,
+			Enabled:   run.settings.Enabled,
+			Applied:   run.applied(),
+			Weight:    run.settings.Weight,
+			Threshold: run.settings.Threshold,
+		}
+		if run.detection != nil {
+			score.Details, score.Excluded = run.detection.Details, run.detection.Excluded
+			for _, e := range run.detection.Matches {
+				if e.Similarity > score.Similarity {
+					id := e.WorkID
+					score.Similarity, score.MatchedWorkID = e.Similarity, &id
+				}
+			}
+		}
+		if score.Applied {
+			totalWeight += score.Weight
+		}
+		scores = append(scores, score)
+	}
+
+	byWork := map[int64]*ReportMatch{}
+	evidenceScore := map[int64]float64{}
+	weighted := map[int64]float64{}
+	for _, run := range runs {
+		if !run.applied() {
+			continue
+		}
+		for _, e := range run.detection.Matches {
+			m, ok := byWork[e.WorkID]
+			if !ok {
+				m = &ReportMatch{WorkID: e.WorkID, Fragments: []Fragment{}, Scores: map[string]float64{}}
+				byWork[e.WorkID] = m
+			}
+			m.Scores[run.settings.Detector] = e.Similarity
+			if e.Similarity >= run.settings.Threshold {
+				weighted[e.WorkID] += run.settings.Weight * e.Similarity
+			}
+			if len(e.Fragments) > 0 && e.Similarity > evidenceScore[e.WorkID] {
+				evidenceScore[e.WorkID] = e.Similarity
+				m.Fragments, m.MatchedTokens = e.Fragments, e.MatchedTokens
+			}
+			m.Functions = append(m.Functions, e.Functions...)
+		}
+	}
+
+	for _, w := range sub.TaskWorks {
+		if m, ok := byWork[w.ID]; ok {
+			m.StudentID, m.Student = w.StudentID, w.Student
+		}
+	}
+	matches := make([]ReportMatch, 0, len(byWork))
+	for id, m := range byWork {
+		if totalWeight > 0 {
+			m.Similarity = weighted[id] / totalWeight
+		}
+		if m.Similarity > 0 {
+			matches = append(matches, *m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].WorkID < matches[j].WorkID
+	})
+	return matches, scores
+}
+
+// joinDetails is the human-readable summary of the detectors that ran.
+func joinDetails(scores []DetectorScore) string {
+	var parts []string
+	for _, s := range scores {
+		if s.Details != "" {
+			parts = append(parts, s.Details)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
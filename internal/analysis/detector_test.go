@@ -0,0 +1,218 @@
+package analysis
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type fakeDetector string
+
+func (d fakeDetector) Name() string { return string(d) }
+
+func (d fakeDetector) Detect(context.Context, *Submission) (*Detection, error) {
+	return &Detection{}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{DetectorShingles, DetectorCode} {
+		if err := r.Register(fakeDetector(name)); err != nil {
+			t.Fatalf("Register(%s): %v", name, err)
+		}
+	}
+	if err := r.Register(fakeDetector(DetectorShingles)); err == nil {
+		t.Error("a second detector with the same name was registered")
+	}
+	if err := r.Register(fakeDetector("")); err == nil {
+		t.Error("a detector without a name was registered")
+	}
+	if got, want := r.Names(), []string{DetectorShingles, DetectorCode}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if _, ok := r.Get(DetectorAST); ok {
+		t.Error("Get() found a detector that was never registered")
+	}
+}
+
+// run is a detector that applies with the given settings and found the
+// works with the given similarities.
+func run(name string, enabled bool, weight, threshold float64, found map[int64]float64) detectorRun {
+	d := &Detection{Applies: true}
+	for id, sim := range found {
+		d.Matches = append(d.Matches, Evidence{WorkID: id, Similarity: sim})
+	}
+	return detectorRun{
+		settings:  DetectorSettings{Detector: name, Enabled: enabled, Weight: weight, Threshold: threshold},
+		detection: d,
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name string
+		runs []detectorRun
+		want map[int64]float64 // combined similarity by work
+	}{
+		{name: "no detectors", runs: nil, want: map[int64]float64{}},
+		{
+			name: "weighted mean",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 1, 0, map[int64]float64{2: 40}),
+				run(DetectorWinnowing, true, 3, 0, map[int64]float64{2: 80}),
+			},
+			want: map[int64]float64{2: 70},
+		},
+		{
+			name: "a work one detector missed scores zero there",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 1, 0, map[int64]float64{2: 60, 3: 30}),
+				run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 40}),
+			},
+			want: map[int64]float64{2: 50, 3: 15},
+		},
+		{
+			name: "zero weight counts for nothing",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 0, 0, map[int64]float64{2: 90}),
+				run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{2: 30},
+		},
+		{
+			name: "all weights zero",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 0, 0, map[int64]float64{2: 90}),
+				run(DetectorWinnowing, true, 0, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{},
+		},
+		{
+			// A task that never configured its detectors gets the defaults.
+			name: "missing settings weigh the same",
+			runs: []detectorRun{
+				{settings: defaultDetectorSettings(DetectorShingles), detection: &Detection{Applies: true, Matches: []Evidence{{WorkID: 2, Similarity: 20}}}},
+				{settings: defaultDetectorSettings(DetectorWinnowing), detection: &Detection{Applies: true, Matches: []Evidence{{WorkID: 2, Similarity: 60}}}},
+			},
+			want: map[int64]float64{2: 40},
+		},
+		{
+			name: "disabled detector is left out of the mean",
+			runs: []detectorRun{
+				run(DetectorShingles, false, 1, 0, map[int64]float64{2: 100, 3: 100}),
+				run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{2: 30},
+		},
+		{
+			name: "detector that does not apply is left out of the mean",
+			runs: []detectorRun{
+				{settings: defaultDetectorSettings(DetectorCode), detection: &Detection{Matches: []Evidence{{WorkID: 2, Similarity: 100}}}},
+				{settings: defaultDetectorSettings(DetectorAST)},
+				run(DetectorShingles, true, 1, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{2: 30},
+		},
+		{
+			name: "score at the threshold counts",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 1, 50, map[int64]float64{2: 50}),
+				run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{2: 40},
+		},
+		{
+			name: "score just below the threshold counts as zero",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 1, 50, map[int64]float64{2: 49.99}),
+				run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 30}),
+			},
+			want: map[int64]float64{2: 15},
+		},
+		{
+			name: "below every threshold",
+			runs: []detectorRun{
+				run(DetectorShingles, true, 1, 50, map[int64]float64{2: 49}),
+				run(DetectorWinnowing, true, 1, 100, map[int64]float64{2: 99}),
+			},
+			want: map[int64]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, scores := combine(&Submission{}, tt.runs)
+			got := map[int64]float64{}
+			for _, m := range matches {
+				got[m.WorkID] = m.Similarity
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("combine() = %v, want %v", got, tt.want)
+			}
+			for id, want := range tt.want {
+				if math.Abs(got[id]-want) > 1e-9 {
+					t.Errorf("similarity with %d = %v, want %v", id, got[id], want)
+				}
+			}
+			if len(scores) != len(tt.runs) {
+				t.Errorf("got %d detector scores, want one per detector (%d)", len(scores), len(tt.runs))
+			}
+		})
+	}
+}
+
+func TestCombineScores(t *testing.T) {
+	runs := []detectorRun{
+		run(DetectorShingles, false, 2, 10, map[int64]float64{3: 70}),
+		{settings: defaultDetectorSettings(DetectorCode), detection: &Detection{Details: "not code"}},
+		run(DetectorWinnowing, true, 1, 0, map[int64]float64{2: 30, 3: 45}),
+	}
+	_, scores := combine(&Submission{}, runs)
+	three := int64(3)
+	want := []DetectorScore{
+		// A disabled detector still shows what it found.
+		{Detector: DetectorShingles, Enabled: false, Applied: false, Weight: 2, Threshold: 10, Similarity: 70, MatchedWorkID: &three},
+		{Detector: DetectorCode, Enabled: true, Applied: false, Weight: 1, Details: "not code"},
+		{Detector: DetectorWinnowing, Enabled: true, Applied: true, Weight: 1, Similarity: 45, MatchedWorkID: &three},
+	}
+	if !reflect.DeepEqual(scores, want) {
+		t.Errorf("scores = %+v, want %+v", scores, want)
+	}
+}
+
+func TestCombineEvidence(t *testing.T) {
+	weak := []Fragment{{WorkID: 2, Start: 0, End: 10}}
+	strong := []Fragment{{WorkID: 2, Start: 20, End: 90}}
+	runs := []detectorRun{
+		{settings: defaultDetectorSettings(DetectorShingles), detection: &Detection{Applies: true, Matches: []Evidence{
+			{WorkID: 2, Similarity: 90}, // best score, but no fragments
+		}}},
+		{settings: defaultDetectorSettings(DetectorWinnowing), detection: &Detection{Applies: true, Matches: []Evidence{
+			{WorkID: 2, Similarity: 40, Fragments: weak},
+		}}},
+		{settings: defaultDetectorSettings(DetectorCode), detection: &Detection{Applies: true, Matches: []Evidence{
+			{WorkID: 2, Similarity: 60, MatchedTokens: 120, Fragments: strong},
+			{WorkID: 4, Similarity: 60},
+		}}},
+	}
+	sub := &Submission{TaskWorks: []Work{{ID: 2, StudentID: 20, Student: "Борис"}, {ID: 4, StudentID: 40, Student: "Дина"}}}
+	matches, _ := combine(sub, runs)
+
+	if len(matches) != 2 || matches[0].WorkID != 2 || matches[1].WorkID != 4 {
+		t.Fatalf("matches = %+v, want works 2 and 4, best first", matches)
+	}
+	m := matches[0]
+	if !reflect.DeepEqual(m.Fragments, strong) || m.MatchedTokens != 120 {
+		t.Errorf("evidence = %v, %d tokens; want the code detector's", m.Fragments, m.MatchedTokens)
+	}
+	wantScores := map[string]float64{DetectorShingles: 90, DetectorWinnowing: 40, DetectorCode: 60}
+	if !reflect.DeepEqual(m.Scores, wantScores) {
+		t.Errorf("scores = %v, want %v", m.Scores, wantScores)
+	}
+	if m.StudentID != 20 || m.Student != "Борис" || matches[1].Student != "Дина" {
+		t.Errorf("students = %d %q, %q; want them from the task's works", m.StudentID, m.Student, matches[1].Student)
+	}
+	if matches[1].Fragments == nil {
+		t.Error("a match without fragments has nil fragments, want an empty list")
+	}
+}
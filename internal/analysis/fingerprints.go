@@ -0,0 +1,133 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+)
+
+// FingerprintSet describes the stored fingerprints of a work and the
+// parameters they were computed with.
+type FingerprintSet struct {
+	WorkID     int64
+	TaskID     int64
+	StudentID  int64
+	K          int
+	Window     int
+	TextLength int
+}
+
+// SaveFingerprints replaces the fingerprints of a work.
+func (r Repository) SaveFingerprints(ctx context.Context, set *FingerprintSet, prints []Fingerprint) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save fingerprints: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const upsert = `
+    INSERT INTO fingerprint_sets (work_id, task_id, student_id, k, window_size, text_length)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (work_id) DO UPDATE SET task_id = EXCLUDED.task_id, student_id = EXCLUDED.student_id,
+        k = EXCLUDED.k, window_size = EXCLUDED.window_size, text_length = EXCLUDED.text_length, created_at = NOW();`
+	if _, err := tx.Exec(ctx, upsert, set.WorkID, set.TaskID, set.StudentID, set.K, set.Window, set.TextLength); err != nil {
+		return fmt.Errorf("failed to save fingerprints: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM work_fingerprints WHERE work_id = $1;`, set.WorkID); err != nil {
+		return fmt.Errorf("failed to clear fingerprints: %w", err)
+	}
+	if len(prints) > 0 {
+		hashes := make([]int64, len(prints))
+		starts := make([]int32, len(prints))
+		ends := make([]int32, len(prints))
+		for i, fp := range prints {
+			hashes[i], starts[i], ends[i] = int64(fp.Hash), int32(fp.Start), int32(fp.End)
+		}
+		const insert = `
+        INSERT INTO work_fingerprints (work_id, task_id, hash, start_pos, end_pos)
+        SELECT $1, $2, hash, start_pos, end_pos
+        FROM unnest($3::bigint[], $4::int[], $5::int[]) AS f (hash, start_pos, end_pos);`
+		if _, err := tx.Exec(ctx, insert, set.WorkID, set.TaskID, hashes, starts, ends); err != nil {
+			return fmt.Errorf("failed to insert fingerprints: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save fingerprints: %w", err)
+	}
+	return nil
+}
+
+// FingerprintedWorks returns the works of a task fingerprinted with the given
+// parameters.
+func (r Repository) FingerprintedWorks(ctx context.Context, taskID int64, k, window int) (map[int64]bool, error) {
+	const query = `
+    SELECT work_id FROM fingerprint_sets
+    WHERE task_id = $1 AND k = $2 AND window_size = $3;`
+
+	rows, err := r.pool.Query(ctx, query, taskID, k, window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprinted works: %w", err)
+	}
+	defer rows.Close()
+	done := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list fingerprinted works: %w", err)
+		}
+		done[id] = true
+	}
+	return done, rows.Err()
+}
+
+// FindSharedFingerprints returns, per other work of the same task, its
+// fingerprints whose hash also occurs in prints. Which of the works belong to
+// other students is up to the caller: a work's student may have changed since
+// it was fingerprinted.
+func (r Repository) FindSharedFingerprints(ctx context.Context, work *Work, k, window int, prints []Fingerprint) (map[int64][]Fingerprint, error) {
+	seen := map[uint64]bool{}
+	var hashes []int64
+	for _, fp := range prints {
+		if !seen[fp.Hash] {
+			seen[fp.Hash] = true
+			hashes = append(hashes, int64(fp.Hash))
+		}
+	}
+	shared := map[int64][]Fingerprint{}
+	if len(hashes) == 0 {
+		return shared, nil
+	}
+
+	const query = `
+    SELECT f.work_id, f.hash, f.start_pos, f.end_pos
+    FROM work_fingerprints f
+    JOIN fingerprint_sets s ON s.work_id = f.work_id
+    WHERE f.task_id = $1 AND f.hash = ANY($2) AND f.work_id <> $3
+      AND s.k = $4 AND s.window_size = $5;`
+
+	rows, err := r.pool.Query(ctx, query, work.TaskID, hashes, work.ID, k, window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shared fingerprints: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var workID, hash int64
+		var start, end int
+		if err := rows.Scan(&workID, &hash, &start, &end); err != nil {
+			return nil, fmt.Errorf("failed to find shared fingerprints: %w", err)
+		}
+		shared[workID] = append(shared[workID], Fingerprint{Hash: uint64(hash), Start: start, End: end})
+	}
+	return shared, rows.Err()
+}
+
+// DeleteFingerprints drops a withdrawn work's fingerprints.
+func (r Repository) DeleteFingerprints(ctx context.Context, workID int64) error {
+	const query = `
+    WITH prints AS (DELETE FROM work_fingerprints WHERE work_id = $1)
+    DELETE FROM fingerprint_sets WHERE work_id = $1;`
+
+	if _, err := r.pool.Exec(ctx, query, workID); err != nil {
+		return fmt.Errorf("failed to delete fingerprints: %w", err)
+	}
+	return nil
+}
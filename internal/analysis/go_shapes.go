@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GoShapes is the stored outcome of parsing a work's Go source: the shapes of
+// all its functions, before the size filter and template subtraction, or the
+// parse error.
+type GoShapes struct {
+	WorkID   int64
+	ParseErr string
+	Shapes   []FuncShape
+}
+
+// shapeRecord is FuncShape as stored in work_go_shapes.
+type shapeRecord struct {
+	Name     string         `json:"name"`
+	Start    int            `json:"start"`
+	End      int            `json:"end"`
+	Nodes    int            `json:"nodes"`
+	Subtrees map[uint64]int `json:"subtrees"`
+	Sizes    map[uint64]int `json:"sizes"`
+}
+
+// SaveGoShapes replaces the function shapes of a work.
+func (r Repository) SaveGoShapes(ctx context.Context, work *Work, shapes []FuncShape, parseErr string) error {
+	records := make([]shapeRecord, len(shapes))
+	for i, s := range shapes {
+		records[i] = shapeRecord{Name: s.Name, Start: s.Start, End: s.End, Nodes: s.Nodes, Subtrees: s.subtrees, Sizes: s.sizes}
+	}
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode go shapes: %w", err)
+	}
+	const query = `
+    INSERT INTO work_go_shapes (work_id, task_id, student_id, parse_error, shapes)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (work_id) DO UPDATE SET task_id = EXCLUDED.task_id, student_id = EXCLUDED.student_id,
+        parse_error = EXCLUDED.parse_error, shapes = EXCLUDED.shapes, created_at = NOW();`
+	if _, err := r.pool.Exec(ctx, query, work.ID, work.TaskID, work.StudentID, parseErr, raw); err != nil {
+		return fmt.Errorf("failed to save go shapes: %w", err)
+	}
+	return nil
+}
+
+// GetGoShapes returns the stored function shapes of those of the works that
+// have them.
+func (r Repository) GetGoShapes(ctx context.Context, workIDs []int64) (map[int64]*GoShapes, error) {
+	const query = `
+    SELECT work_id, parse_error, shapes
+    FROM work_go_shapes
+    WHERE work_id = ANY($1);`
+
+	rows, err := r.pool.Query(ctx, query, workIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get go shapes: %w", err)
+	}
+	defer rows.Close()
+	result := map[int64]*GoShapes{}
+	for rows.Next() {
+		var g GoShapes
+		var records []shapeRecord
+		if err := rows.Scan(&g.WorkID, &g.ParseErr, &records); err != nil {
+			return nil, fmt.Errorf("failed to get go shapes: %w", err)
+		}
+		g.Shapes = make([]FuncShape, len(records))
+		for i, rec := range records {
+			s := FuncShape{Name: rec.Name, Start: rec.Start, End: rec.End, Nodes: rec.Nodes, subtrees: rec.Subtrees, sizes: rec.Sizes}
+			if s.subtrees == nil {
+				s.subtrees, s.sizes = map[uint64]int{}, map[uint64]int{}
+			}
+			for _, n := range s.subtrees {
+				s.total += n
+			}
+			g.Shapes[i] = s
+		}
+		result[g.WorkID] = &g
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get go shapes: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteGoShapes drops a withdrawn work's function shapes.
+func (r Repository) DeleteGoShapes(ctx context.Context, workID int64) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM work_go_shapes WHERE work_id = $1;`, workID); err != nil {
+		return fmt.Errorf("failed to delete go shapes: %w", err)
+	}
+	return nil
+}
@@ -2,24 +2,30 @@ package analysis
 
 import (
 	"log/slog"
-	"math/rand"
 	"net/http"
 	"strconv"
-	"time"
+
+	"HW_KPO3/internal/config"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 type Handler struct {
-	repo *Repository
+	repo      *Repository
+	queue     *Queue
+	storage   *StorageClient
+	detectors *Registry
+	lsh       config.LSH
 }
 
-func NewHandler(repo *Repository) *Handler {
+func NewHandler(repo *Repository, queue *Queue, storage *StorageClient, detectors *Registry, lsh config.LSH) *Handler {
 	return &Handler{
-		repo: repo,
+		repo:      repo,
+		queue:     queue,
+		storage:   storage,
+		detectors: detectors,
+		lsh:       lsh,
 	}
 }
 
@@ -31,12 +37,61 @@ type createReportRequest struct {
 }
 
 type reportResponse struct {
-	ID         int64   `json:"id"`
-	WorkID     int64   `json:"work_id"`
-	Status     string  `json:"status"`
-	Similarity float64 `json:"similarity"`
-	Details    string  `json:"details"`
-	CreatedAt  string  `json:"created_at"`
+	ID               int64           `json:"id"`
+	WorkID           int64           `json:"work_id"`
+	Status           string          `json:"status"`
+	Similarity       float64         `json:"similarity"`
+	MatchedWorkID    *int64          `json:"matched_work_id"`
+	Details          string          `json:"details"`
+	Scores           []DetectorScore `json:"scores"`
+	BoilerplateChars int             `json:"boilerplate_chars"`
+	BoilerplateShare float64         `json:"boilerplate_share"`
+	Citations        []Citation      `json:"citations"`
+	CitationChars    int             `json:"citation_chars"`
+	CitationShare    float64         `json:"citation_share"`
+	Findings         []Finding       `json:"findings"`
+	Error            string          `json:"error"`
+	Attempts         int             `json:"attempts"`
+	CreatedAt        string          `json:"created_at"`
+	StartedAt        string          `json:"started_at,omitempty"`
+	FinishedAt       string          `json:"finished_at,omitempty"`
+}
+
+func newReportResponse(report *Report) *reportResponse {
+	response := &reportResponse{
+		ID:               report.ID,
+		WorkID:           report.WorkID,
+		Status:           report.Status,
+		Similarity:       report.Similarity,
+		MatchedWorkID:    report.MatchedWorkID,
+		Details:          report.Details,
+		Scores:           report.Scores,
+		BoilerplateChars: report.BoilerplateChars,
+		BoilerplateShare: report.BoilerplateShare,
+		Citations:        report.Citations,
+		CitationChars:    report.CitationChars,
+		CitationShare:    report.CitationShare,
+		Findings:         report.Findings,
+		Error:            report.Error,
+		Attempts:         report.Attempts,
+		CreatedAt:        report.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if response.Scores == nil {
+		response.Scores = []DetectorScore{}
+	}
+	if response.Citations == nil {
+		response.Citations = []Citation{}
+	}
+	if response.Findings == nil {
+		response.Findings = []Finding{}
+	}
+	if report.StartedAt != nil {
+		response.StartedAt = report.StartedAt.Format("2006-01-02 15:04:05")
+	}
+	if report.FinishedAt != nil {
+		response.FinishedAt = report.FinishedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
 }
 
 func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
@@ -47,31 +102,39 @@ func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.WorkID <= 0 || req.Status == "" {
-		http.Error(w, "work_id and status are required", http.StatusBadRequest)
+	if req.WorkID <= 0 {
+		http.Error(w, "work_id is required", http.StatusBadRequest)
+		return
+	}
+	switch req.Status {
+	case "", StatusPending, StatusProcessing, StatusDone, StatusFailed:
+	default:
+		http.Error(w, "status must be one of pending, processing, done, failed", http.StatusBadRequest)
+		return
+	}
+	if req.Similarity < 0 || req.Similarity > 100 {
+		http.Error(w, "similarity must be between 0 and 100", http.StatusBadRequest)
 		return
 	}
 
+	var report *Report
+	queued := false
 	switch req.Status {
-	case "done":
-		if req.Similarity == 0 || req.Similarity == SimilarityUnknown {
-			req.Similarity = float64(rng.Intn(101))
-		}
-		if req.Similarity < 0 || req.Similarity > 100 {
-			http.Error(w, "similarity must be between 0 and 100", http.StatusBadRequest)
-			return
-		}
+	case "", StatusPending, StatusDone:
+		// The check itself runs in the queue; the client gets the pending
+		// report and polls it.
+		report = &Report{WorkID: req.WorkID, Status: StatusPending, Similarity: SimilarityUnknown}
+		queued = true
 	default:
 		if req.Similarity == 0 {
 			req.Similarity = SimilarityUnknown
 		}
-	}
-
-	report := &Report{
-		WorkID:     req.WorkID,
-		Status:     req.Status,
-		Details:    req.Details,
-		Similarity: req.Similarity,
+		report = &Report{
+			WorkID:     req.WorkID,
+			Status:     req.Status,
+			Details:    req.Details,
+			Similarity: req.Similarity,
+		}
 	}
 	if err := h.repo.CreateReport(r.Context(), report); err != nil {
 		slog.Error("failed to create report", "err", err)
@@ -79,15 +142,13 @@ func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := &reportResponse{
-		ID:         report.ID,
-		WorkID:     report.WorkID,
-		Status:     report.Status,
-		Similarity: report.Similarity,
-		Details:    report.Details,
-		CreatedAt:  report.CreatedAt.Format("2006-01-02 15:04:05"),
-	}
-	render.Status(r, http.StatusCreated)
+	response := newReportResponse(report)
+	if queued {
+		h.queue.Notify()
+		render.Status(r, http.StatusAccepted)
+	} else {
+		render.Status(r, http.StatusCreated)
+	}
 	render.JSON(w, r, response)
 }
 
@@ -109,14 +170,7 @@ func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-	response := &reportResponse{
-		ID:         report.ID,
-		WorkID:     report.WorkID,
-		Status:     report.Status,
-		Similarity: report.Similarity,
-		Details:    report.Details,
-		CreatedAt:  report.CreatedAt.Format("2006-01-02 15:04:05"),
-	}
+	response := newReportResponse(report)
 	render.Status(r, http.StatusOK)
 	render.JSON(w, r, response)
 }
@@ -139,14 +193,35 @@ func (h *Handler) GetReportByWorkID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-	response := &reportResponse{
-		ID:         report.ID,
-		WorkID:     report.WorkID,
-		Status:     report.Status,
-		Similarity: report.Similarity,
-		Details:    report.Details,
-		CreatedAt:  report.CreatedAt.Format("2006-01-02 15:04:05"),
-	}
+	response := newReportResponse(report)
 	render.Status(r, http.StatusOK)
 	render.JSON(w, r, response)
 }
+
+func (h *Handler) HideReportsByWorkID(w http.ResponseWriter, r *http.Request) {
+	workID, err := strconv.ParseInt(chi.URLParam(r, "work_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid work_id parameter", http.StatusBadRequest)
+		return
+	}
+	hidden, err := h.repo.HideReportsByWorkID(r.Context(), workID)
+	if err != nil {
+		slog.Error("failed to hide reports", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.repo.DeleteSignature(r.Context(), workID); err != nil {
+		slog.Error("failed to drop work from lsh index", "work_id", workID, "err", err)
+	}
+	if err := h.repo.DeleteFingerprints(r.Context(), workID); err != nil {
+		slog.Error("failed to drop work fingerprints", "work_id", workID, "err", err)
+	}
+	if err := h.repo.DeleteCodeStream(r.Context(), workID); err != nil {
+		slog.Error("failed to drop work code stream", "work_id", workID, "err", err)
+	}
+	if err := h.repo.DeleteGoShapes(r.Context(), workID); err != nil {
+		slog.Error("failed to drop work go shapes", "work_id", workID, "err", err)
+	}
+	slog.Info("reports hidden", "work_id", workID, "count", hidden)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,34 @@
+package analysis
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCreateReportRejectsInvalidInput covers the requests refused before
+// anything is stored, so the handler needs no repository.
+func TestCreateReportRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{"work_id":`},
+		{name: "missing work", body: `{"status":"failed"}`},
+		{name: "unknown status", body: `{"work_id":1,"status":"weird"}`},
+		{name: "status in another case", body: `{"work_id":1,"status":"FAILED"}`},
+		{name: "negative similarity", body: `{"work_id":1,"status":"failed","similarity":-1}`},
+		{name: "similarity above 100", body: `{"work_id":1,"status":"failed","similarity":100.5}`},
+	}
+	h := &Handler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.CreateReport(rec, httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
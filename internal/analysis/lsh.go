@@ -0,0 +1,313 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"HW_KPO3/internal/config"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+)
+
+// LSHSettings is the banding used for the candidate index of a task. More
+// bands raise recall, more rows per band cut the number of candidates.
+type LSHSettings struct {
+	TaskID    int64
+	Bands     int
+	Rows      int
+	Custom    bool
+	UpdatedAt *time.Time
+}
+
+// WorkSignature is the indexed form of a work's text.
+type WorkSignature struct {
+	WorkID      int64
+	TaskID      int64
+	StudentID   int64
+	ShingleSize int
+	NumHashes   int
+	Signature   Signature
+}
+
+type lshSettingsRequest struct {
+	Bands int `json:"bands"`
+	Rows  int `json:"rows"`
+}
+
+type lshSettingsResponse struct {
+	TaskID    int64   `json:"task_id"`
+	Bands     int     `json:"bands"`
+	Rows      int     `json:"rows"`
+	NumHashes int     `json:"num_hashes"`
+	Threshold float64 `json:"threshold"`
+	Custom    bool    `json:"custom"`
+	UpdatedAt string  `json:"updated_at,omitempty"`
+}
+
+func newLSHSettingsResponse(s *LSHSettings, numHashes int) *lshSettingsResponse {
+	response := &lshSettingsResponse{
+		TaskID:    s.TaskID,
+		Bands:     s.Bands,
+		Rows:      s.Rows,
+		NumHashes: numHashes,
+		Threshold: LSHThreshold(s.Bands, s.Rows),
+		Custom:    s.Custom,
+	}
+	if s.UpdatedAt != nil {
+		response.UpdatedAt = s.UpdatedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+func toInt64s(sig Signature) []int64 {
+	out := make([]int64, len(sig))
+	for i, v := range sig {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+func fromInt64s(values []int64) Signature {
+	sig := make(Signature, len(values))
+	for i, v := range values {
+		sig[i] = uint64(v)
+	}
+	return sig
+}
+
+// GetLSHSettings returns the task's banding, or defaults when the task has
+// none of its own.
+func (r Repository) GetLSHSettings(ctx context.Context, taskID int64, defaults config.LSH) (*LSHSettings, error) {
+	const query = `
+    SELECT bands, rows, updated_at FROM task_lsh_settings WHERE task_id = $1;`
+
+	s := &LSHSettings{TaskID: taskID, Bands: defaults.Bands, Rows: defaults.Rows}
+	var updatedAt time.Time
+	err := r.pool.QueryRow(ctx, query, taskID).Scan(&s.Bands, &s.Rows, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lsh settings: %w", err)
+	}
+	s.Custom, s.UpdatedAt = true, &updatedAt
+	return s, nil
+}
+
+// SaveLSHSettings stores the task's banding and rebuilds its buckets, those of
+// the code index included, from the stored signatures, so the new settings
+// apply to works indexed earlier too.
+func (r Repository) SaveLSHSettings(ctx context.Context, s *LSHSettings) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save lsh settings: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const upsert = `
+    INSERT INTO task_lsh_settings (task_id, bands, rows, updated_at)
+    VALUES ($1, $2, $3, NOW())
+    ON CONFLICT (task_id) DO UPDATE SET bands = EXCLUDED.bands, rows = EXCLUDED.rows, updated_at = EXCLUDED.updated_at
+    RETURNING updated_at;`
+	var updatedAt time.Time
+	if err := tx.QueryRow(ctx, upsert, s.TaskID, s.Bands, s.Rows).Scan(&updatedAt); err != nil {
+		return fmt.Errorf("failed to save lsh settings: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM lsh_buckets WHERE task_id = $1;`, s.TaskID); err != nil {
+		return fmt.Errorf("failed to clear lsh buckets: %w", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT work_id, signature FROM work_signatures WHERE task_id = $1;`, s.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to read signatures: %w", err)
+	}
+	buckets := map[int64][]int64{}
+	for rows.Next() {
+		var workID int64
+		var values []int64
+		if err := rows.Scan(&workID, &values); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read signatures: %w", err)
+		}
+		buckets[workID] = fromInt64s(values).Buckets(s.Bands, s.Rows)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read signatures: %w", err)
+	}
+	for workID, keys := range buckets {
+		if err := insertBuckets(ctx, tx, s.TaskID, workID, keys); err != nil {
+			return err
+		}
+	}
+	if err := rebuildCodeBuckets(ctx, tx, s); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save lsh settings: %w", err)
+	}
+	s.Custom, s.UpdatedAt = true, &updatedAt
+	return nil
+}
+
+// SaveSignature replaces the signature and buckets of a work.
+func (r Repository) SaveSignature(ctx context.Context, sig *WorkSignature, buckets []int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save signature: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const upsert = `
+    INSERT INTO work_signatures (work_id, task_id, student_id, shingle_size, num_hashes, signature)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (work_id) DO UPDATE SET task_id = EXCLUDED.task_id, student_id = EXCLUDED.student_id,
+        shingle_size = EXCLUDED.shingle_size, num_hashes = EXCLUDED.num_hashes,
+        signature = EXCLUDED.signature, created_at = NOW();`
+	if _, err := tx.Exec(ctx, upsert, sig.WorkID, sig.TaskID, sig.StudentID, sig.ShingleSize, sig.NumHashes, toInt64s(sig.Signature)); err != nil {
+		return fmt.Errorf("failed to save signature: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM lsh_buckets WHERE work_id = $1;`, sig.WorkID); err != nil {
+		return fmt.Errorf("failed to clear lsh buckets: %w", err)
+	}
+	if err := insertBuckets(ctx, tx, sig.TaskID, sig.WorkID, buckets); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save signature: %w", err)
+	}
+	return nil
+}
+
+func insertBuckets(ctx context.Context, tx pgx.Tx, taskID, workID int64, buckets []int64) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	bands := make([]int32, len(buckets))
+	for i := range bands {
+		bands[i] = int32(i)
+	}
+	const query = `
+    INSERT INTO lsh_buckets (task_id, band, bucket, work_id)
+    SELECT $1, band, bucket, $2 FROM unnest($3::int[], $4::bigint[]) AS b (band, bucket)
+    ON CONFLICT DO NOTHING;`
+	if _, err := tx.Exec(ctx, query, taskID, workID, bands, buckets); err != nil {
+		return fmt.Errorf("failed to insert lsh buckets: %w", err)
+	}
+	return nil
+}
+
+// IndexedWorks returns the works of a task whose signature was computed with
+// the given parameters. Works missing here need to be (re)indexed.
+func (r Repository) IndexedWorks(ctx context.Context, taskID int64, shingleSize, numHashes int) (map[int64]bool, error) {
+	const query = `
+    SELECT work_id FROM work_signatures
+    WHERE task_id = $1 AND shingle_size = $2 AND num_hashes = $3;`
+
+	rows, err := r.pool.Query(ctx, query, taskID, shingleSize, numHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed works: %w", err)
+	}
+	defer rows.Close()
+	indexed := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to list indexed works: %w", err)
+		}
+		indexed[id] = true
+	}
+	return indexed, rows.Err()
+}
+
+// FindCandidates returns the works that share at least one LSH bucket with
+// the work. Which of them belong to other students is up to the caller: a
+// work's student may have changed since it was indexed. The lookup goes
+// through the bucket index, so its cost depends on the bucket sizes rather
+// than on the number of works.
+func (r Repository) FindCandidates(ctx context.Context, workID int64) ([]int64, error) {
+	const query = `
+    SELECT DISTINCT other.work_id
+    FROM lsh_buckets own
+    JOIN lsh_buckets other
+      ON other.task_id = own.task_id AND other.band = own.band AND other.bucket = own.bucket
+    WHERE own.work_id = $1 AND other.work_id <> own.work_id
+    ORDER BY other.work_id;`
+
+	rows, err := r.pool.Query(ctx, query, workID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find candidates: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to find candidates: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteSignature drops a withdrawn work from the candidate index.
+func (r Repository) DeleteSignature(ctx context.Context, workID int64) error {
+	const query = `
+    WITH buckets AS (DELETE FROM lsh_buckets WHERE work_id = $1)
+    DELETE FROM work_signatures WHERE work_id = $1;`
+
+	if _, err := r.pool.Exec(ctx, query, workID); err != nil {
+		return fmt.Errorf("failed to delete signature: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) GetTaskLSH(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	settings, err := h.repo.GetLSHSettings(r.Context(), taskID, h.lsh)
+	if err != nil {
+		slog.Error("failed to get lsh settings", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newLSHSettingsResponse(settings, h.lsh.NumHashes))
+}
+
+func (h *Handler) UpdateTaskLSH(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	var req lshSettingsRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Bands <= 0 || req.Rows <= 0 || req.Bands*req.Rows > h.lsh.NumHashes {
+		http.Error(w, fmt.Sprintf("bands and rows must be positive and bands*rows must not exceed %d", h.lsh.NumHashes), http.StatusBadRequest)
+		return
+	}
+
+	settings := &LSHSettings{TaskID: taskID, Bands: req.Bands, Rows: req.Rows}
+	if err := h.repo.SaveLSHSettings(r.Context(), settings); err != nil {
+		slog.Error("failed to save lsh settings", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newLSHSettingsResponse(settings, h.lsh.NumHashes))
+}
@@ -0,0 +1,275 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultMatchesLimit = 20
+	maxMatchesLimit     = 100
+)
+
+// ReportMatch is the evidence against one counterpart work: its score, how
+// many tokens of the checked work lie in common passages (words of text, or
+// lexical tokens for source code), where those passages are and, for Go,
+// which functions have the same structure. Scores holds each detector's own
+// score for the counterpart.
+type ReportMatch struct {
+	WorkID        int64              `json:"work_id"`
+	StudentID     int64              `json:"student_id"`
+	Student       string             `json:"student"`
+	Similarity    float64            `json:"similarity"`
+	Scores        map[string]float64 `json:"scores"`
+	MatchedTokens int                `json:"matched_tokens"`
+	Fragments     []Fragment         `json:"fragments"`
+	Functions     []FuncMatch        `json:"functions,omitempty"`
+}
+
+type reportMatchesResponse struct {
+	ReportID int64         `json:"report_id"`
+	Total    int           `json:"total"`
+	Items    []ReportMatch `json:"items"`
+}
+
+// matchedTokens counts the words of text that start inside a fragment.
+func matchedTokens(text []rune, fragments []Fragment) int {
+	covered := make([]bool, len(text))
+	for _, f := range fragments {
+		for i := max(f.Start, 0); i < f.End && i < len(text); i++ {
+			covered[i] = true
+		}
+	}
+	count, inWord := 0, false
+	for i, r := range text {
+		isWordChar := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordChar && !inWord && covered[i] {
+			count++
+		}
+		inWord = isWordChar
+	}
+	return count
+}
+
+// ListReportMatches returns the best matches of a report with their fragments
+// and the total number of matches. A non-nil workID keeps only the match with
+// that work.
+func (r Repository) ListReportMatches(ctx context.Context, reportID int64, limit int, workID *int64) ([]ReportMatch, int, error) {
+	var total int
+	const countQuery = `
+    SELECT COUNT(*) FROM report_matches
+    WHERE report_id = $1 AND ($2::bigint IS NULL OR matched_work_id = $2);`
+	if err := r.pool.QueryRow(ctx, countQuery, reportID, workID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count report matches: %w", err)
+	}
+
+	const query = `
+    SELECT matched_work_id, student_id, student, similarity, scores, matched_tokens
+    FROM report_matches
+    WHERE report_id = $1 AND ($3::bigint IS NULL OR matched_work_id = $3)
+    ORDER BY similarity DESC, matched_work_id
+    LIMIT $2;`
+	rows, err := r.pool.Query(ctx, query, reportID, limit, workID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list report matches: %w", err)
+	}
+	matches := []ReportMatch{}
+	index := map[int64]int{}
+	var workIDs []int64
+	for rows.Next() {
+		m := ReportMatch{Fragments: []Fragment{}}
+		if err := rows.Scan(&m.WorkID, &m.StudentID, &m.Student, &m.Similarity, &m.Scores, &m.MatchedTokens); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to list report matches: %w", err)
+		}
+		index[m.WorkID] = len(matches)
+		workIDs = append(workIDs, m.WorkID)
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list report matches: %w", err)
+	}
+	if len(matches) == 0 {
+		return matches, total, nil
+	}
+
+	const fragmentsQuery = `
+    SELECT matched_work_id, start_pos, end_pos, matched_start, matched_end
+    FROM report_fragments
+    WHERE report_id = $1 AND matched_work_id = ANY($2)
+    ORDER BY matched_work_id, start_pos;`
+	frows, err := r.pool.Query(ctx, fragmentsQuery, reportID, workIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get report fragments: %w", err)
+	}
+	defer frows.Close()
+	for frows.Next() {
+		var f Fragment
+		if err := frows.Scan(&f.WorkID, &f.Start, &f.End, &f.MatchedStart, &f.MatchedEnd); err != nil {
+			return nil, 0, fmt.Errorf("failed to get report fragments: %w", err)
+		}
+		m := &matches[index[f.WorkID]]
+		m.Fragments = append(m.Fragments, f)
+	}
+	if err := frows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get report fragments: %w", err)
+	}
+
+	const functionsQuery = `
+    SELECT matched_work_id, function_name, matched_function, similarity, start_pos, end_pos, matched_start, matched_end
+    FROM report_functions
+    WHERE report_id = $1 AND matched_work_id = ANY($2)
+    ORDER BY matched_work_id, start_pos;`
+	fnrows, err := r.pool.Query(ctx, functionsQuery, reportID, workIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get report functions: %w", err)
+	}
+	defer fnrows.Close()
+	for fnrows.Next() {
+		var workID int64
+		var f FuncMatch
+		if err := fnrows.Scan(&workID, &f.Function, &f.MatchedFunction, &f.Similarity, &f.Start, &f.End, &f.MatchedStart, &f.MatchedEnd); err != nil {
+			return nil, 0, fmt.Errorf("failed to get report functions: %w", err)
+		}
+		m := &matches[index[workID]]
+		m.Functions = append(m.Functions, f)
+	}
+	return matches, total, fnrows.Err()
+}
+
+func insertMatches(ctx context.Context, tx pgx.Tx, reportID int64, matches []ReportMatch) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM report_fragments WHERE report_id = $1;`, reportID); err != nil {
+		return fmt.Errorf("failed to clear report fragments: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM report_functions WHERE report_id = $1;`, reportID); err != nil {
+		return fmt.Errorf("failed to clear report functions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM report_matches WHERE report_id = $1;`, reportID); err != nil {
+		return fmt.Errorf("failed to clear report matches: %w", err)
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	n := len(matches)
+	workIDs, studentIDs := make([]int64, n), make([]int64, n)
+	students, similarities := make([]string, n), make([]float64, n)
+	scores, tokens := make([]string, n), make([]int32, n)
+	var fragWorkIDs []int64
+	var starts, ends, matchedStarts, matchedEnds []int32
+	for i, m := range matches {
+		workIDs[i], studentIDs[i], students[i] = m.WorkID, m.StudentID, m.Student
+		similarities[i], tokens[i] = m.Similarity, int32(m.MatchedTokens)
+		raw, err := json.Marshal(m.Scores)
+		if err != nil {
+			return fmt.Errorf("failed to encode match scores: %w", err)
+		}
+		scores[i] = string(raw)
+		for _, f := range m.Fragments {
+			fragWorkIDs = append(fragWorkIDs, m.WorkID)
+			starts, ends = append(starts, int32(f.Start)), append(ends, int32(f.End))
+			matchedStarts, matchedEnds = append(matchedStarts, int32(f.MatchedStart)), append(matchedEnds, int32(f.MatchedEnd))
+		}
+	}
+
+	const insertMatches = `
+    INSERT INTO report_matches (report_id, matched_work_id, student_id, student, similarity, scores, matched_tokens)
+    SELECT $1, w, st, sn, sim, sc::jsonb, tok
+    FROM unnest($2::int[], $3::int[], $4::text[], $5::float8[], $6::text[], $7::int[]) AS m (w, st, sn, sim, sc, tok);`
+	if _, err := tx.Exec(ctx, insertMatches, reportID, workIDs, studentIDs, students, similarities, scores, tokens); err != nil {
+		return fmt.Errorf("failed to insert report matches: %w", err)
+	}
+	if len(fragWorkIDs) > 0 {
+		const insertFragments = `
+    INSERT INTO report_fragments (report_id, matched_work_id, start_pos, end_pos, matched_start, matched_end)
+    SELECT $1, w, s, e, ms, me
+    FROM unnest($2::int[], $3::int[], $4::int[], $5::int[], $6::int[]) AS f (w, s, e, ms, me);`
+		if _, err := tx.Exec(ctx, insertFragments, reportID, fragWorkIDs, starts, ends, matchedStarts, matchedEnds); err != nil {
+			return fmt.Errorf("failed to insert report fragments: %w", err)
+		}
+	}
+	return insertFunctions(ctx, tx, reportID, matches)
+}
+
+func insertFunctions(ctx context.Context, tx pgx.Tx, reportID int64, matches []ReportMatch) error {
+	var workIDs []int64
+	var names, matchedNames []string
+	var similarities []float64
+	var starts, ends, matchedStarts, matchedEnds []int32
+	for _, m := range matches {
+		for _, f := range m.Functions {
+			workIDs = append(workIDs, m.WorkID)
+			names, matchedNames = append(names, f.Function), append(matchedNames, f.MatchedFunction)
+			similarities = append(similarities, f.Similarity)
+			starts, ends = append(starts, int32(f.Start)), append(ends, int32(f.End))
+			matchedStarts, matchedEnds = append(matchedStarts, int32(f.MatchedStart)), append(matchedEnds, int32(f.MatchedEnd))
+		}
+	}
+	if len(workIDs) == 0 {
+		return nil
+	}
+	const query = `
+    INSERT INTO report_functions (report_id, matched_work_id, function_name, matched_function, similarity,
+                                  start_pos, end_pos, matched_start, matched_end)
+    SELECT $1, w, fn, mfn, sim, s, e, ms, me
+    FROM unnest($2::int[], $3::text[], $4::text[], $5::float8[], $6::int[], $7::int[], $8::int[], $9::int[])
+        AS f (w, fn, mfn, sim, s, e, ms, me);`
+	if _, err := tx.Exec(ctx, query, reportID, workIDs, names, matchedNames, similarities, starts, ends, matchedStarts, matchedEnds); err != nil {
+		return fmt.Errorf("failed to insert report functions: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) GetReportMatches(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id parameter", http.StatusBadRequest)
+		return
+	}
+	limit := defaultMatchesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxMatchesLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxMatchesLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	var workID *int64
+	if v := r.URL.Query().Get("work_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid work_id parameter", http.StatusBadRequest)
+			return
+		}
+		workID = &id
+	}
+
+	// Hidden reports are not found, so their evidence is not either.
+	if _, err := h.repo.GetReport(r.Context(), id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get report", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	matches, total, err := h.repo.ListReportMatches(r.Context(), id, limit, workID)
+	if err != nil {
+		slog.Error("failed to list report matches", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &reportMatchesResponse{ReportID: id, Total: total, Items: matches})
+}
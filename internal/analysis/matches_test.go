@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMatchedTokens(t *testing.T) {
+	text := []rune("один два, три четыре")
+	tests := []struct {
+		name      string
+		fragments []Fragment
+		want      int
+	}{
+		{name: "no fragments", fragments: nil, want: 0},
+		{name: "whole text", fragments: []Fragment{{Start: 0, End: len(text)}}, want: 4},
+		{name: "word start inside", fragments: []Fragment{{Start: 5, End: 6}}, want: 1},
+		{name: "only the tail of a word", fragments: []Fragment{{Start: 2, End: 4}}, want: 0},
+		{name: "punctuation only", fragments: []Fragment{{Start: 8, End: 10}}, want: 0},
+		{name: "overlapping fragments count once", fragments: []Fragment{{Start: 0, End: 9}, {Start: 5, End: 14}}, want: 3},
+		{name: "out of range is clamped", fragments: []Fragment{{Start: -5, End: 3}, {Start: 14, End: 100}}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchedTokens(text, tt.fragments); got != tt.want {
+				t.Errorf("matchedTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetReportMatchesRejects covers the requests refused before the report
+// is looked up, so the handler needs no repository.
+func TestGetReportMatchesRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    string
+		query string
+	}{
+		{name: "invalid id", id: "x"},
+		{name: "zero limit", id: "1", query: "?limit=0"},
+		{name: "limit above max", id: "1", query: "?limit=101"},
+		{name: "limit not a number", id: "1", query: "?limit=ten"},
+		{name: "invalid work", id: "1", query: "?work_id=x"},
+	}
+	h := &Handler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req := httptest.NewRequest(http.MethodGet, "/reports/"+tt.id+"/matches"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+			h.GetReportMatches(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Formats of the similarity matrix.
+const (
+	MatrixJSON = "json"
+	MatrixCSV  = "csv"
+)
+
+// MatrixWork is a row and column of the similarity matrix: a student's
+// submission, shown as the latest version of the work. Checked tells whether
+// any version of it has a finished report.
+type MatrixWork struct {
+	WorkID     int64  `json:"work_id"`
+	StudentID  int64  `json:"student_id"`
+	Student    string `json:"student"`
+	Version    int    `json:"version"`
+	FileName   string `json:"file_name"`
+	UploadedAt string `json:"uploaded_at"`
+	Label      string `json:"label"`
+	Checked    bool   `json:"checked"`
+}
+
+// matrix lays the graph out as a table ordered by student. A cell is the
+// stored similarity of the pair in percent, 100 on the diagonal, 0 when a
+// check that could see the other submission found no match and nil when the
+// pair was never compared: neither was checked, or each was checked before
+// the other was uploaded.
+func (g *submissionGraph) matrix() ([]MatrixWork, [][]*float64) {
+	order := make([]int, len(g.nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := g.nodes[order[i]].work, g.nodes[order[j]].work
+		if a.Student != b.Student {
+			return a.Student < b.Student
+		}
+		return a.ID < b.ID
+	})
+
+	works := make([]MatrixWork, len(order))
+	cells := make([][]*float64, len(order))
+	for i, n := range order {
+		s := g.nodes[n]
+		works[i] = MatrixWork{
+			WorkID:     s.work.ID,
+			StudentID:  s.work.StudentID,
+			Student:    s.work.Student,
+			Version:    s.work.Version,
+			FileName:   s.work.FileName,
+			UploadedAt: s.work.UploadedAt,
+			Label:      fmt.Sprintf("%s #%d", s.work.Student, s.work.ID),
+			Checked:    s.checkedAt != "",
+		}
+		cells[i] = make([]*float64, len(order))
+		for j, m := range order {
+			sim, linked := g.edges[[2]int{min(n, m), max(n, m)}]
+			switch {
+			case n == m:
+				sim = 100
+			case !linked && !g.compared(n, m):
+				continue
+			}
+			cells[i][j] = &sim
+		}
+	}
+	return works, cells
+}
+
+// matrixCorner heads the CSV's label column and says what a row stands for.
+const matrixCorner = "student #latest work (any version counts)"
+
+type matrixResponse struct {
+	TaskID int64        `json:"task_id"`
+	Works  []MatrixWork `json:"works"`
+	Matrix [][]*float64 `json:"matrix"`
+}
+
+// GetTaskMatrix returns the similarity of every pair of the task's
+// submissions from the stored matches, as JSON or, with format=csv, as a
+// spreadsheet with the works as rows and columns. There is one row per
+// student, not per work: it is labelled with the latest version, and a match
+// with any version of the student's work counts for it.
+func (h *Handler) GetTaskMatrix(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = MatrixJSON
+	case MatrixJSON, MatrixCSV:
+	default:
+		http.Error(w, fmt.Sprintf("format must be %s or %s", MatrixJSON, MatrixCSV), http.StatusBadRequest)
+		return
+	}
+
+	g, ok := h.loadSubmissionGraph(w, r, taskID)
+	if !ok {
+		return
+	}
+	works, cells := g.matrix()
+
+	if format == MatrixJSON {
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, &matrixResponse{TaskID: taskID, Works: works, Matrix: cells})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="task-%d-matrix.csv"`, taskID))
+	w.WriteHeader(http.StatusOK)
+	if err := writeMatrixCSV(w, works, cells); err != nil {
+		slog.Error("failed to write similarity matrix", "task_id", taskID, "err", err)
+	}
+}
+
+// writeMatrixCSV writes the matrix with the works' labels heading the rows
+// and columns. Labels are made of student names, so they are neutralised
+// before a spreadsheet can take one for a formula.
+func writeMatrixCSV(w io.Writer, works []MatrixWork, cells [][]*float64) error {
+	// The byte order mark makes Excel read the names as UTF-8.
+	if _, err := w.Write([]byte("\uFEFF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	header := make([]string, len(works)+1)
+	header[0] = matrixCorner
+	for i, work := range works {
+		header[i+1] = csvText(work.Label)
+	}
+	_ = cw.Write(header)
+	for i, row := range cells {
+		record := make([]string, len(row)+1)
+		record[0] = csvText(works[i].Label)
+		for j, sim := range row {
+			if sim != nil {
+				record[j+1] = strconv.FormatFloat(*sim, 'f', 1, 64)
+			}
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText prefixes text that a spreadsheet would evaluate as a formula with
+// an apostrophe, so it is shown as typed.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
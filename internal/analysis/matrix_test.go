@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "Иванов Иван #3", want: "Иванов Иван #3"},
+		{in: "=HYPERLINK(\"http://x\") #1", want: "'=HYPERLINK(\"http://x\") #1"},
+		{in: "+1 #2", want: "'+1 #2"},
+		{in: "-2+3 #2", want: "'-2+3 #2"},
+		{in: "@SUM(A1) #2", want: "'@SUM(A1) #2"},
+		{in: "\t=1 #2", want: "'\t=1 #2"},
+		{in: "\r=1 #2", want: "'\r=1 #2"},
+		{in: "a=1 #2", want: "a=1 #2"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteMatrixCSV(t *testing.T) {
+	sim := 42.25
+	full := 100.0
+	works := []MatrixWork{{Label: "=cmd|' /C calc'!A0 #1"}, {Label: "Петров #2"}}
+	cells := [][]*float64{{&full, &sim}, {&sim, &full}}
+
+	var buf bytes.Buffer
+	if err := writeMatrixCSV(&buf, works, cells); err != nil {
+		t.Fatal(err)
+	}
+	out, ok := strings.CutPrefix(buf.String(), "\uFEFF")
+	if !ok {
+		t.Fatal("no byte order mark")
+	}
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{matrixCorner, "'=cmd|' /C calc'!A0 #1", "Петров #2"},
+		{"'=cmd|' /C calc'!A0 #1", "100.0", "42.2"},
+		{"Петров #2", "42.2", "100.0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
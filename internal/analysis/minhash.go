@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// minhashSeed fixes the hash family. Signatures are stored, so changing it
+// invalidates every stored signature.
+const minhashSeed = 0x5eeda11ce0fbada0
+
+// Signature is a MinHash signature: for each hash function of the family, the
+// minimum over the hashed shingles of a text.
+type Signature []uint64
+
+// MinHasher computes signatures with a fixed family of hash functions.
+type MinHasher struct {
+	seeds []uint64
+}
+
+func NewMinHasher(numHashes int) *MinHasher {
+	seeds := make([]uint64, numHashes)
+	state := uint64(minhashSeed)
+	for i := range seeds {
+		state = splitmix64(state)
+		seeds[i] = state
+	}
+	return &MinHasher{seeds: seeds}
+}
+
+func (m *MinHasher) Size() int {
+	return len(m.seeds)
+}
+
+// Signature returns nil for an empty shingle set, which has nothing to match.
+func (m *MinHasher) Signature(shingles ShingleSet) Signature {
+	if len(shingles) == 0 {
+		return nil
+	}
+	sig := make(Signature, len(m.seeds))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for h := range shingles {
+		for i, seed := range m.seeds {
+			if v := splitmix64(h ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// EstimateJaccard is the share of equal positions, an unbiased estimate of the
+// Jaccard index of the underlying shingle sets.
+func EstimateJaccard(a, b Signature) float64 {
+	n := min(len(a), len(b))
+	if n == 0 {
+		return 0
+	}
+	equal := 0
+	for i := 0; i < n; i++ {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(n)
+}
+
+// Buckets splits the first bands×rows values of the signature into bands and
+// hashes each band to a bucket key. Two texts become candidates when they
+// share a bucket in any band, which for Jaccard s happens with probability
+// 1 - (1 - s^rows)^bands.
+func (sig Signature) Buckets(bands, rows int) []int64 {
+	if bands*rows > len(sig) {
+		return nil
+	}
+	keys := make([]int64, bands)
+	buf := make([]byte, 8)
+	for b := 0; b < bands; b++ {
+		h := fnv.New64a()
+		for _, v := range sig[b*rows : (b+1)*rows] {
+			binary.BigEndian.PutUint64(buf, v)
+			_, _ = h.Write(buf)
+		}
+		keys[b] = int64(h.Sum64())
+	}
+	return keys
+}
+
+// LSHThreshold is the Jaccard index at which the candidate probability of
+// the banding rises most steeply, roughly where recall reaches one half.
+func LSHThreshold(bands, rows int) float64 {
+	return math.Pow(1/float64(bands), 1/float64(rows))
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
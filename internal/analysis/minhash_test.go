@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+// hashRange is the shingle set {from, ..., to-1}.
+func hashRange(from, to uint64) ShingleSet {
+	set := ShingleSet{}
+	for h := from; h < to; h++ {
+		set[h] = struct{}{}
+	}
+	return set
+}
+
+func TestMinHasherSignature(t *testing.T) {
+	m := NewMinHasher(64)
+	if got := m.Signature(ShingleSet{}); got != nil {
+		t.Errorf("Signature of an empty set = %v, want nil", got)
+	}
+	a := m.Signature(hashRange(0, 100))
+	if len(a) != m.Size() {
+		t.Fatalf("len(Signature) = %d, want %d", len(a), m.Size())
+	}
+	if b := NewMinHasher(64).Signature(hashRange(0, 100)); EstimateJaccard(a, b) != 1 {
+		t.Error("signatures of the same set differ between hashers")
+	}
+	if b := NewMinHasher(128).Signature(hashRange(0, 100)); EstimateJaccard(a, b[:64]) != 1 {
+		t.Error("a larger family does not extend the smaller one")
+	}
+}
+
+func TestEstimateJaccard(t *testing.T) {
+	m := NewMinHasher(256)
+	tests := []struct {
+		name string
+		a, b Signature
+		want float64
+		tol  float64
+	}{
+		{"identical", m.Signature(hashRange(0, 1000)), m.Signature(hashRange(0, 1000)), 1, 0},
+		{"disjoint", m.Signature(hashRange(0, 1000)), m.Signature(hashRange(1000, 2000)), 0, 0.02},
+		{"one third", m.Signature(hashRange(0, 1000)), m.Signature(hashRange(500, 1500)), 1.0 / 3, 0.1},
+		{"contained half", m.Signature(hashRange(0, 500)), m.Signature(hashRange(0, 1000)), 0.5, 0.1},
+		{"empty", nil, m.Signature(hashRange(0, 10)), 0, 0},
+		{"different lengths compare the common prefix", Signature{1, 2, 3, 4}, Signature{1, 2}, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateJaccard(tt.a, tt.b); math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("EstimateJaccard = %.3f, want %.3f ± %.2f", got, tt.want, tt.tol)
+			}
+		})
+	}
+}
+
+func TestSignatureBuckets(t *testing.T) {
+	sig := Signature{1, 2, 3, 4, 5, 6, 7, 8}
+	oneChanged := Signature{1, 2, 3, 4, 5, 99, 7, 8}
+
+	tests := []struct {
+		name       string
+		a, b       Signature
+		bands      int
+		rows       int
+		wantShared []bool
+	}{
+		{"identical signatures share every band", sig, sig, 4, 2, []bool{true, true, true, true}},
+		{"a changed value moves only its band", sig, oneChanged, 4, 2, []bool{true, true, false, true}},
+		{"values past bands×rows are ignored", sig, oneChanged, 2, 2, []bool{true, true}},
+		{"single band", sig, oneChanged, 1, 8, []bool{false}},
+		{"too few values", sig, sig, 3, 3, nil},
+		{"empty signature", nil, nil, 1, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.a.Buckets(tt.bands, tt.rows), tt.b.Buckets(tt.bands, tt.rows)
+			if tt.wantShared == nil {
+				if a != nil {
+					t.Fatalf("Buckets = %v, want nil", a)
+				}
+				return
+			}
+			if len(a) != tt.bands || len(b) != tt.bands {
+				t.Fatalf("got %d and %d buckets, want %d", len(a), len(b), tt.bands)
+			}
+			for i, want := range tt.wantShared {
+				if got := a[i] == b[i]; got != want {
+					t.Errorf("band %d shared = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSignatureBucketsKeepRowOrder(t *testing.T) {
+	// Rows are positions of different hash functions, so a band with the
+	// same values in another order is a different bucket.
+	keys := Signature{1, 2, 2, 1}.Buckets(2, 2)
+	if keys[0] == keys[1] {
+		t.Errorf("bands {1 2} and {2 1} share bucket %d", keys[0])
+	}
+}
+
+func TestLSHThreshold(t *testing.T) {
+	tests := []struct {
+		bands, rows int
+		want        float64
+	}{
+		{1, 1, 1},
+		{16, 1, 1.0 / 16},
+		{20, 5, 0.5493},
+		{32, 4, 0.4204},
+	}
+	for _, tt := range tests {
+		if got := LSHThreshold(tt.bands, tt.rows); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("LSHThreshold(%d, %d) = %.4f, want %.4f", tt.bands, tt.rows, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of obfuscation found in a work.
+const (
+	FindingHomoglyphs     = "homoglyphs"
+	FindingInvisibleChars = "invisible_chars"
+	FindingHiddenText     = "hidden_text"
+
+	// SeverityHigh marks findings that point at a deliberate attempt to get
+	// past the check rather than at similarity.
+	SeverityHigh = "high"
+)
+
+const (
+	// maxFindingSpans caps the positions stored with a finding.
+	maxFindingSpans = 50
+
+	// maxFindingSample caps the text quoted in a finding.
+	maxFindingSample = 300
+)
+
+// Span is a passage of a work's text in characters (runes), End exclusive.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Finding is a sign of obfuscation in a work: letters swapped for look-alikes
+// from another alphabet, invisible characters or text the file hides from the
+// reader. Count is the number of affected words or characters, Spans their
+// positions in the work's text and Sample an excerpt of hidden text.
+type Finding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+	Details  string `json:"details"`
+	Spans    []Span `json:"spans,omitempty"`
+	Sample   string `json:"sample,omitempty"`
+}
+
+// Scripts that share look-alike letters.
+const (
+	scriptOther = iota
+	scriptLatin
+	scriptCyrillic
+	scriptGreek
+	numScripts
+)
+
+// lookAlikes lists letters that look the same in Latin, Cyrillic and Greek;
+// 0 means the script has no such letter.
+var lookAlikes = [][numScripts]rune{
+	{0, 'a', 'а', 0}, {0, 'A', 'А', 'Α'}, {0, 'B', 'В', 'Β'}, {0, 'c', 'с', 0}, {0, 'C', 'С', 0},
+	{0, 'd', 'ԁ', 0}, {0, 'e', 'е', 0}, {0, 'E', 'Е', 'Ε'}, {0, 'h', 'һ', 0}, {0, 'H', 'Н', 'Η'},
+	{0, 'i', 'і', 0}, {0, 'I', 'І', 'Ι'}, {0, 'j', 'ј', 0}, {0, 'J', 'Ј', 0}, {0, 'K', 'К', 'Κ'},
+	{0, 'M', 'М', 'Μ'}, {0, 'N', 0, 'Ν'}, {0, 'o', 'о', 'ο'}, {0, 'O', 'О', 'Ο'}, {0, 'p', 'р', 'ρ'},
+	{0, 'P', 'Р', 'Ρ'}, {0, 's', 'ѕ', 0}, {0, 'S', 'Ѕ', 0}, {0, 'T', 'Т', 'Τ'}, {0, 'v', 0, 'ν'},
+	{0, 'x', 'х', 'χ'}, {0, 'X', 'Х', 'Χ'}, {0, 'y', 'у', 0}, {0, 'Y', 'Ү', 'Υ'}, {0, 'Z', 0, 'Ζ'},
+}
+
+// lookAlike maps a letter to its look-alikes indexed by script.
+var lookAlike = func() map[rune]*[numScripts]rune {
+	m := map[rune]*[numScripts]rune{}
+	for i := range lookAlikes {
+		for _, r := range lookAlikes[i] {
+			if r != 0 {
+				m[r] = &lookAlikes[i]
+			}
+		}
+	}
+	return m
+}()
+
+func scriptOf(r rune) int {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	case unicode.Is(unicode.Cyrillic, r):
+		return scriptCyrillic
+	case unicode.Is(unicode.Greek, r):
+		return scriptGreek
+	}
+	return scriptOther
+}
+
+// isInvisible reports whether r is a character that takes no space when
+// shown: zero-width spaces and joiners, direction marks and other format
+// characters, and the blank Hangul fillers.
+func isInvisible(r rune) bool {
+	return unicode.Is(unicode.Cf, r) || r == '\u115F' || r == '\u1160' || r == '\u3164' || r == '\uFFA0'
+}
+
+// dropInvisible removes invisible characters, so a zero-width space inside
+// a word does not split it.
+func dropInvisible(text string) string {
+	return strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// wordSpans returns the words of text: runs of letters, digits and the
+// invisible characters hidden inside them.
+func wordSpans(text []rune) []Span {
+	var words []Span
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && isInvisible(r))
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, Span{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, Span{Start: start, End: len(text)})
+	}
+	return words
+}
+
+// wordScript decides which alphabet a word that mixes them is written in:
+// the one with more letters that have no look-alike, or failing that with
+// more letters at all. It returns scriptOther for words in one alphabet.
+func wordScript(word []rune) int {
+	var all, distinct [numScripts]int
+	used := 0
+	for _, r := range word {
+		s := scriptOf(r)
+		if s == scriptOther {
+			continue
+		}
+		if all[s] == 0 {
+			used++
+		}
+		all[s]++
+		if lookAlike[r] == nil {
+			distinct[s]++
+		}
+	}
+	if used < 2 {
+		return scriptOther
+	}
+	best := scriptCyrillic
+	for s := scriptLatin; s < numScripts; s++ {
+		if distinct[s] > distinct[best] || (distinct[s] == distinct[best] && all[s] > all[best]) {
+			best = s
+		}
+	}
+	return best
+}
+
+// foldWord rewrites the look-alike letters of a mixed word in its main
+// alphabet. Letters without a look-alike there stay as they are.
+func foldWord(word []rune, script int) {
+	for i, r := range word {
+		if s := scriptOf(r); s == scriptOther || s == script {
+			continue
+		}
+		if row := lookAlike[r]; row != nil && row[script] != 0 {
+			word[i] = row[script]
+		}
+	}
+}
+
+// CanonicalText folds homoglyphs: in every word that mixes Latin, Cyrillic
+// and Greek letters, look-alike letters are rewritten in the word's main
+// alphabet, so "cлово" with a Latin c compares equal to "слово". Words in a
+// single alphabet are left alone, and the text keeps its length, so
+// positions found in it point into the extracted text. Invisible characters
+// are kept for the same reason; Words, Fingerprints and LexCode skip them.
+func CanonicalText(text string) string {
+	runes := []rune(text)
+	changed := false
+	for _, w := range wordSpans(runes) {
+		word := runes[w.Start:w.End]
+		if script := wordScript(word); script != scriptOther {
+			foldWord(word, script)
+			changed = true
+		}
+	}
+	if !changed {
+		return text
+	}
+	return string(runes)
+}
+
+// FindObfuscation looks for ways of hiding copied text from a checker in a
+// work's extracted text and the text its file hides from the reader.
+func FindObfuscation(text, hidden string) []Finding {
+	runes := []rune(text)
+	var findings []Finding
+
+	var mixed []Span
+	for _, w := range wordSpans(runes) {
+		if wordScript(runes[w.Start:w.End]) != scriptOther {
+			mixed = append(mixed, w)
+		}
+	}
+	if len(mixed) > 0 {
+		w := mixed[0]
+		findings = append(findings, Finding{
+			Kind:     FindingHomoglyphs,
+			Severity: SeverityHigh,
+			Count:    len(mixed),
+			Details: fmt.Sprintf("%d words mix letters of different alphabets that look alike, e.g. %q",
+				len(mixed), dropInvisible(string(runes[w.Start:w.End]))),
+			Spans: capSpans(mixed),
+		})
+	}
+
+	var invisible []Span
+	count := 0
+	codes := map[rune]bool{}
+	for i, r := range runes {
+		if !isInvisible(r) {
+			continue
+		}
+		count++
+		codes[r] = true
+		if n := len(invisible); n > 0 && invisible[n-1].End == i {
+			invisible[n-1].End++
+		} else {
+			invisible = append(invisible, Span{Start: i, End: i + 1})
+		}
+	}
+	if count > 0 {
+		findings = append(findings, Finding{
+			Kind:     FindingInvisibleChars,
+			Severity: SeverityHigh,
+			Count:    count,
+			Details:  fmt.Sprintf("%d invisible characters (%s) in the text", count, codePoints(codes)),
+			Spans:    capSpans(invisible),
+		})
+	}
+
+	if hidden = strings.TrimSpace(hidden); hidden != "" {
+		sample := []rune(hidden)
+		chars := len(sample)
+		if len(sample) > maxFindingSample {
+			sample = sample[:maxFindingSample]
+		}
+		findings = append(findings, Finding{
+			Kind:     FindingHiddenText,
+			Severity: SeverityHigh,
+			Count:    chars,
+			Details:  fmt.Sprintf("%d characters of text hidden in the file (white, invisible or too small to read)", chars),
+			Sample:   string(sample),
+		})
+	}
+	return findings
+}
+
+func capSpans(spans []Span) []Span {
+	if len(spans) > maxFindingSpans {
+		return spans[:maxFindingSpans]
+	}
+	return spans
+}
+
+// codePoints lists the characters as U+XXXX, at most five of them.
+func codePoints(set map[rune]bool) string {
+	codes := make([]rune, 0, len(set))
+	for r := range set {
+		codes = append(codes, r)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	var parts []string
+	for i, r := range codes {
+		if i == 5 {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, fmt.Sprintf("U+%04X", r))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Look-alike letters are written as escapes so the tests show which
+// alphabet each one comes from.
+const (
+	cyrA = "\u0430" // Cyrillic а
+	cyrO = "\u043E" // Cyrillic о
+	cyrS = "\u0441" // Cyrillic с
+	grkO = "\u03BF" // Greek ο
+	zwsp = "\u200B" // zero-width space
+	zwj  = "\u200D" // zero-width joiner
+)
+
+func TestCanonicalText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"single alphabet words are kept", "hello мир Ωμέγα", "hello мир Ωμέγα"},
+		{"latin letter in a cyrillic word", "c" + "лово", cyrS + "лово"},
+		{"cyrillic letters in a latin word", "p" + cyrA + "ssw" + cyrO + "rd", "password"},
+		{"greek letter in a latin word", "hell" + grkO, "hello"},
+		{"letters without a look-alike stay", "Джо" + "N" + "н", "ДжоNн"},
+		{"invisible characters are kept in place", "c" + zwsp + "лово", cyrS + zwsp + "лово"},
+		{"positions after multi-byte runes", "🙂 日本 c" + "лово, " + "p" + cyrA + "th", "🙂 日本 " + cyrS + "лово, path"},
+		{"digits are part of the word", "v2" + cyrO + "ne", "v2one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CanonicalText(tt.text)
+			if got != tt.want {
+				t.Errorf("CanonicalText(%+q) = %+q, want %+q", tt.text, got, tt.want)
+			}
+			if n, want := utf8.RuneCountInString(got), utf8.RuneCountInString(tt.text); n != want {
+				t.Errorf("CanonicalText changed the length from %d to %d runes", want, n)
+			}
+		})
+	}
+}
+
+func TestCanonicalTextKeepsOffsets(t *testing.T) {
+	text := "Введение. 🙂 Этот тeкст (с латинской e) и " + zwsp + "ещё p" + cyrA + "ssage."
+	got := []rune(CanonicalText(text))
+	orig := []rune(text)
+	if len(got) != len(orig) {
+		t.Fatalf("length changed from %d to %d runes", len(orig), len(got))
+	}
+	for i := range orig {
+		if scriptOf(orig[i]) == scriptOther && got[i] != orig[i] {
+			t.Errorf("rune %d changed from %q to %q", i, orig[i], got[i])
+		}
+	}
+}
+
+func TestFindObfuscation(t *testing.T) {
+	longHidden := strings.Repeat("скрыто ", 100)
+
+	tests := []struct {
+		name   string
+		text   string
+		hidden string
+		want   []Finding
+	}{
+		{name: "empty", text: "", hidden: "", want: nil},
+		{name: "clean text", text: "Обычный текст. Plain text.", hidden: " \n ", want: nil},
+		{
+			name: "homoglyphs",
+			text: "Это c" + "лово и втор" + "o" + "е",
+			want: []Finding{{
+				Kind: FindingHomoglyphs, Severity: SeverityHigh, Count: 2,
+				Details: `2 words mix letters of different alphabets that look alike, e.g. "cлово"`,
+				Spans:   []Span{{Start: 4, End: 9}, {Start: 12, End: 18}},
+			}},
+		},
+		{
+			name: "invisible characters",
+			text: "a" + zwsp + zwsp + "b c" + zwj + "d",
+			want: []Finding{{
+				Kind: FindingInvisibleChars, Severity: SeverityHigh, Count: 3,
+				Details: "3 invisible characters (U+200B, U+200D) in the text",
+				Spans:   []Span{{Start: 1, End: 3}, {Start: 6, End: 7}},
+			}},
+		},
+		{
+			name: "spans count runes after multi-byte characters",
+			text: "🙂日本" + zwsp + "語",
+			want: []Finding{{
+				Kind: FindingInvisibleChars, Severity: SeverityHigh, Count: 1,
+				Details: "1 invisible characters (U+200B) in the text",
+				Spans:   []Span{{Start: 3, End: 4}},
+			}},
+		},
+		{
+			name: "invisible character inside a mixed word",
+			text: "c" + zwsp + "лово",
+			want: []Finding{
+				{
+					Kind: FindingHomoglyphs, Severity: SeverityHigh, Count: 1,
+					Details: `1 words mix letters of different alphabets that look alike, e.g. "cлово"`,
+					Spans:   []Span{{Start: 0, End: 6}},
+				},
+				{
+					Kind: FindingInvisibleChars, Severity: SeverityHigh, Count: 1,
+					Details: "1 invisible characters (U+200B) in the text",
+					Spans:   []Span{{Start: 1, End: 2}},
+				},
+			},
+		},
+		{
+			name:   "hidden text",
+			text:   "Видимый текст.",
+			hidden: "  белый текст  ",
+			want: []Finding{{
+				Kind: FindingHiddenText, Severity: SeverityHigh, Count: 11,
+				Details: "11 characters of text hidden in the file (white, invisible or too small to read)",
+				Sample:  "белый текст",
+			}},
+		},
+		{
+			name:   "long hidden text is sampled",
+			text:   "Видимый текст.",
+			hidden: longHidden,
+			want: []Finding{{
+				Kind: FindingHiddenText, Severity: SeverityHigh, Count: 699,
+				Details: "699 characters of text hidden in the file (white, invisible or too small to read)",
+				Sample:  string([]rune(longHidden)[:maxFindingSample]),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindObfuscation(tt.text, tt.hidden); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindObfuscation =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindObfuscationCapsSpans(t *testing.T) {
+	text := strings.Repeat("x"+zwsp+"y ", maxFindingSpans+10)
+	findings := FindObfuscation(text, "")
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1", len(findings))
+	}
+	f := findings[0]
+	if f.Count != maxFindingSpans+10 {
+		t.Errorf("Count = %d, want %d", f.Count, maxFindingSpans+10)
+	}
+	if len(f.Spans) != maxFindingSpans {
+		t.Errorf("%d spans stored, want %d", len(f.Spans), maxFindingSpans)
+	}
+}
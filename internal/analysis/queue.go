@@ -0,0 +1,175 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"HW_KPO3/internal/config"
+)
+
+// Queue runs plagiarism checks in the background. The reports table is the
+// queue itself: a pending report is a job, and workers claim jobs one at a
+// time with ClaimReport. Every job runs the registered detectors the task
+// has enabled.
+type Queue struct {
+	repo      *Repository
+	storage   *StorageClient
+	detectors *Registry
+	cfg       config.Queue
+	winnowK   int
+	wake      chan struct{}
+}
+
+func NewQueue(repo *Repository, storage *StorageClient, detectors *Registry, cfg config.Queue, winnowing config.Winnowing) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if winnowing.K <= 0 {
+		winnowing.K = DefaultWinnowK
+	}
+	return &Queue{
+		repo:      repo,
+		storage:   storage,
+		detectors: detectors,
+		cfg:       cfg,
+		winnowK:   winnowing.K,
+		wake:      make(chan struct{}, cfg.Workers),
+	}
+}
+
+// Notify wakes an idle worker so a new report does not wait for the next
+// poll.
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled and every worker
+// has stopped.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			q.work(ctx, worker)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context, worker int) {
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		report, err := q.repo.ClaimReport(ctx, q.cfg.JobTimeout)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to claim report", "worker", worker, "err", err)
+		}
+		if report != nil {
+			q.process(ctx, worker, report)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) process(ctx context.Context, worker int, report *Report) {
+	log := slog.With("worker", worker, "report_id", report.ID, "work_id", report.WorkID, "attempt", report.Attempts)
+	if report.Attempts > q.cfg.MaxAttempts {
+		report.Status, report.Error = StatusFailed, fmt.Sprintf("gave up after %d attempts", q.cfg.MaxAttempts)
+		q.finish(log, report)
+		return
+	}
+
+	log.Info("processing report")
+	checkCtx, cancel := context.WithTimeout(ctx, q.cfg.JobTimeout)
+	err := q.check(checkCtx, report)
+	cancel()
+	if ctx.Err() != nil {
+		// Shutting down: hand the report back instead of failing it.
+		if err := q.repo.ReleaseReport(context.Background(), report.ID); err != nil {
+			log.Error("failed to release report", "err", err)
+		}
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrNoText):
+		report.Status, report.Error = StatusFailed, err.Error()
+	case errors.Is(err, ErrWorkNotFound):
+		report.Status, report.Error = StatusFailed, "work not found in storage"
+	case errors.Is(err, context.DeadlineExceeded):
+		report.Status, report.Error = StatusFailed, "plagiarism check timed out"
+	case err != nil:
+		log.Error("plagiarism check failed", "err", err)
+		if report.Attempts < q.cfg.MaxAttempts {
+			// Most likely storage is briefly unavailable. The report stays in
+			// processing and is claimed again once JobTimeout has passed.
+			return
+		}
+		report.Status, report.Error = StatusFailed, "plagiarism check failed: storage unavailable"
+	default:
+		report.Status = StatusDone
+	}
+	q.finish(log, report)
+}
+
+// check runs the task's detectors and fills in the report. The report's
+// similarity is that of its best match.
+func (q *Queue) check(ctx context.Context, report *Report) error {
+	report.Similarity = SimilarityUnknown
+	sub, err := q.storage.LoadSubmission(ctx, report.WorkID)
+	if err != nil {
+		return err
+	}
+	settings, err := q.repo.GetTaskDetectors(ctx, sub.Work.TaskID, q.detectors.Names())
+	if err != nil {
+		return err
+	}
+
+	runs := make([]detectorRun, len(settings))
+	for i, d := range q.detectors.Detectors() {
+		runs[i].settings = settings[i]
+		if !settings[i].Enabled {
+			continue
+		}
+		if runs[i].detection, err = d.Detect(ctx, sub); err != nil {
+			return fmt.Errorf("detector %s: %w", d.Name(), err)
+		}
+	}
+
+	report.BoilerplateChars, report.BoilerplateShare = measureBoilerplate(sub, q.winnowK)
+	report.Citations, report.CitationChars, report.CitationShare = measureCitations(sub)
+	report.Findings = sub.Findings
+	report.Matches, report.Scores = combine(sub, runs)
+	report.Similarity, report.MatchedWorkID = 0, nil
+	if len(report.Matches) > 0 {
+		report.Similarity, report.MatchedWorkID = report.Matches[0].Similarity, &report.Matches[0].WorkID
+	}
+	report.Details = joinDetails(report.Scores)
+	return nil
+}
+
+func (q *Queue) finish(log *slog.Logger, report *Report) {
+	// The report must leave processing even if the service is stopping.
+	if err := q.repo.FinishReport(context.Background(), report); err != nil {
+		log.Error("failed to save report result", "err", err)
+		return
+	}
+	log.Info("report finished", "status", report.Status, "similarity", report.Similarity)
+}
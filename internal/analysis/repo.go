@@ -2,21 +2,65 @@ package analysis
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const SimilarityUnknown = -1.0
 
+// Report lifecycle: pending → processing → done | failed.
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+)
+
+// Report is the outcome of checking one work. BoilerplateChars is how much
+// of the work's text comes from the task's templates and was left out of the
+// comparison, BoilerplateShare the same in percent of the text. Citations are
+// the quotations and references left out of the text comparison, with
+// CitationChars and CitationShare counted the same way. Findings are signs of
+// obfuscation, reported apart from the similarity.
 type Report struct {
-	ID         int64     `json:"id"`
-	WorkID     int64     `json:"work_id"`
-	Status     string    `json:"status"`
-	Similarity float64   `json:"similarity"`
-	Details    string    `json:"details"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID               int64           `json:"id"`
+	WorkID           int64           `json:"work_id"`
+	Status           string          `json:"status"`
+	Similarity       float64         `json:"similarity"`
+	MatchedWorkID    *int64          `json:"matched_work_id"`
+	Details          string          `json:"details"`
+	BoilerplateChars int             `json:"boilerplate_chars"`
+	BoilerplateShare float64         `json:"boilerplate_share"`
+	Citations        []Citation      `json:"citations"`
+	CitationChars    int             `json:"citation_chars"`
+	CitationShare    float64         `json:"citation_share"`
+	Findings         []Finding       `json:"findings"`
+	Error            string          `json:"error"`
+	Attempts         int             `json:"attempts"`
+	CreatedAt        time.Time       `json:"created_at"`
+	StartedAt        *time.Time      `json:"started_at"`
+	FinishedAt       *time.Time      `json:"finished_at"`
+	Scores           []DetectorScore `json:"scores"`
+	Matches          []ReportMatch   `json:"matches"`
+}
+
+const reportColumns = `id, work_id, status, similarity, matched_work_id, details, scores, boilerplate_chars, boilerplate_share,
+    citations, citation_chars, citation_share, findings, error, attempts, created_at, started_at, finished_at`
+
+func scanReport(row pgx.Row) (*Report, error) {
+	var report Report
+	err := row.Scan(&report.ID, &report.WorkID, &report.Status, &report.Similarity, &report.MatchedWorkID,
+		&report.Details, &report.Scores, &report.BoilerplateChars, &report.BoilerplateShare,
+		&report.Citations, &report.CitationChars, &report.CitationShare, &report.Findings, &report.Error, &report.Attempts, &report.CreatedAt, &report.StartedAt, &report.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
 }
 
 type Repository struct {
@@ -29,11 +73,11 @@ func NewRepository(pool *pgxpool.Pool) *Repository {
 
 func (r Repository) CreateReport(ctx context.Context, report *Report) error {
 	const query = `
-	INSERT INTO reports (work_id, status, similarity, details)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO reports (work_id, status, similarity, matched_work_id, details, error)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	RETURNING id, created_at;`
 
-	row := r.pool.QueryRow(ctx, query, report.WorkID, report.Status, report.Similarity, report.Details)
+	row := r.pool.QueryRow(ctx, query, report.WorkID, report.Status, report.Similarity, report.MatchedWorkID, report.Details, report.Error)
 	if err := row.Scan(&report.ID, &report.CreatedAt); err != nil {
 		return fmt.Errorf("failed to insert report: %w", err)
 	}
@@ -41,31 +85,140 @@ func (r Repository) CreateReport(ctx context.Context, report *Report) error {
 }
 
 func (r Repository) GetReport(ctx context.Context, id int64) (*Report, error) {
-	const query = `
-    SELECT id, work_id, status, similarity, details, created_at 
-    FROM reports 
-    WHERE id = $1;`
+	query := `
+    SELECT ` + reportColumns + `
+    FROM reports
+    WHERE id = $1 AND hidden_at IS NULL;`
 
-	row := r.pool.QueryRow(ctx, query, id)
-	var report Report
-	if err := row.Scan(&report.ID, &report.WorkID, &report.Status, &report.Similarity, &report.Details, &report.CreatedAt); err != nil {
+	report, err := scanReport(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
 		return nil, fmt.Errorf("failed to get report: %w", err)
 	}
-	return &report, nil
+	return report, nil
 }
 
 func (r Repository) GetReportByWorkID(ctx context.Context, workID int64) (*Report, error) {
-	const query = `
-    SELECT id, work_id, status, similarity, details, created_at 
-    FROM reports 
-    WHERE work_id = $1
+	query := `
+    SELECT ` + reportColumns + `
+    FROM reports
+    WHERE work_id = $1 AND hidden_at IS NULL
     ORDER BY created_at DESC
     LIMIT 1;`
 
-	row := r.pool.QueryRow(ctx, query, workID)
-	var report Report
-	if err := row.Scan(&report.ID, &report.WorkID, &report.Status, &report.Similarity, &report.Details, &report.CreatedAt); err != nil {
+	report, err := scanReport(r.pool.QueryRow(ctx, query, workID))
+	if err != nil {
 		return nil, fmt.Errorf("failed to get report by work_id: %w", err)
 	}
-	return &report, nil
+	return report, nil
+}
+
+// ClaimReport moves the oldest pending report to processing and returns it.
+// Reports stuck in processing for longer than staleAfter, e.g. because their
+// worker died, are claimed again. It returns nil when there is nothing to do.
+// SKIP LOCKED lets several workers, also in other instances, claim in
+// parallel without taking the same report.
+func (r Repository) ClaimReport(ctx context.Context, staleAfter time.Duration) (*Report, error) {
+	query := `
+    UPDATE reports SET status = $1, started_at = NOW(), attempts = attempts + 1
+    WHERE id = (
+        SELECT id FROM reports
+        WHERE hidden_at IS NULL
+          AND (status = $2 OR (status = $1 AND started_at < NOW() - make_interval(secs => $3)))
+        ORDER BY id
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING ` + reportColumns + `;`
+
+	report, err := scanReport(r.pool.QueryRow(ctx, query, StatusProcessing, StatusPending, staleAfter.Seconds()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim report: %w", err)
+	}
+	return report, nil
+}
+
+// FinishReport stores the outcome of a processed report with its matches.
+func (r Repository) FinishReport(ctx context.Context, report *Report) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to finish report: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if report.Scores == nil {
+		report.Scores = []DetectorScore{}
+	}
+	scores, err := json.Marshal(report.Scores)
+	if err != nil {
+		return fmt.Errorf("failed to encode report scores: %w", err)
+	}
+	if report.Citations == nil {
+		report.Citations = []Citation{}
+	}
+	citations, err := json.Marshal(report.Citations)
+	if err != nil {
+		return fmt.Errorf("failed to encode report citations: %w", err)
+	}
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	findings, err := json.Marshal(report.Findings)
+	if err != nil {
+		return fmt.Errorf("failed to encode report findings: %w", err)
+	}
+
+	const query = `
+    UPDATE reports
+    SET status = $2, similarity = $3, matched_work_id = $4, details = $5, scores = $6,
+        boilerplate_chars = $7, boilerplate_share = $8, citations = $9, citation_chars = $10, citation_share = $11,
+        findings = $12, error = $13, finished_at = NOW()
+    WHERE id = $1
+    RETURNING finished_at;`
+
+	row := tx.QueryRow(ctx, query, report.ID, report.Status, report.Similarity, report.MatchedWorkID, report.Details, scores,
+		report.BoilerplateChars, report.BoilerplateShare, citations, report.CitationChars, report.CitationShare,
+		findings, report.Error)
+	if err := row.Scan(&report.FinishedAt); err != nil {
+		return fmt.Errorf("failed to finish report: %w", err)
+	}
+
+	// A retried report may already have matches from an earlier attempt.
+	if err := insertMatches(ctx, tx, report.ID, report.Matches); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to finish report: %w", err)
+	}
+	return nil
+}
+
+// ReleaseReport puts a report that was interrupted by shutdown back into the
+// queue without counting the attempt.
+func (r Repository) ReleaseReport(ctx context.Context, id int64) error {
+	const query = `
+    UPDATE reports SET status = $2, started_at = NULL, attempts = GREATEST(attempts - 1, 0)
+    WHERE id = $1 AND status = $3;`
+
+	if _, err := r.pool.Exec(ctx, query, id, StatusPending, StatusProcessing); err != nil {
+		return fmt.Errorf("failed to release report: %w", err)
+	}
+	return nil
+}
+
+// HideReportsByWorkID marks all reports of a withdrawn work as hidden so they
+// no longer show up in lookups.
+func (r Repository) HideReportsByWorkID(ctx context.Context, workID int64) (int64, error) {
+	const query = `
+    UPDATE reports SET hidden_at = NOW()
+    WHERE work_id = $1 AND hidden_at IS NULL;`
+
+	tag, err := r.pool.Exec(ctx, query, workID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hide reports: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+const DefaultShingleSize = 5
+
+// ErrNoText means the work has no extracted text to compare.
+var ErrNoText = errors.New("work has no extracted text")
+
+// ShingleSet is the set of hashed word n-grams of a text.
+type ShingleSet map[uint64]struct{}
+
+// Words splits text into lower-cased words made of letters and digits.
+// Invisible characters inside a word do not split it.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(dropInvisible(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Shingles hashes every run of size consecutive words. Texts shorter than
+// size produce a single shingle of all their words.
+func Shingles(words []string, size int) ShingleSet {
+	set := ShingleSet{}
+	if len(words) == 0 {
+		return set
+	}
+	if size <= 0 {
+		size = DefaultShingleSize
+	}
+	if len(words) < size {
+		size = len(words)
+	}
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		for _, w := range words[i : i+size] {
+			_, _ = h.Write([]byte(w))
+			_, _ = h.Write([]byte{0})
+		}
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+// Compare returns the Jaccard index of a and b and the containment of a in
+// b, i.e. the share of a's shingles that also occur in b.
+func Compare(a, b ShingleSet) (jaccard, containment float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	small, large := a, b
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	common := 0
+	for h := range small {
+		if _, ok := large[h]; ok {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	return float64(common) / float64(union), float64(common) / float64(len(a))
+}
+
+// ShingleMatch is the shingle score of the checked work against one other
+// work.
+type ShingleMatch struct {
+	WorkID      int64
+	Similarity  float64
+	Jaccard     float64
+	Containment float64
+}
+
+// CheckResult is the best match found for a work plus every other work that
+// shares shingles with it. Excluded is the share of the work's shingles that
+// come from the task's templates or its citations, in percent.
+type CheckResult struct {
+	Similarity    float64
+	MatchedWorkID *int64
+	Jaccard       float64
+	Containment   float64
+	Compared      int
+	Indexed       int
+	Excluded      float64
+	Matches       []ShingleMatch
+}
+
+// without returns the shingles of s that are not in template.
+func (s ShingleSet) without(template ShingleSet) ShingleSet {
+	if len(template) == 0 {
+		return s
+	}
+	rest := ShingleSet{}
+	for h := range s {
+		if _, ok := template[h]; !ok {
+			rest[h] = struct{}{}
+		}
+	}
+	return rest
+}
+
+// ShingleDetector compares a work against the other works of its task by
+// word n-gram shingles. The candidate index narrows the comparison down, so
+// only candidates are fetched and scored exactly.
+type ShingleDetector struct {
+	storage *StorageClient
+	index   *CandidateIndex
+	size    int
+}
+
+func NewShingleDetector(storage *StorageClient, index *CandidateIndex) *ShingleDetector {
+	return &ShingleDetector{storage: storage, index: index, size: index.size}
+}
+
+// Check scores the work against the candidates the index returns. The
+// similarity is the higher of Jaccard and containment, in percent. Citations
+// of both works and shingles of the task's templates are left out first; the
+// index keeps all shingles, so it does not have to be rebuilt when templates
+// change.
+func (d *ShingleDetector) Check(ctx context.Context, sub *Submission) (*CheckResult, error) {
+	shingles := Shingles(Words(sub.Text), d.size)
+	template := ShingleSet{}
+	for _, text := range sub.templateTexts("") {
+		for h := range Shingles(Words(text), d.size) {
+			template[h] = struct{}{}
+		}
+	}
+	own := d.uncited(sub.Text, sub.Citations).without(template)
+
+	candidates, indexed, err := d.index.Candidates(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[int64]Work{}
+	for _, w := range sub.TaskWorks {
+		live[w.ID] = w
+	}
+	result := &CheckResult{Indexed: indexed}
+	if len(shingles) > 0 {
+		excluded := 0
+		for h := range shingles {
+			if _, ok := own[h]; !ok {
+				excluded++
+			}
+		}
+		result.Excluded = float64(excluded) / float64(len(shingles)) * 100
+	}
+	for _, id := range candidates {
+		other := live[id]
+		otherText, err := d.storage.GetWorkText(ctx, id)
+		if err != nil {
+			slog.Warn("failed to get text of work to compare", "work_id", id, "err", err)
+			continue
+		}
+		otherShingles := d.uncited(otherText.Text, citationsOf(other.FileName, otherText.Text))
+		jaccard, containment := Compare(own, otherShingles.without(template))
+		result.Compared++
+		score := max(jaccard, containment) * 100
+		if score > 0 {
+			result.Matches = append(result.Matches, ShingleMatch{WorkID: id, Similarity: score, Jaccard: jaccard, Containment: containment})
+		}
+		if score > result.Similarity {
+			result.Similarity, result.MatchedWorkID = score, &id
+			result.Jaccard, result.Containment = jaccard, containment
+		}
+	}
+	return result, nil
+}
+
+// uncited shingles the passages of text between its citations, so that no
+// shingle reaches into a quotation.
+func (d *ShingleDetector) uncited(text string, citations []Citation) ShingleSet {
+	if len(citations) == 0 {
+		return Shingles(Words(text), d.size)
+	}
+	set := ShingleSet{}
+	for _, part := range uncitedParts(text, citations) {
+		for h := range Shingles(Words(part), d.size) {
+			set[h] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (d *ShingleDetector) Name() string {
+	return DetectorShingles
+}
+
+func (d *ShingleDetector) Detect(ctx context.Context, sub *Submission) (*Detection, error) {
+	result, err := d.Check(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	detection := &Detection{Applies: true, Details: result.Details(), Excluded: result.Excluded}
+	for _, m := range result.Matches {
+		detection.Matches = append(detection.Matches, Evidence{WorkID: m.WorkID, Similarity: m.Similarity})
+	}
+	return detection, nil
+}
+
+// Details is the human-readable summary stored with the report.
+func (r *CheckResult) Details() string {
+	if r.Compared == 0 {
+		return fmt.Sprintf("No similar works among %d indexed works of this task", r.Indexed)
+	}
+	if r.MatchedWorkID == nil {
+		return fmt.Sprintf("Compared with %d candidate works, no common fragments found", r.Compared)
+	}
+	return fmt.Sprintf("Compared with %d candidate works; closest is work %d (Jaccard %.1f%%, containment %.1f%%)",
+		r.Compared, *r.MatchedWorkID, r.Jaccard*100, r.Containment*100)
+}
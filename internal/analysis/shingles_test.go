@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: nil},
+		{text: "  ...  ", want: nil},
+		{text: "Мама мыла РАМУ.", want: []string{"мама", "мыла", "раму"}},
+		{text: "x1-y2, (z3)", want: []string{"x1", "y2", "z3"}},
+	}
+	for _, tt := range tests {
+		got := Words(tt.text)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestShingles(t *testing.T) {
+	words := strings.Fields("a b c d e f")
+	tests := []struct {
+		name  string
+		words []string
+		size  int
+		want  int
+	}{
+		{name: "empty", words: nil, size: 3, want: 0},
+		{name: "shorter than size is one shingle", words: words[:2], size: 3, want: 1},
+		{name: "every window", words: words, size: 3, want: 4},
+		{name: "repeated windows count once", words: strings.Fields("a b a b a b"), size: 2, want: 2},
+		{name: "non-positive size uses the default", words: words, size: 0, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Shingles(tt.words, tt.size); len(got) != tt.want {
+				t.Errorf("Shingles() has %d shingles, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	// Words are separated in the hash, so "ab c" and "a bc" differ.
+	if reflect.DeepEqual(Shingles([]string{"ab", "c"}, 2), Shingles([]string{"a", "bc"}, 2)) {
+		t.Error("shingles of differently split words are equal")
+	}
+}
+
+// shingleRange is the set of hashes from, from+1, ..., to-1.
+func shingleRange(from, to uint64) ShingleSet {
+	set := ShingleSet{}
+	for h := from; h < to; h++ {
+		set[h] = struct{}{}
+	}
+	return set
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name            string
+		a, b            ShingleSet
+		wantJaccard     float64
+		wantContainment float64
+	}{
+		{name: "both empty", a: ShingleSet{}, b: ShingleSet{}, wantJaccard: 0, wantContainment: 0},
+		{name: "one empty", a: shingleRange(0, 4), b: ShingleSet{}, wantJaccard: 0, wantContainment: 0},
+		{name: "identical", a: shingleRange(0, 4), b: shingleRange(0, 4), wantJaccard: 1, wantContainment: 1},
+		{name: "disjoint", a: shingleRange(0, 4), b: shingleRange(4, 8), wantJaccard: 0, wantContainment: 0},
+		{name: "half overlap", a: shingleRange(0, 4), b: shingleRange(2, 6), wantJaccard: 2.0 / 6, wantContainment: 0.5},
+		{name: "a inside b", a: shingleRange(0, 2), b: shingleRange(0, 8), wantJaccard: 0.25, wantContainment: 1},
+		{name: "b inside a", a: shingleRange(0, 8), b: shingleRange(0, 2), wantJaccard: 0.25, wantContainment: 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jaccard, containment := Compare(tt.a, tt.b)
+			if math.Abs(jaccard-tt.wantJaccard) > 1e-9 || math.Abs(containment-tt.wantContainment) > 1e-9 {
+				t.Errorf("Compare() = %v, %v; want %v, %v", jaccard, containment, tt.wantJaccard, tt.wantContainment)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Storage text statuses, see storage.TextStatus*.
+const (
+	TextStatusPending = "pending"
+	TextStatusDone    = "done"
+)
+
+// textPollInterval is how often a check looks again at a text that storage
+// is still extracting.
+const textPollInterval = time.Second
+
+var ErrWorkNotFound = errors.New("work not found")
+
+// Work is the part of a storage work the analysis service relies on.
+type Work struct {
+	ID         int64  `json:"id"`
+	StudentID  int64  `json:"student_id"`
+	Student    string `json:"student"`
+	TaskID     int64  `json:"task_id"`
+	Task       string `json:"task"`
+	Version    int    `json:"version"`
+	FileName   string `json:"file_name"`
+	TextStatus string `json:"text_status"`
+	TextError  string `json:"text_error"`
+	UploadedAt string `json:"uploaded_at"`
+}
+
+// WorkText is the extracted text of a work. Hidden is the text its file
+// keeps from the reader, see storage.Extracted.
+type WorkText struct {
+	WorkID int64  `json:"work_id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Text   string `json:"text"`
+	Hidden string `json:"hidden_text"`
+}
+
+// Template is a file handed out with the task, see storage GET
+// /tasks/{id}/templates.
+type Template struct {
+	ID         int64  `json:"id"`
+	FileName   string `json:"file_name"`
+	TextStatus string `json:"text_status"`
+	Text       string `json:"text"`
+}
+
+// Submission is a work prepared for the detectors: its extracted text with
+// homoglyphs folded, the live works of its task that have text, the work
+// itself included, the task's templates whose content is not to be counted
+// as copied, the passages of the text that cite sources, and the signs of
+// obfuscation found in the original text. The candidates from the word and
+// code indexes are looked up once and shared by the detectors.
+type Submission struct {
+	Work      *Work
+	Text      string
+	TaskWorks []Work
+	Templates []Template
+	Citations []Citation
+	Findings  []Finding
+
+	candidates *candidateSet
+	code       *codeCandidateSet
+}
+
+// StorageClient reads works and their extracted text from the storage
+// service.
+type StorageClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewStorageClient(baseURL string, timeout time.Duration) *StorageClient {
+	return &StorageClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *StorageClient) GetWork(ctx context.Context, id int64) (*Work, error) {
+	var work Work
+	if err := c.getJSON(ctx, "/works/"+strconv.FormatInt(id, 10), &work); err != nil {
+		return nil, fmt.Errorf("get work %d: %w", id, err)
+	}
+	return &work, nil
+}
+
+// GetWorkText returns the text of a work as the detectors compare it, with
+// homoglyphs folded, see CanonicalText.
+func (c *StorageClient) GetWorkText(ctx context.Context, id int64) (*WorkText, error) {
+	text, err := c.getWorkText(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	text.Text = CanonicalText(text.Text)
+	return text, nil
+}
+
+func (c *StorageClient) getWorkText(ctx context.Context, id int64) (*WorkText, error) {
+	var text WorkText
+	if err := c.getJSON(ctx, "/works/"+strconv.FormatInt(id, 10)+"/text", &text); err != nil {
+		return nil, fmt.Errorf("get text of work %d: %w", id, err)
+	}
+	return &text, nil
+}
+
+// waitWorkText polls the text of a work while it is pending.
+func (c *StorageClient) waitWorkText(ctx context.Context, id int64) (*WorkText, error) {
+	for {
+		text, err := c.getWorkText(ctx, id)
+		if err != nil || text.Status != TextStatusPending {
+			return text, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(textPollInterval):
+		}
+	}
+}
+
+func (c *StorageClient) GetTaskTemplates(ctx context.Context, taskID int64) ([]Template, error) {
+	var templates []Template
+	if err := c.getJSON(ctx, "/tasks/"+strconv.FormatInt(taskID, 10)+"/templates", &templates); err != nil {
+		return nil, fmt.Errorf("get templates of task %d: %w", taskID, err)
+	}
+	for i := range templates {
+		templates[i].Text = CanonicalText(templates[i].Text)
+	}
+	return templates, nil
+}
+
+// ListTaskWorks pages through every live work of a task.
+func (c *StorageClient) ListTaskWorks(ctx context.Context, taskID int64) ([]Work, error) {
+	var works []Work
+	cursor := ""
+	for {
+		q := url.Values{}
+		q.Set("task_id", strconv.FormatInt(taskID, 10))
+		q.Set("sort", "id")
+		q.Set("limit", "100")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		var page struct {
+			Items      []Work `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.getJSON(ctx, "/works?"+q.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("list works of task %d: %w", taskID, err)
+		}
+		works = append(works, page.Items...)
+		if page.NextCursor == "" {
+			return works, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// LoadSubmission fetches everything the detectors need about a work. Storage
+// extracts text in the background, so a fresh upload is waited for until ctx
+// is done; a work without extracted text yields ErrNoText. Obfuscation is
+// looked for in the text as extracted, before homoglyphs are folded.
+func (c *StorageClient) LoadSubmission(ctx context.Context, workID int64) (*Submission, error) {
+	work, err := c.GetWork(ctx, workID)
+	if err != nil {
+		return nil, err
+	}
+	text, err := c.waitWorkText(ctx, workID)
+	if err != nil {
+		return nil, err
+	}
+	if text.Status != TextStatusDone {
+		reason := text.Error
+		if reason == "" {
+			reason = "text status " + text.Status
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNoText, reason)
+	}
+	works, err := c.ListTaskWorks(ctx, work.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := c.GetTaskTemplates(ctx, work.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	canonical := CanonicalText(text.Text)
+	sub := &Submission{
+		Work:      work,
+		Text:      canonical,
+		Citations: citationsOf(work.FileName, canonical),
+		Findings:  FindObfuscation(text.Text, text.Hidden),
+	}
+	for _, w := range works {
+		if w.TextStatus == TextStatusDone {
+			sub.TaskWorks = append(sub.TaskWorks, w)
+		}
+	}
+	for _, t := range templates {
+		if t.TextStatus == TextStatusDone {
+			sub.Templates = append(sub.Templates, t)
+		}
+	}
+	return sub, nil
+}
+
+func (c *StorageClient) getJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrWorkNotFound
+	default:
+		return fmt.Errorf("unexpected status %d from storage", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
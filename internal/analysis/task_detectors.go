@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Settings a detector has for a task that has not configured it.
+const (
+	DefaultDetectorWeight    = 1.0
+	DefaultDetectorThreshold = 0.0
+)
+
+// DetectorSettings says whether a detector runs for a task, how much its
+// score weighs in the combined similarity and below which score it counts
+// as zero.
+type DetectorSettings struct {
+	Detector  string  `json:"detector"`
+	Enabled   bool    `json:"enabled"`
+	Weight    float64 `json:"weight"`
+	Threshold float64 `json:"threshold"`
+	Custom    bool    `json:"custom"`
+}
+
+func defaultDetectorSettings(name string) DetectorSettings {
+	return DetectorSettings{Detector: name, Enabled: true, Weight: DefaultDetectorWeight, Threshold: DefaultDetectorThreshold}
+}
+
+type taskDetectorsRequest struct {
+	Detectors []struct {
+		Detector  string   `json:"detector"`
+		Enabled   *bool    `json:"enabled"`
+		Weight    *float64 `json:"weight"`
+		Threshold *float64 `json:"threshold"`
+	} `json:"detectors"`
+}
+
+type taskDetectorsResponse struct {
+	TaskID    int64              `json:"task_id"`
+	Detectors []DetectorSettings `json:"detectors"`
+}
+
+// GetTaskDetectors returns the settings of every named detector for the task,
+// in the given order. Detectors the task has not configured get defaults.
+func (r Repository) GetTaskDetectors(ctx context.Context, taskID int64, names []string) ([]DetectorSettings, error) {
+	const query = `
+    SELECT detector, enabled, weight, threshold FROM task_detectors WHERE task_id = $1;`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task detectors: %w", err)
+	}
+	defer rows.Close()
+	custom := map[string]DetectorSettings{}
+	for rows.Next() {
+		s := DetectorSettings{Custom: true}
+		if err := rows.Scan(&s.Detector, &s.Enabled, &s.Weight, &s.Threshold); err != nil {
+			return nil, fmt.Errorf("failed to get task detectors: %w", err)
+		}
+		custom[s.Detector] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get task detectors: %w", err)
+	}
+
+	settings := make([]DetectorSettings, len(names))
+	for i, name := range names {
+		if s, ok := custom[name]; ok {
+			settings[i] = s
+		} else {
+			settings[i] = defaultDetectorSettings(name)
+		}
+	}
+	return settings, nil
+}
+
+// SaveTaskDetectors replaces the task's detector settings. Detectors left out
+// go back to defaults.
+func (r Repository) SaveTaskDetectors(ctx context.Context, taskID int64, settings []DetectorSettings) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save task detectors: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM task_detectors WHERE task_id = $1;`, taskID); err != nil {
+		return fmt.Errorf("failed to clear task detectors: %w", err)
+	}
+	const insert = `
+    INSERT INTO task_detectors (task_id, detector, enabled, weight, threshold, updated_at)
+    VALUES ($1, $2, $3, $4, $5, NOW());`
+	for _, s := range settings {
+		if _, err := tx.Exec(ctx, insert, taskID, s.Detector, s.Enabled, s.Weight, s.Threshold); err != nil {
+			return fmt.Errorf("failed to save task detectors: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save task detectors: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) GetTaskDetectors(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	settings, err := h.repo.GetTaskDetectors(r.Context(), taskID, h.detectors.Names())
+	if err != nil {
+		slog.Error("failed to get task detectors", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &taskDetectorsResponse{TaskID: taskID, Detectors: settings})
+}
+
+// UpdateTaskDetectors configures the detectors of a task. Omitted fields of a
+// listed detector take their defaults; unlisted detectors are reset.
+func (h *Handler) UpdateTaskDetectors(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task_id parameter", http.StatusBadRequest)
+		return
+	}
+	var req taskDetectorsRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	custom := map[string]DetectorSettings{}
+	var settings []DetectorSettings
+	for _, d := range req.Detectors {
+		if _, ok := h.detectors.Get(d.Detector); !ok {
+			http.Error(w, fmt.Sprintf("unknown detector %q", d.Detector), http.StatusBadRequest)
+			return
+		}
+		if _, ok := custom[d.Detector]; ok {
+			http.Error(w, fmt.Sprintf("detector %q is listed twice", d.Detector), http.StatusBadRequest)
+			return
+		}
+		s := defaultDetectorSettings(d.Detector)
+		if d.Enabled != nil {
+			s.Enabled = *d.Enabled
+		}
+		if d.Weight != nil {
+			s.Weight = *d.Weight
+		}
+		if d.Threshold != nil {
+			s.Threshold = *d.Threshold
+		}
+		if s.Weight < 0 {
+			http.Error(w, "weight must not be negative", http.StatusBadRequest)
+			return
+		}
+		if s.Threshold < 0 || s.Threshold > 100 {
+			http.Error(w, "threshold must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+		custom[s.Detector] = s
+		settings = append(settings, s)
+	}
+	active := false
+	for _, name := range h.detectors.Names() {
+		s, ok := custom[name]
+		if !ok {
+			s = defaultDetectorSettings(name)
+		}
+		active = active || (s.Enabled && s.Weight > 0)
+	}
+	if !active {
+		http.Error(w, "at least one detector must be enabled with a positive weight", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SaveTaskDetectors(r.Context(), taskID, settings); err != nil {
+		slog.Error("failed to save task detectors", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	saved, err := h.repo.GetTaskDetectors(r.Context(), taskID, h.detectors.Names())
+	if err != nil {
+		slog.Error("failed to get task detectors", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &taskDetectorsResponse{TaskID: taskID, Detectors: saved})
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestUpdateTaskDetectorsRejects covers the settings refused before anything
+// is stored, so the handler needs no repository.
+func TestUpdateTaskDetectorsRejects(t *testing.T) {
+	registry := NewRegistry()
+	for _, name := range []string{DetectorShingles, DetectorWinnowing} {
+		if err := registry.Register(fakeDetector(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{detectors: registry}
+
+	tests := []struct {
+		name   string
+		taskID string
+		body   string
+	}{
+		{name: "invalid task", taskID: "x", body: `{"detectors":[]}`},
+		{name: "malformed json", taskID: "1", body: `{"detectors":`},
+		{name: "unknown detector", taskID: "1", body: `{"detectors":[{"detector":"magic"}]}`},
+		{name: "listed twice", taskID: "1", body: `{"detectors":[{"detector":"shingles"},{"detector":"shingles"}]}`},
+		{name: "negative weight", taskID: "1", body: `{"detectors":[{"detector":"shingles","weight":-1}]}`},
+		{name: "negative threshold", taskID: "1", body: `{"detectors":[{"detector":"shingles","threshold":-0.1}]}`},
+		{name: "threshold above 100", taskID: "1", body: `{"detectors":[{"detector":"shingles","threshold":100.1}]}`},
+		{
+			name:   "all weights zero",
+			taskID: "1",
+			body:   `{"detectors":[{"detector":"shingles","weight":0},{"detector":"winnowing","weight":0}]}`,
+		},
+		{
+			name:   "all disabled",
+			taskID: "1",
+			body:   `{"detectors":[{"detector":"shingles","enabled":false},{"detector":"winnowing","enabled":false}]}`,
+		},
+		{
+			// The unlisted detector is back to its defaults, but it is
+			// the only one with a weight and it is disabled.
+			name:   "the only weighted detector disabled",
+			taskID: "1",
+			body:   `{"detectors":[{"detector":"shingles","weight":0},{"detector":"winnowing","enabled":false,"weight":2}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("task_id", tt.taskID)
+			req := httptest.NewRequest(http.MethodPut, "/tasks/"+tt.taskID+"/detectors", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+			h.UpdateTaskDetectors(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"unicode"
+
+	"HW_KPO3/internal/config"
+)
+
+const (
+	DefaultWinnowK      = 30
+	DefaultWinnowWindow = 20
+
+	// maxHashOccurrences skips k-grams that repeat within a text, such as a
+	// header on every page: they say nothing about copying and would pair up
+	// every occurrence with every other.
+	maxHashOccurrences = 10
+
+	// maxMatchFragments caps the evidence stored for one matched work.
+	maxMatchFragments = 100
+
+	rollingBase = 1099511628211
+)
+
+// Fingerprint is a selected k-gram hash with the span of the k-gram in the
+// original text. Offsets count characters (runes), End is exclusive.
+type Fingerprint struct {
+	Hash  uint64
+	Start int
+	End   int
+}
+
+// Fragment is a matched passage: Start/End in the checked work and
+// MatchedStart/MatchedEnd in the work identified by WorkID.
+type Fragment struct {
+	WorkID       int64 `json:"work_id"`
+	Start        int   `json:"start"`
+	End          int   `json:"end"`
+	MatchedStart int   `json:"matched_start"`
+	MatchedEnd   int   `json:"matched_end"`
+}
+
+// Fingerprints winnows the k-gram hashes of text as in MOSS: in every window
+// of consecutive hashes the rightmost minimum is kept. Case, whitespace and
+// punctuation are ignored, so any common passage of at least k+window-1
+// letters and digits is guaranteed to share a fingerprint.
+func Fingerprints(text string, k, window int) []Fingerprint {
+	var chars []rune
+	var offsets []int
+	offset := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			chars = append(chars, unicode.ToLower(r))
+			offsets = append(offsets, offset)
+		}
+		offset++
+	}
+	if len(chars) == 0 {
+		return nil
+	}
+	if k > len(chars) {
+		k = len(chars)
+	}
+
+	// Rolling polynomial hash of every k-gram, then mixed so that minima are
+	// spread evenly.
+	hashes := make([]uint64, len(chars)-k+1)
+	var h, pow uint64 = 0, 1
+	for i := 0; i < k; i++ {
+		h = h*rollingBase + uint64(chars[i])
+		if i > 0 {
+			pow *= rollingBase
+		}
+	}
+	hashes[0] = splitmix64(h)
+	for i := 1; i < len(hashes); i++ {
+		h = (h-uint64(chars[i-1])*pow)*rollingBase + uint64(chars[i+k-1])
+		hashes[i] = splitmix64(h)
+	}
+
+	if window > len(hashes) {
+		window = len(hashes)
+	}
+	var prints []Fingerprint
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		minPos := start
+		for i := start + 1; i < start+window; i++ {
+			if hashes[i] <= hashes[minPos] {
+				minPos = i
+			}
+		}
+		if minPos != last {
+			prints = append(prints, Fingerprint{
+				Hash:  hashes[minPos],
+				Start: offsets[minPos],
+				End:   offsets[minPos+k-1] + 1,
+			})
+			last = minPos
+		}
+	}
+	return prints
+}
+
+// MatchFragments pairs up the fingerprints two texts share and merges pairs
+// that continue each other in both texts into fragments.
+func MatchFragments(own, other []Fingerprint, otherID int64) []Fragment {
+	byHash := map[uint64][]Fingerprint{}
+	for _, fp := range other {
+		byHash[fp.Hash] = append(byHash[fp.Hash], fp)
+	}
+	ownCount := map[uint64]int{}
+	for _, fp := range own {
+		ownCount[fp.Hash]++
+	}
+
+	var pairs []Fragment
+	for _, fp := range own {
+		matches := byHash[fp.Hash]
+		if len(matches) == 0 || len(matches) > maxHashOccurrences || ownCount[fp.Hash] > maxHashOccurrences {
+			continue
+		}
+		for _, m := range matches {
+			pairs = append(pairs, Fragment{WorkID: otherID, Start: fp.Start, End: fp.End, MatchedStart: m.Start, MatchedEnd: m.End})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Start != pairs[j].Start {
+			return pairs[i].Start < pairs[j].Start
+		}
+		return pairs[i].MatchedStart < pairs[j].MatchedStart
+	})
+
+	var fragments []Fragment
+	for _, p := range pairs {
+		if n := len(fragments); n > 0 {
+			cur := &fragments[n-1]
+			if p.Start <= cur.End && p.MatchedStart >= cur.MatchedStart && p.MatchedStart <= cur.MatchedEnd {
+				cur.End = max(cur.End, p.End)
+				cur.MatchedEnd = max(cur.MatchedEnd, p.MatchedEnd)
+				continue
+			}
+		}
+		fragments = append(fragments, p)
+	}
+	return fragments
+}
+
+// coveredChars is the number of characters of the checked text that lie in
+// at least one fragment.
+func coveredChars(fragments []Fragment) int {
+	spans := make([]Fragment, len(fragments))
+	copy(spans, fragments)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	covered, end := 0, 0
+	for _, s := range spans {
+		if s.Start > end {
+			end = s.Start
+		}
+		if s.End > end {
+			covered += s.End - end
+			end = s.End
+		}
+	}
+	return covered
+}
+
+// WinnowMatch is the common passages of the checked work with one other work.
+// Similarity is the share of the checked text they cover, in percent.
+type WinnowMatch struct {
+	WorkID     int64
+	Similarity float64
+	Fragments  []Fragment
+}
+
+// WinnowResult is the best match by fingerprints plus every work that shares
+// any. Excluded is the share of the text made of template passages and
+// citations, in percent.
+type WinnowResult struct {
+	Similarity    float64
+	MatchedWorkID *int64
+	Excluded      float64
+	Matches       []WinnowMatch
+}
+
+// WinnowDetector finds common passages through the stored fingerprints of the
+// task's works. Lookups go by hash, so only works that share fingerprints
+// with the checked one are read.
+type WinnowDetector struct {
+	storage *StorageClient
+	repo    *Repository
+	k       int
+	window  int
+}
+
+func NewWinnowDetector(storage *StorageClient, repo *Repository, cfg config.Winnowing) *WinnowDetector {
+	d := &WinnowDetector{storage: storage, repo: repo, k: cfg.K, window: cfg.Window}
+	if d.k <= 0 {
+		d.k = DefaultWinnowK
+	}
+	if d.window <= 0 {
+		d.window = DefaultWinnowWindow
+	}
+	return d
+}
+
+// Check fingerprints the work and collects the fragments it shares with works
+// of other students of the task. Fingerprints of template passages and of
+// the work's citations are subtracted before the lookup, so neither text
+// every student got from the teacher nor a properly quoted source ever
+// matches. The similarity with a work is the share of the rest of the
+// checked text covered by fragments matched in it, in percent.
+func (d *WinnowDetector) Check(ctx context.Context, sub *Submission) (*WinnowResult, error) {
+	all := Fingerprints(sub.Text, d.k, d.window)
+	if err := d.repo.SaveFingerprints(ctx, d.fingerprintSet(sub.Work, sub.Text), all); err != nil {
+		return nil, err
+	}
+	if err := d.indexTask(ctx, sub.TaskWorks); err != nil {
+		return nil, err
+	}
+	template := templateKGrams(sub.templateTexts(""), d.k)
+	own := make([]Fingerprint, 0, len(all))
+	for _, fp := range all {
+		if !template[fp.Hash] && !cited(sub.Citations, fp.Start, fp.End) {
+			own = append(own, fp)
+		}
+	}
+	others, err := d.repo.FindSharedFingerprints(ctx, sub.Work, d.k, d.window, own)
+	if err != nil {
+		return nil, err
+	}
+
+	// Students are taken from the live works, as the stored fingerprint sets
+	// keep the student a work had when it was fingerprinted.
+	live := map[int64]bool{}
+	for _, w := range sub.TaskWorks {
+		live[w.ID] = w.StudentID != sub.Work.StudentID
+	}
+	textLen := len([]rune(sub.Text))
+	excluded := coveredChars(append(boilerplateSpans(sub.Text, d.k, template), citationSpans(sub.Citations)...))
+	result := &WinnowResult{}
+	if textLen > 0 {
+		result.Excluded = float64(excluded) / float64(textLen) * 100
+	}
+	for otherID, prints := range others {
+		if !live[otherID] || textLen <= excluded {
+			continue
+		}
+		fragments := MatchFragments(own, prints, otherID)
+		if len(fragments) == 0 {
+			continue
+		}
+		match := WinnowMatch{
+			WorkID:     otherID,
+			Similarity: min(float64(coveredChars(fragments))/float64(textLen-excluded)*100, 100),
+			Fragments:  fragments,
+		}
+		// Longest fragments first, so the cap drops the least convincing ones.
+		sort.Slice(match.Fragments, func(i, j int) bool {
+			a, b := match.Fragments[i], match.Fragments[j]
+			if la, lb := a.End-a.Start, b.End-b.Start; la != lb {
+				return la > lb
+			}
+			return a.Start < b.Start
+		})
+		if len(match.Fragments) > maxMatchFragments {
+			match.Fragments = match.Fragments[:maxMatchFragments]
+		}
+		result.Matches = append(result.Matches, match)
+		if match.Similarity > result.Similarity {
+			result.Similarity, result.MatchedWorkID = match.Similarity, &otherID
+		}
+	}
+	return result, nil
+}
+
+func (d *WinnowDetector) Name() string {
+	return DetectorWinnowing
+}
+
+func (d *WinnowDetector) Detect(ctx context.Context, sub *Submission) (*Detection, error) {
+	result, err := d.Check(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	text := []rune(sub.Text)
+	detection := &Detection{Applies: true, Details: result.Details(), Excluded: result.Excluded}
+	for _, m := range result.Matches {
+		detection.Matches = append(detection.Matches, Evidence{
+			WorkID:        m.WorkID,
+			Similarity:    m.Similarity,
+			MatchedTokens: matchedTokens(text, m.Fragments),
+			Fragments:     m.Fragments,
+		})
+	}
+	return detection, nil
+}
+
+func (d *WinnowDetector) fingerprintSet(work *Work, text string) *FingerprintSet {
+	return &FingerprintSet{
+		WorkID:     work.ID,
+		TaskID:     work.TaskID,
+		StudentID:  work.StudentID,
+		K:          d.k,
+		Window:     d.window,
+		TextLength: len([]rune(text)),
+	}
+}
+
+// indexTask fingerprints the task's works that have no fingerprints for the
+// current k and window yet.
+func (d *WinnowDetector) indexTask(ctx context.Context, works []Work) error {
+	if len(works) == 0 {
+		return nil
+	}
+	done, err := d.repo.FingerprintedWorks(ctx, works[0].TaskID, d.k, d.window)
+	if err != nil {
+		return err
+	}
+	for i := range works {
+		w := &works[i]
+		if done[w.ID] {
+			continue
+		}
+		text, err := d.storage.GetWorkText(ctx, w.ID)
+		if err != nil {
+			slog.Warn("failed to get text of work to fingerprint", "work_id", w.ID, "err", err)
+			continue
+		}
+		prints := Fingerprints(text.Text, d.k, d.window)
+		if err := d.repo.SaveFingerprints(ctx, d.fingerprintSet(w, text.Text), prints); err != nil {
+			return fmt.Errorf("fingerprint work %d: %w", w.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *WinnowResult) Details() string {
+	if r.MatchedWorkID == nil {
+		return "no common passages found"
+	}
+	fragments := 0
+	for _, m := range r.Matches {
+		fragments += len(m.Fragments)
+	}
+	return fmt.Sprintf("%d common passages with %d works, up to %.1f%% of the text (work %d)",
+		fragments, len(r.Matches), r.Similarity, *r.MatchedWorkID)
+}
@@ -0,0 +1,199 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"unicode"
+)
+
+func TestFingerprints(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		k, window int
+		wantNone  bool
+	}{
+		{name: "empty", text: "", k: 5, window: 4, wantNone: true},
+		{name: "punctuation only", text: " ,.!? — \n", k: 5, window: 4, wantNone: true},
+		{name: "shorter than k", text: "abc", k: 5, window: 4},
+		{name: "fewer k-grams than the window", text: "abcdefg", k: 5, window: 10},
+		{name: "latin", text: "The quick brown fox jumps over the lazy dog.", k: 5, window: 4},
+		{name: "multi-byte runes", text: "Съешь же ещё этих мягких французских булок, да выпей чаю.", k: 5, window: 4},
+		{name: "mixed scripts and digits", text: "Шаг 1: x := ёлка(42); 日本語のテキスト", k: 4, window: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prints := Fingerprints(tt.text, tt.k, tt.window)
+			if tt.wantNone {
+				if prints != nil {
+					t.Fatalf("Fingerprints = %v, want nil", prints)
+				}
+				return
+			}
+			if len(prints) == 0 {
+				t.Fatal("no fingerprints")
+			}
+			runes := []rune(tt.text)
+			for i, fp := range prints {
+				if fp.Start < 0 || fp.End > len(runes) || fp.Start >= fp.End {
+					t.Fatalf("fingerprint %d spans [%d, %d) in %d runes", i, fp.Start, fp.End, len(runes))
+				}
+				if i > 0 && fp.Start <= prints[i-1].Start {
+					t.Errorf("fingerprint %d starts at %d, not after %d", i, fp.Start, prints[i-1].Start)
+				}
+				span := runes[fp.Start:fp.End]
+				if !isWordRune(span[0]) || !isWordRune(span[len(span)-1]) {
+					t.Errorf("fingerprint %d span %q does not start and end on a letter", i, string(span))
+				}
+				if n, want := countWordRunes(span), min(tt.k, countWordRunes(runes)); n != want {
+					t.Errorf("fingerprint %d span %q has %d letters, want %d", i, string(span), n, want)
+				}
+			}
+		})
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func countWordRunes(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		if isWordRune(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func fingerprintHashes(prints []Fingerprint) []uint64 {
+	hashes := make([]uint64, len(prints))
+	for i, fp := range prints {
+		hashes[i] = fp.Hash
+	}
+	return hashes
+}
+
+func TestFingerprintsIgnoreCaseAndPunctuation(t *testing.T) {
+	a := Fingerprints("Привет, Мир! Это — тест winnowing.", 5, 4)
+	b := Fingerprints("привет мир это тест WINNOWING", 5, 4)
+	if !reflect.DeepEqual(fingerprintHashes(a), fingerprintHashes(b)) {
+		t.Errorf("hashes differ:\n%v\n%v", fingerprintHashes(a), fingerprintHashes(b))
+	}
+}
+
+func TestFingerprintsGuaranteeSharedPassage(t *testing.T) {
+	const k, window = 5, 4
+	passage := "общийфрагмент" // k+window-1 letters and more
+	a := Fingerprints("начало первого текста "+passage+" и его конец", k, window)
+	b := Fingerprints("совсем другой текст, но "+passage, k, window)
+	if len(MatchFragments(a, b, 2)) == 0 {
+		t.Errorf("no shared fingerprint for a passage of %d letters", len([]rune(passage)))
+	}
+}
+
+func TestMatchFragments(t *testing.T) {
+	fp := func(hash uint64, start int) Fingerprint {
+		return Fingerprint{Hash: hash, Start: start, End: start + 10}
+	}
+	repeated := make([]Fingerprint, maxHashOccurrences+1)
+	for i := range repeated {
+		repeated[i] = fp(7, 100+20*i)
+	}
+
+	tests := []struct {
+		name  string
+		own   []Fingerprint
+		other []Fingerprint
+		want  []Fragment
+	}{
+		{name: "empty own", own: nil, other: []Fingerprint{fp(1, 0)}, want: nil},
+		{name: "empty other", own: []Fingerprint{fp(1, 0)}, other: nil, want: nil},
+		{name: "no common hash", own: []Fingerprint{fp(1, 0)}, other: []Fingerprint{fp(2, 0)}, want: nil},
+		{
+			name:  "overlapping pairs merge",
+			own:   []Fingerprint{fp(1, 0), fp(2, 5), fp(3, 12)},
+			other: []Fingerprint{fp(1, 100), fp(2, 105), fp(3, 112)},
+			want:  []Fragment{{WorkID: 9, Start: 0, End: 22, MatchedStart: 100, MatchedEnd: 122}},
+		},
+		{
+			name:  "a gap splits fragments",
+			own:   []Fingerprint{fp(1, 0), fp(2, 30)},
+			other: []Fingerprint{fp(1, 50), fp(2, 80)},
+			want: []Fragment{
+				{WorkID: 9, Start: 0, End: 10, MatchedStart: 50, MatchedEnd: 60},
+				{WorkID: 9, Start: 30, End: 40, MatchedStart: 80, MatchedEnd: 90},
+			},
+		},
+		{
+			name:  "overlapping in own but not in other",
+			own:   []Fingerprint{fp(1, 0), fp(2, 5)},
+			other: []Fingerprint{fp(1, 100), fp(2, 40)},
+			want: []Fragment{
+				{WorkID: 9, Start: 0, End: 10, MatchedStart: 100, MatchedEnd: 110},
+				{WorkID: 9, Start: 5, End: 15, MatchedStart: 40, MatchedEnd: 50},
+			},
+		},
+		{
+			name:  "a passage copied twice matches both copies",
+			own:   []Fingerprint{fp(1, 0)},
+			other: []Fingerprint{fp(1, 10), fp(1, 60)},
+			want: []Fragment{
+				{WorkID: 9, Start: 0, End: 10, MatchedStart: 10, MatchedEnd: 20},
+				{WorkID: 9, Start: 0, End: 10, MatchedStart: 60, MatchedEnd: 70},
+			},
+		},
+		{
+			name:  "hashes repeated too often are ignored",
+			own:   []Fingerprint{fp(7, 0), fp(1, 40)},
+			other: append([]Fingerprint{fp(1, 20)}, repeated...),
+			want:  []Fragment{{WorkID: 9, Start: 40, End: 50, MatchedStart: 20, MatchedEnd: 30}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchFragments(tt.own, tt.other, 9); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchFragments =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFragmentsOfIdenticalTexts(t *testing.T) {
+	text := "Съешь же ещё этих мягких французских булок, да выпей чаю."
+	prints := Fingerprints(text, 5, 4)
+	fragments := MatchFragments(prints, prints, 1)
+	if len(fragments) != 1 {
+		t.Fatalf("got %d fragments, want 1: %+v", len(fragments), fragments)
+	}
+	f := fragments[0]
+	if f.Start != f.MatchedStart || f.End != f.MatchedEnd {
+		t.Errorf("fragment %+v does not map onto itself", f)
+	}
+	if f.Start != prints[0].Start || f.End != prints[len(prints)-1].End {
+		t.Errorf("fragment [%d, %d) does not span the fingerprints [%d, %d)", f.Start, f.End, prints[0].Start, prints[len(prints)-1].End)
+	}
+}
+
+func TestCoveredChars(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans []Fragment
+		want  int
+	}{
+		{"none", nil, 0},
+		{"single", []Fragment{{Start: 3, End: 8}}, 5},
+		{"disjoint", []Fragment{{Start: 10, End: 12}, {Start: 0, End: 4}}, 6},
+		{"overlapping", []Fragment{{Start: 0, End: 10}, {Start: 5, End: 15}}, 15},
+		{"nested", []Fragment{{Start: 0, End: 20}, {Start: 5, End: 10}}, 20},
+		{"touching", []Fragment{{Start: 0, End: 5}, {Start: 5, End: 9}}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coveredChars(tt.spans); got != tt.want {
+				t.Errorf("coveredChars = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,8 +11,11 @@ import (
 type Config struct {
 	Env            string        `yaml:"env" env:"ENV" env-default:"local"`
 	StoragePath    string        `yaml:"storage_path" env:"STORAGE_PATH" env-default:"./storage"`
+	BlobStorage    BlobStorage   `yaml:"blob_storage"`
+	Submissions    Submissions   `yaml:"submissions"`
 	HTTPServer     HTTPServer    `yaml:"http_server"`
 	AnalysisServer HTTPServer    `yaml:"analysis_server"`
+	Analysis       Analysis      `yaml:"analysis"`
 	StorageDB      StorageDB     `yaml:"storage_db"`
 	AnalysisDB     AnalysisDB    `yaml:"analysis_db"`
 	Gateway        GatewayConfig `yaml:"gateway"`
@@ -24,6 +27,87 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"120s"`
 }
 
+// BlobStorage selects the blob backend. Blobs no work or template refers to
+// are removed by a periodic sweep once they are older than GCGrace, which
+// leaves an upload in flight time to insert its work.
+type BlobStorage struct {
+	Backend    string        `yaml:"backend" env:"BLOB_BACKEND" env-default:"local"`
+	S3         S3            `yaml:"s3"`
+	GCInterval time.Duration `yaml:"gc_interval" env:"BLOB_GC_INTERVAL" env-default:"1h"`
+	GCGrace    time.Duration `yaml:"gc_grace" env:"BLOB_GC_GRACE" env-default:"24h"`
+}
+
+type S3 struct {
+	Endpoint  string        `yaml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string        `yaml:"region" env:"S3_REGION" env-default:"us-east-1"`
+	Bucket    string        `yaml:"bucket" env:"S3_BUCKET" env-default:"works"`
+	AccessKey string        `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string        `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	Timeout   time.Duration `yaml:"timeout" env:"S3_TIMEOUT" env-default:"60s"`
+}
+
+// Submissions controls what happens to works submitted after a task deadline.
+// Late works are always flagged; with RejectLate they are refused once the
+// deadline, the task's grace period and HardCutoff have all passed.
+type Submissions struct {
+	RejectLate bool          `yaml:"reject_late" env:"SUBMISSIONS_REJECT_LATE" env-default:"false"`
+	HardCutoff time.Duration `yaml:"hard_cutoff" env:"SUBMISSIONS_HARD_CUTOFF" env-default:"0s"`
+}
+
+// Analysis configures the plagiarism checks of the analysis service, which
+// reads works and their extracted text from storage.
+type Analysis struct {
+	StorageBaseURL string        `yaml:"storage_base_url" env:"STORAGE_BASE_URL"`
+	StorageTimeout time.Duration `yaml:"storage_timeout" env:"ANALYSIS_STORAGE_TIMEOUT" env-default:"30s"`
+	ShingleSize    int           `yaml:"shingle_size" env:"ANALYSIS_SHINGLE_SIZE" env-default:"5"`
+	Queue          Queue         `yaml:"queue"`
+	LSH            LSH           `yaml:"lsh"`
+	Winnowing      Winnowing     `yaml:"winnowing"`
+	Code           Code          `yaml:"code"`
+	AST            AST           `yaml:"ast"`
+}
+
+// AST configures the structural comparison of Go sources: functions smaller
+// than MinNodes syntax nodes are ignored, and two functions match when the
+// similarity of their shapes reaches Threshold percent.
+type AST struct {
+	MinNodes  int     `yaml:"min_nodes" env:"ANALYSIS_AST_MIN_NODES" env-default:"15"`
+	Threshold float64 `yaml:"threshold" env:"ANALYSIS_AST_THRESHOLD" env-default:"60"`
+}
+
+// Code configures the token-based comparison of source files: MinMatch is the
+// shortest run of tokens Greedy String Tiling counts as a match.
+type Code struct {
+	MinMatch int `yaml:"min_match" env:"ANALYSIS_CODE_MIN_MATCH" env-default:"9"`
+}
+
+// Winnowing configures fingerprinting: k is the k-gram length in characters,
+// Window the number of consecutive k-grams one fingerprint is chosen from.
+// Common passages of at least K+Window-1 characters are always found.
+type Winnowing struct {
+	K      int `yaml:"k" env:"ANALYSIS_WINNOW_K" env-default:"30"`
+	Window int `yaml:"window" env:"ANALYSIS_WINNOW_WINDOW" env-default:"20"`
+}
+
+// LSH configures the MinHash signatures and the default banding of the
+// candidate index. Bands × rows may not exceed NumHashes; tasks can override
+// both through the analysis API.
+type LSH struct {
+	NumHashes int `yaml:"num_hashes" env:"ANALYSIS_MINHASH_SIZE" env-default:"128"`
+	Bands     int `yaml:"bands" env:"ANALYSIS_LSH_BANDS" env-default:"64"`
+	Rows      int `yaml:"rows" env:"ANALYSIS_LSH_ROWS" env-default:"2"`
+}
+
+// Queue configures the workers that process pending reports. A report that
+// stays in processing longer than JobTimeout is picked up again, at most
+// MaxAttempts times in total.
+type Queue struct {
+	Workers      int           `yaml:"workers" env:"ANALYSIS_WORKERS" env-default:"4"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"ANALYSIS_POLL_INTERVAL" env-default:"2s"`
+	JobTimeout   time.Duration `yaml:"job_timeout" env:"ANALYSIS_JOB_TIMEOUT" env-default:"5m"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"ANALYSIS_MAX_ATTEMPTS" env-default:"3"`
+}
+
 type StorageDB struct {
 	DSN string `yaml:"dsn" env:"STORAGE_DB_DSN"`
 }
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Students and tasks live in storage; the gateway forwards these calls as is.
+
+func (g *Gateway) StudentsProxy(w http.ResponseWriter, r *http.Request) {
+	g.proxy(w, r, g.storageBaseURL+"/students", r.Body)
+}
+
+func (g *Gateway) StudentProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/students/"+id, r.Body)
+}
+
+func (g *Gateway) TasksProxy(w http.ResponseWriter, r *http.Request) {
+	g.proxy(w, r, g.storageBaseURL+"/tasks", r.Body)
+}
+
+func (g *Gateway) TaskProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/tasks/"+id, r.Body)
+}
+
+// TaskLSHProxy forwards the task's candidate index settings, which belong to
+// analysis.
+func (g *Gateway) TaskLSHProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.analysisBaseURL+"/tasks/"+id+"/lsh", r.Body)
+}
+
+// TaskDetectorsProxy forwards which detectors run for the task and how their
+// scores are combined.
+func (g *Gateway) TaskDetectorsProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.analysisBaseURL+"/tasks/"+id+"/detectors", r.Body)
+}
+
+// TaskClustersProxy forwards the groups of similar submissions of the task,
+// query included.
+func (g *Gateway) TaskClustersProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.analysisBaseURL+"/tasks/"+id+"/clusters", r.Body)
+}
+
+// TaskMatrixProxy forwards the similarity matrix of the task. The CSV export
+// keeps its Content-Type and Content-Disposition.
+func (g *Gateway) TaskMatrixProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.analysisBaseURL+"/tasks/"+id+"/matrix", r.Body)
+}
+
+// TaskTemplatesProxy forwards the files handed out with the task. Uploads are
+// multipart and pass through untouched, Content-Type included.
+func (g *Gateway) TaskTemplatesProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/tasks/"+id+"/templates", r.Body)
+}
+
+func (g *Gateway) TaskTemplateProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	templateID := chi.URLParam(r, "template_id")
+	if id == "" || templateID == "" {
+		http.Error(w, "id and template_id are required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/tasks/"+id+"/templates/"+templateID, r.Body)
+}
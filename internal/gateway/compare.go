@@ -0,0 +1,249 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// matchColors is how many highlight colours the comparison page cycles
+// through; a fragment has the same colour in both texts.
+const matchColors = 8
+
+// errNoMatch means neither work's report has stored evidence against the other.
+var errNoMatch = errors.New("no stored match between the works")
+
+// workText is the extracted text of a work, see storage GET /works/{id}/text.
+type workText struct {
+	Status string `json:"status"`
+	Text   string `json:"text"`
+}
+
+// segment is a run of a work's text on the comparison page. Matched runs
+// carry the fragment number, which names their anchor and the anchor of the
+// same fragment in the other text.
+type segment struct {
+	Text    string
+	Matched bool
+	Pair    int
+	Color   int
+}
+
+type comparePane struct {
+	Side     string
+	Other    string
+	Work     Work
+	Status   string
+	Segments []segment
+}
+
+type comparePage struct {
+	Left, Right comparePane
+	Match       ReportMatch
+	ReportID    int64
+	Reversed    bool
+	Matched     int
+}
+
+// highlight cuts text into segments at the given fragments, [start, end) in
+// characters. Fragments are numbered in the order given; where they overlap
+// in this text the later one is cut to what is left.
+func highlight(text string, spans [][2]int) []segment {
+	runes := []rune(text)
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return spans[order[i]][0] < spans[order[j]][0] })
+
+	var segments []segment
+	pos := 0
+	for _, i := range order {
+		start, end := max(spans[i][0], pos), min(spans[i][1], len(runes))
+		if start >= end {
+			continue
+		}
+		if start > pos {
+			segments = append(segments, segment{Text: string(runes[pos:start])})
+		}
+		segments = append(segments, segment{Text: string(runes[start:end]), Matched: true, Pair: i, Color: i % matchColors})
+		pos = end
+	}
+	if pos < len(runes) {
+		segments = append(segments, segment{Text: string(runes[pos:])})
+	}
+	return segments
+}
+
+// findMatch returns the stored evidence that work a and work b are similar,
+// with offsets into a's text first. It looks in a's latest report and then in
+// b's, turning b's evidence around; reversed tells which one was used.
+func (g *Gateway) findMatch(ctx context.Context, a, b int64) (match ReportMatch, reportID int64, reversed bool, err error) {
+	for _, pair := range [][2]int64{{a, b}, {b, a}} {
+		var report Report
+		status, err := g.fetchJSON(ctx, g.analysisBaseURL+"/reports/work/"+strconv.FormatInt(pair[0], 10), &report)
+		if status == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return ReportMatch{}, 0, false, err
+		}
+		var matches struct {
+			Items []ReportMatch `json:"items"`
+		}
+		url := fmt.Sprintf("%s/reports/%d/matches?limit=1&work_id=%d", g.analysisBaseURL, report.ID, pair[1])
+		if _, err := g.fetchJSON(ctx, url, &matches); err != nil {
+			return ReportMatch{}, 0, false, err
+		}
+		if len(matches.Items) == 0 {
+			continue
+		}
+		match = matches.Items[0]
+		if pair[0] == b {
+			for i, f := range match.Fragments {
+				match.Fragments[i] = Fragment{WorkID: a, Start: f.MatchedStart, End: f.MatchedEnd, MatchedStart: f.Start, MatchedEnd: f.End}
+			}
+			for i, f := range match.Functions {
+				match.Functions[i] = FunctionMatch{Function: f.MatchedFunction, MatchedFunction: f.Function, Similarity: f.Similarity,
+					Start: f.MatchedStart, End: f.MatchedEnd, MatchedStart: f.Start, MatchedEnd: f.End}
+			}
+		}
+		return match, report.ID, pair[0] == b, nil
+	}
+	return ReportMatch{}, 0, false, errNoMatch
+}
+
+// CompareView renders a self-contained HTML page with the extracted texts of
+// two works side by side. The matched fragments stored by the analysis
+// service are highlighted in the same colour in both texts and link to each
+// other, so a click on one scrolls the other text to its counterpart.
+func (g *Gateway) CompareView(w http.ResponseWriter, r *http.Request) {
+	a, errA := strconv.ParseInt(chi.URLParam(r, "workA"), 10, 64)
+	b, errB := strconv.ParseInt(chi.URLParam(r, "workB"), 10, 64)
+	if errA != nil || errB != nil {
+		http.Error(w, "invalid work id", http.StatusBadRequest)
+		return
+	}
+	if a == b {
+		http.Error(w, "works must differ", http.StatusBadRequest)
+		return
+	}
+
+	var panes [2]comparePane
+	for i, id := range []int64{a, b} {
+		p := &panes[i]
+		status, err := g.fetchJSON(r.Context(), g.storageBaseURL+"/works/"+strconv.FormatInt(id, 10), &p.Work)
+		if err != nil {
+			if status == http.StatusNotFound {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+			slog.Error("failed to get work from storage", "work_id", id, "err", err)
+			http.Error(w, "storage service unavailable", http.StatusBadGateway)
+			return
+		}
+	}
+
+	match, reportID, reversed, err := g.findMatch(r.Context(), a, b)
+	if errors.Is(err, errNoMatch) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get match evidence", "work_id", a, "matched_work_id", b, "err", err)
+		http.Error(w, "analysis service unavailable", http.StatusBadGateway)
+		return
+	}
+
+	left := make([][2]int, len(match.Fragments))
+	right := make([][2]int, len(match.Fragments))
+	for i, f := range match.Fragments {
+		left[i] = [2]int{f.Start, f.End}
+		right[i] = [2]int{f.MatchedStart, f.MatchedEnd}
+	}
+	for i, spans := range [][][2]int{left, right} {
+		p := &panes[i]
+		var text workText
+		if _, err := g.fetchJSON(r.Context(), g.storageBaseURL+"/works/"+strconv.FormatInt(p.Work.ID, 10)+"/text", &text); err != nil {
+			slog.Error("failed to get work text from storage", "work_id", p.Work.ID, "err", err)
+			http.Error(w, "storage service unavailable", http.StatusBadGateway)
+			return
+		}
+		p.Status = text.Status
+		p.Segments = highlight(text.Text, spans)
+	}
+	panes[0].Side, panes[0].Other = "a", "b"
+	panes[1].Side, panes[1].Other = "b", "a"
+
+	page := comparePage{Left: panes[0], Right: panes[1], Match: match, ReportID: reportID, Reversed: reversed}
+	for _, s := range page.Left.Segments {
+		if s.Matched {
+			page.Matched++
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := compareTemplate.Execute(w, page); err != nil {
+		slog.Error("failed to render comparison", "err", err)
+	}
+}
+
+var compareTemplate = template.Must(template.New("compare").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Сравнение работ #{{.Left.Work.ID}} и #{{.Right.Work.ID}}</title>
+<style>
+body { margin: 0; font: 14px/1.5 system-ui, sans-serif; color: #222; }
+header { padding: 12px 16px; border-bottom: 1px solid #ddd; }
+header h1 { margin: 0 0 4px; font-size: 18px; }
+header p { margin: 2px 0; color: #555; }
+main { display: flex; height: calc(100vh - 120px); }
+section { flex: 1; display: flex; flex-direction: column; min-width: 0; border-right: 1px solid #ddd; }
+section h2 { margin: 0; padding: 8px 16px; font-size: 14px; background: #f5f5f5; border-bottom: 1px solid #ddd; }
+pre { flex: 1; margin: 0; padding: 12px 16px; overflow: auto; white-space: pre-wrap; word-wrap: break-word; font: 13px/1.5 ui-monospace, monospace; }
+a.m { color: inherit; text-decoration: none; border-radius: 2px; }
+a.m:target { outline: 2px solid #333; }
+.c0 { background: #ffe08a; } .c1 { background: #a8e6cf; } .c2 { background: #ffc3c3; } .c3 { background: #b9d7ff; }
+.c4 { background: #e1c8ff; } .c5 { background: #ffd3a8; } .c6 { background: #c6f0f5; } .c7 { background: #e8f5a8; }
+</style>
+</head>
+<body>
+<header>
+<h1>Сходство {{printf "%.1f" .Match.Similarity}}%</h1>
+<p>Совпадающих фрагментов: {{.Matched}}, совпадающих слов (токенов): {{.Match.MatchedTokens}}.
+Источник: отчёт #{{.ReportID}} по работе #{{if .Reversed}}{{.Right.Work.ID}}{{else}}{{.Left.Work.ID}}{{end}}.</p>
+{{with .Match.Scores}}<p>Детекторы:{{range $name, $score := .}} {{$name}} {{printf "%.1f" $score}}%;{{end}}</p>{{end}}
+{{with .Match.Functions}}<p>Функции с одинаковой структурой:{{range .}} {{.Function}} ↔ {{.MatchedFunction}} ({{printf "%.1f" .Similarity}}%);{{end}}</p>{{end}}
+</header>
+<main>
+{{template "pane" .Left}}
+{{template "pane" .Right}}
+</main>
+<script>
+document.querySelectorAll("a.m").forEach(function (a) {
+  a.addEventListener("click", function (e) {
+    var target = document.getElementById(a.getAttribute("href").slice(1));
+    if (!target) return;
+    e.preventDefault();
+    target.scrollIntoView({block: "center"});
+    a.scrollIntoView({block: "center"});
+    history.replaceState(null, "", "#" + target.id);
+  });
+});
+</script>
+</body>
+</html>
+{{define "pane"}}<section>
+<h2>Работа #{{.Work.ID}} — {{.Work.Student}}, версия {{.Work.Version}}, {{.Work.FileName}}{{if ne .Status "done"}} (текст: {{.Status}}){{end}}</h2>
+<pre>{{$side := .Side}}{{$other := .Other}}{{range .Segments}}{{if .Matched}}<a class="m c{{.Color}}" id="{{$side}}{{.Pair}}" href="#{{$other}}{{.Pair}}">{{.Text}}</a>{{else}}{{.Text}}{{end}}{{end}}</pre>
+</section>{{end}}
+`))
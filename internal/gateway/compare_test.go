@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+)
+
+func plain(text string) segment {
+	return segment{Text: text}
+}
+
+func matched(text string, pair int) segment {
+	return segment{Text: text, Matched: true, Pair: pair, Color: pair % matchColors}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		spans [][2]int
+		want  []segment
+	}{
+		{name: "empty text", text: "", spans: [][2]int{{0, 3}}, want: nil},
+		{name: "no spans", text: "abc", spans: nil, want: []segment{plain("abc")}},
+		{
+			name:  "span in the middle",
+			text:  "abcdef",
+			spans: [][2]int{{2, 4}},
+			want:  []segment{plain("ab"), matched("cd", 0), plain("ef")},
+		},
+		{
+			name:  "spans at both ends",
+			text:  "abcdef",
+			spans: [][2]int{{4, 6}, {0, 2}},
+			want:  []segment{matched("ab", 1), plain("cd"), matched("ef", 0)},
+		},
+		{
+			name:  "adjacent spans",
+			text:  "abcdef",
+			spans: [][2]int{{0, 3}, {3, 6}},
+			want:  []segment{matched("abc", 0), matched("def", 1)},
+		},
+		{
+			name:  "overlapping spans, the later is cut",
+			text:  "abcdefgh",
+			spans: [][2]int{{1, 5}, {3, 7}},
+			want:  []segment{plain("a"), matched("bcde", 0), matched("fg", 1), plain("h")},
+		},
+		{
+			name:  "overlap with the same start keeps the order given",
+			text:  "abcdef",
+			spans: [][2]int{{0, 2}, {0, 4}},
+			want:  []segment{matched("ab", 0), matched("cd", 1), plain("ef")},
+		},
+		{
+			name:  "nested span is dropped",
+			text:  "abcdefgh",
+			spans: [][2]int{{0, 6}, {2, 4}},
+			want:  []segment{matched("abcdef", 0), plain("gh")},
+		},
+		{
+			name:  "span past the end is clipped",
+			text:  "abcdef",
+			spans: [][2]int{{4, 100}},
+			want:  []segment{plain("abcd"), matched("ef", 0)},
+		},
+		{
+			name:  "span outside the text is skipped",
+			text:  "abc",
+			spans: [][2]int{{5, 9}},
+			want:  []segment{plain("abc")},
+		},
+		{
+			name:  "negative start is clipped",
+			text:  "abcdef",
+			spans: [][2]int{{-3, 2}},
+			want:  []segment{matched("ab", 0), plain("cdef")},
+		},
+		{
+			name:  "empty and reversed spans are skipped",
+			text:  "abcdef",
+			spans: [][2]int{{2, 2}, {4, 3}, {1, 3}},
+			want:  []segment{plain("a"), matched("bc", 2), plain("def")},
+		},
+		{
+			name:  "offsets count runes",
+			text:  "Привет, 世界 🙂!",
+			spans: [][2]int{{0, 6}, {8, 10}, {11, 12}},
+			want:  []segment{matched("Привет", 0), plain(", "), matched("世界", 1), plain(" "), matched("🙂", 2), plain("!")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.spans); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("highlight =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightColorsCycle(t *testing.T) {
+	spans := make([][2]int, matchColors+2)
+	for i := range spans {
+		spans[i] = [2]int{i, i + 1}
+	}
+	segments := highlight("abcdefghij", spans)
+	if len(segments) != len(spans) {
+		t.Fatalf("got %d segments, want %d", len(segments), len(spans))
+	}
+	for i, s := range segments {
+		if s.Pair != i || s.Color != i%matchColors {
+			t.Errorf("segment %d has pair %d and colour %d", i, s.Pair, s.Color)
+		}
+	}
+}
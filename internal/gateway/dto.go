@@ -1,30 +1,163 @@
 package gateway
 
 type Work struct {
+	ID             int64           `json:"id"`
+	StudentID      int64           `json:"student_id"`
+	Student        string          `json:"student"`
+	TaskID         int64           `json:"task_id"`
+	Task           string          `json:"task"`
+	Version        int             `json:"version"`
+	FilePath       string          `json:"file_path"`
+	FileName       string          `json:"file_name"`
+	FileSize       int64           `json:"file_size"`
+	MimeType       string          `json:"mime_type"`
+	ContentHash    string          `json:"content_hash"`
+	IsLate         bool            `json:"is_late"`
+	LateBy         string          `json:"late_by"`
+	TextStatus     string          `json:"text_status"`
+	TextError      string          `json:"text_error"`
+	IdenticalWorks []IdenticalWork `json:"identical_works"`
+	UploadedAt     string          `json:"uploaded_at"`
+}
+
+type IdenticalWork struct {
 	ID         int64  `json:"id"`
 	Student    string `json:"student"`
 	Task       string `json:"task"`
-	FilePath   string `json:"file_path"`
 	UploadedAt string `json:"uploaded_at"`
 }
 
 type Report struct {
-	ID         int64   `json:"id"`
-	WorkID     int64   `json:"work_id"`
-	Status     string  `json:"status"`
-	Similarity float64 `json:"similarity"`
-	Details    string  `json:"details"`
-	CreatedAt  string  `json:"created_at"`
+	ID               int64           `json:"id"`
+	WorkID           int64           `json:"work_id"`
+	Status           string          `json:"status"`
+	Similarity       float64         `json:"similarity"`
+	MatchedWorkID    *int64          `json:"matched_work_id"`
+	Details          string          `json:"details"`
+	Scores           []DetectorScore `json:"scores,omitempty"`
+	BoilerplateChars int             `json:"boilerplate_chars"`
+	BoilerplateShare float64         `json:"boilerplate_share"`
+	Citations        []Citation      `json:"citations,omitempty"`
+	CitationChars    int             `json:"citation_chars"`
+	CitationShare    float64         `json:"citation_share"`
+	Findings         []Finding       `json:"findings,omitempty"`
+	Error            string          `json:"error"`
+	Attempts         int             `json:"attempts"`
+	CreatedAt        string          `json:"created_at"`
+	StartedAt        string          `json:"started_at,omitempty"`
+	FinishedAt       string          `json:"finished_at,omitempty"`
+	TopMatches       []ReportMatch   `json:"top_matches,omitempty"`
+}
+
+// DetectorScore is one detector's part in a report, see analysis
+// GET /reports/{id}.
+type DetectorScore struct {
+	Detector      string  `json:"detector"`
+	Enabled       bool    `json:"enabled"`
+	Applied       bool    `json:"applied"`
+	Weight        float64 `json:"weight"`
+	Threshold     float64 `json:"threshold"`
+	Similarity    float64 `json:"similarity"`
+	MatchedWorkID *int64  `json:"matched_work_id"`
+	Excluded      float64 `json:"excluded"`
+	Details       string  `json:"details"`
+}
+
+// Citation is a quotation or reference list left out of the comparison, see
+// analysis GET /reports/{id}; offsets are characters of the extracted text.
+type Citation struct {
+	Kind  string `json:"kind"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Finding is a sign of obfuscation in a work, such as look-alike letters from
+// another alphabet or hidden text, see analysis GET /reports/{id}.
+type Finding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+	Details  string `json:"details"`
+	Spans    []Span `json:"spans,omitempty"`
+	Sample   string `json:"sample,omitempty"`
+}
+
+// Span is a passage of a work's extracted text in characters.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ReportMatch is the evidence against one counterpart work, see analysis
+// GET /reports/{id}/matches.
+type ReportMatch struct {
+	WorkID        int64              `json:"work_id"`
+	StudentID     int64              `json:"student_id"`
+	Student       string             `json:"student"`
+	Similarity    float64            `json:"similarity"`
+	Scores        map[string]float64 `json:"scores,omitempty"`
+	MatchedTokens int                `json:"matched_tokens"`
+	Fragments     []Fragment         `json:"fragments"`
+	Functions     []FunctionMatch    `json:"functions,omitempty"`
+}
+
+// Fragment is a matched passage; offsets are characters of the extracted text
+// of the work and of the matched work.
+type Fragment struct {
+	WorkID       int64 `json:"work_id"`
+	Start        int   `json:"start"`
+	End          int   `json:"end"`
+	MatchedStart int   `json:"matched_start"`
+	MatchedEnd   int   `json:"matched_end"`
+}
+
+// FunctionMatch is a pair of Go functions with the same structure; offsets are
+// characters of the extracted texts, as for Fragment.
+type FunctionMatch struct {
+	Function        string  `json:"function"`
+	MatchedFunction string  `json:"matched_function"`
+	Similarity      float64 `json:"similarity"`
+	Start           int     `json:"start"`
+	End             int     `json:"end"`
+	MatchedStart    int     `json:"matched_start"`
+	MatchedEnd      int     `json:"matched_end"`
 }
 
 type CreateWorkRequest struct {
-	Student  string `json:"student"`
-	Task     string `json:"task"`
-	FilePath string `json:"file_path"`
+	StudentID int64  `json:"student_id"`
+	TaskID    int64  `json:"task_id"`
+	FilePath  string `json:"file_path"`
 }
 
 type CombinedWorkResponse struct {
-	Work   Work   `json:"work"`
-	Report Report `json:"report"`
+	Version int    `json:"version"`
+	IsLate  bool   `json:"is_late"`
+	LateBy  string `json:"late_by"`
+	Work    Work   `json:"work"`
+	Report  Report `json:"report"`
 }
 
+func newCombinedWorkResponse(work Work, report Report) CombinedWorkResponse {
+	return CombinedWorkResponse{
+		Version: work.Version,
+		IsLate:  work.IsLate,
+		LateBy:  work.LateBy,
+		Work:    work,
+		Report:  report,
+	}
+}
+
+type WorkVersion struct {
+	Version int     `json:"version"`
+	Work    Work    `json:"work"`
+	Report  *Report `json:"report"`
+}
+
+type WorkVersionsResponse struct {
+	StudentID     int64         `json:"student_id"`
+	Student       string        `json:"student"`
+	TaskID        int64         `json:"task_id"`
+	Task          string        `json:"task"`
+	LatestVersion int           `json:"latest_version"`
+	Items         []WorkVersion `json:"items"`
+}
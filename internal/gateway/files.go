@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (g *Gateway) GetWorkFileProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/works/"+id+"/file", nil)
+}
+
+func (g *Gateway) WorkTextProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/works/"+id+"/text", nil)
+}
+
+// WorkWordCloudProxy forwards the word cloud of the work; the format is the
+// extension of the requested path, .png or .svg.
+func (g *Gateway) WorkWordCloudProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/works/"+id+"/"+path.Base(r.URL.Path), nil)
+}
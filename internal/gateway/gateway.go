@@ -5,19 +5,32 @@ import (
 	"time"
 )
 
+// streamHeaderTimeout bounds how long an upstream may take to start
+// answering a streamed request once the request body has been sent, e.g.
+// while storage moves an upload to the blob store.
+const streamHeaderTimeout = time.Minute
+
 type Gateway struct {
 	storageBaseURL  string
 	analysisBaseURL string
 	httpClient      *http.Client
+	// streamClient carries uploads and downloads. It has no overall timeout,
+	// which would cut off a large file in the middle; the client's request
+	// context ends the transfer instead.
+	streamClient *http.Client
 }
 
 func NewGateway(storageBaseURL, analysisBaseURL string) *Gateway {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = streamHeaderTimeout
 	return &Gateway{
 		storageBaseURL:  storageBaseURL,
 		analysisBaseURL: analysisBaseURL,
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		streamClient: &http.Client{
+			Transport: transport,
+		},
 	}
 }
-
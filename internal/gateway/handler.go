@@ -3,38 +3,50 @@ package gateway
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func (g *Gateway) CreateWorkAndReport(w http.ResponseWriter, r *http.Request) {
-	var req CreateWorkRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("failed to decode createWork request", "err", err)
-		http.Error(w, "invalid json", http.StatusBadRequest)
-		return
-	}
-
 	storageURL := g.storageBaseURL + "/works"
 
-	bodyBytes, err := json.Marshal(req)
-	if err != nil {
-		slog.Error("failed to marshal request to storage", "err", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		return
+	var body io.Reader
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "multipart/form-data" {
+		body = r.Body
+	} else {
+		var req CreateWorkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Error("failed to decode createWork request", "err", err)
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+
+		bodyBytes, err := json.Marshal(req)
+		if err != nil {
+			slog.Error("failed to marshal request to storage", "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		body = bytes.NewReader(bodyBytes)
+		contentType = "application/json"
 	}
 
-	stReq, err := http.NewRequestWithContext(r.Context(), http.MethodPost, storageURL, bytes.NewReader(bodyBytes))
+	stReq, err := http.NewRequestWithContext(r.Context(), http.MethodPost, storageURL, body)
 	if err != nil {
 		slog.Error("failed to create storage request", "err", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	stReq.Header.Set("Content-Type", "application/json")
+	stReq.Header.Set("Content-Type", contentType)
 
-	stResp, err := g.httpClient.Do(stReq)
+	// The body may be a large multipart upload.
+	stResp, err := g.streamClient.Do(stReq)
 	if err != nil {
 		slog.Error("storage request failed", "err", err)
 		http.Error(w, "storage service unavailable", http.StatusBadGateway)
@@ -44,6 +56,12 @@ func (g *Gateway) CreateWorkAndReport(w http.ResponseWriter, r *http.Request) {
 
 	if stResp.StatusCode != http.StatusCreated {
 		slog.Error("storage returned non-201", "status", stResp.StatusCode)
+		switch stResp.StatusCode {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusRequestEntityTooLarge:
+			msg, _ := io.ReadAll(io.LimitReader(stResp.Body, 1<<10))
+			http.Error(w, strings.TrimSpace(string(msg)), stResp.StatusCode)
+			return
+		}
 		http.Error(w, "failed to create work", http.StatusBadGateway)
 		return
 	}
@@ -57,11 +75,9 @@ func (g *Gateway) CreateWorkAndReport(w http.ResponseWriter, r *http.Request) {
 
 	analysisURL := g.analysisBaseURL + "/reports"
 
+	// The analysis service computes the result itself from the stored text.
 	createReportPayload := map[string]interface{}{
-		"work_id":    createdWork.ID,
-		"status":     "done",
-		"similarity": 0,
-		"details":    "Plagiarism check completed",
+		"work_id": createdWork.ID,
 	}
 
 	reportBody, err := json.Marshal(createReportPayload)
@@ -87,8 +103,8 @@ func (g *Gateway) CreateWorkAndReport(w http.ResponseWriter, r *http.Request) {
 	}
 	defer anResp.Body.Close()
 
-	if anResp.StatusCode != http.StatusCreated {
-		slog.Error("analysis returned non-201", "status", anResp.StatusCode)
+	if anResp.StatusCode != http.StatusAccepted {
+		slog.Error("analysis returned non-202", "status", anResp.StatusCode)
 		http.Error(w, "failed to create report", http.StatusBadGateway)
 		return
 	}
@@ -100,13 +116,11 @@ func (g *Gateway) CreateWorkAndReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	combined := CombinedWorkResponse{
-		Work:   createdWork,
-		Report: createdReport,
-	}
+	combined := newCombinedWorkResponse(createdWork, createdReport)
 
+	// The report is still pending; clients poll GET /works/{id} for the result.
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted)
 	if err := json.NewEncoder(w).Encode(combined); err != nil {
 		slog.Error("failed to encode gateway response", "err", err)
 	}
@@ -188,12 +202,15 @@ func (g *Gateway) GetWorkProxy(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "both services unavailable", http.StatusServiceUnavailable)
 		return
 	}
+	if hasReport {
+		reportData.TopMatches = g.topMatches(r.Context(), reportData.ID)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	if hasWork && hasReport {
-		if err := json.NewEncoder(w).Encode(CombinedWorkResponse{Work: *workData, Report: *reportData}); err != nil {
+		if err := json.NewEncoder(w).Encode(newCombinedWorkResponse(*workData, *reportData)); err != nil {
 			slog.Error("failed to encode gateway response", "err", err)
 		}
 		return
@@ -213,3 +230,68 @@ func (g *Gateway) GetWorkProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (g *Gateway) ListWorksProxy(w http.ResponseWriter, r *http.Request) {
+	g.proxy(w, r, g.storageBaseURL+"/works", nil)
+}
+
+func (g *Gateway) UpdateWorkProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.storageBaseURL+"/works/"+id, r.Body)
+}
+
+// DeleteWork withdraws the work in storage and then hides its analysis
+// reports. A failure to hide reports is logged but does not undo the delete.
+func (g *Gateway) DeleteWork(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	stReq, err := http.NewRequestWithContext(r.Context(), http.MethodDelete, g.storageBaseURL+"/works/"+id, nil)
+	if err != nil {
+		slog.Error("failed to create storage request", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if actor := r.Header.Get("X-Actor"); actor != "" {
+		stReq.Header.Set("X-Actor", actor)
+	}
+	stResp, err := g.httpClient.Do(stReq)
+	if err != nil {
+		slog.Error("storage request failed", "err", err)
+		http.Error(w, "storage service unavailable", http.StatusBadGateway)
+		return
+	}
+	defer stResp.Body.Close()
+
+	switch stResp.StatusCode {
+	case http.StatusNoContent:
+	case http.StatusNotFound:
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	default:
+		slog.Error("storage returned non-204", "status", stResp.StatusCode)
+		http.Error(w, "failed to delete work", http.StatusBadGateway)
+		return
+	}
+
+	anReq, err := http.NewRequestWithContext(r.Context(), http.MethodDelete, g.analysisBaseURL+"/reports/work/"+id, nil)
+	if err != nil {
+		slog.Warn("failed to create analysis request", "err", err)
+	} else if anResp, err := g.httpClient.Do(anReq); err != nil {
+		slog.Warn("failed to hide reports of deleted work", "work_id", id, "err", err)
+	} else {
+		_ = anResp.Body.Close()
+		if anResp.StatusCode != http.StatusNoContent {
+			slog.Warn("analysis returned non-204 while hiding reports", "work_id", id, "status", anResp.StatusCode)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+var forwardedRequestHeaders = []string{
+	"Content-Type",
+	"Range",
+	"If-Range",
+	"If-None-Match",
+	"If-Modified-Since",
+	"X-Actor",
+}
+
+var forwardedResponseHeaders = []string{
+	"Accept-Ranges",
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+}
+
+// proxy forwards the request to targetURL (keeping the client's query string)
+// and streams the upstream response back unchanged. Bodies of any size pass
+// through, so it runs for as long as the client's request does.
+func (g *Gateway) proxy(w http.ResponseWriter, r *http.Request, targetURL string, body io.Reader) {
+	if r.URL.RawQuery != "" {
+		targetURL += "?" + r.URL.RawQuery
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, body)
+	if err != nil {
+		slog.Error("failed to create upstream request", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	for _, name := range forwardedRequestHeaders {
+		if v := r.Header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+
+	resp, err := g.streamClient.Do(req)
+	if err != nil {
+		slog.Error("upstream request failed", "url", targetURL, "err", err)
+		http.Error(w, "upstream service unavailable", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, name := range forwardedResponseHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			w.Header().Set(name, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		slog.Warn("failed to stream upstream response", "url", targetURL, "err", err)
+	}
+}
+
+// fetchJSON GETs url and decodes a 200 response into v. The upstream status
+// code is returned so callers can tell "not found" from "unavailable".
+func (g *Gateway) fetchJSON(ctx context.Context, url string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("decode %s: %w", url, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// topMatchesLimit is how many matches GET /works/{id} shows with the report.
+const topMatchesLimit = 3
+
+func (g *Gateway) ReportMatchesProxy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	g.proxy(w, r, g.analysisBaseURL+"/reports/"+id+"/matches", nil)
+}
+
+// topMatches returns the best matches of a report. The report is still
+// useful without them, so failures are only logged.
+func (g *Gateway) topMatches(ctx context.Context, reportID int64) []ReportMatch {
+	var matches struct {
+		Items []ReportMatch `json:"items"`
+	}
+	url := g.analysisBaseURL + "/reports/" + strconv.FormatInt(reportID, 10) + "/matches?limit=" + strconv.Itoa(topMatchesLimit)
+	if _, err := g.fetchJSON(ctx, url, &matches); err != nil {
+		slog.Warn("failed to get report matches", "report_id", reportID, "err", err)
+		return nil
+	}
+	return matches.Items
+}
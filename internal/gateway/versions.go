@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type storageVersions struct {
+	StudentID     int64  `json:"student_id"`
+	Student       string `json:"student"`
+	TaskID        int64  `json:"task_id"`
+	Task          string `json:"task"`
+	LatestVersion int    `json:"latest_version"`
+	Items         []Work `json:"items"`
+}
+
+func (g *Gateway) GetLatestWorkProxy(w http.ResponseWriter, r *http.Request) {
+	g.proxy(w, r, g.storageBaseURL+"/works/latest", nil)
+}
+
+// GetWorkVersions returns every version of the submission the work belongs
+// to, each paired with its own analysis report (nil if there is none yet).
+func (g *Gateway) GetWorkVersions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	var versions storageVersions
+	status, err := g.fetchJSON(r.Context(), g.storageBaseURL+"/works/"+id+"/versions", &versions)
+	if err != nil {
+		if status == http.StatusNotFound {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get versions from storage", "err", err)
+		http.Error(w, "storage service unavailable", http.StatusBadGateway)
+		return
+	}
+
+	response := WorkVersionsResponse{
+		StudentID:     versions.StudentID,
+		Student:       versions.Student,
+		TaskID:        versions.TaskID,
+		Task:          versions.Task,
+		LatestVersion: versions.LatestVersion,
+		Items:         make([]WorkVersion, 0, len(versions.Items)),
+	}
+	for _, work := range versions.Items {
+		item := WorkVersion{Version: work.Version, Work: work}
+		var report Report
+		reportURL := g.analysisBaseURL + "/reports/work/" + strconv.FormatInt(work.ID, 10)
+		if status, err := g.fetchJSON(r.Context(), reportURL, &report); err == nil {
+			item.Report = &report
+		} else if status != http.StatusNotFound {
+			slog.Warn("failed to get report for version", "work_id", work.ID, "err", err)
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode gateway response", "err", err)
+	}
+}
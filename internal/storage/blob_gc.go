@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"path"
+	"time"
+
+	"HW_KPO3/internal/config"
+)
+
+// gcBatch is how many blobs the sweep checks for references at a time.
+const gcBatch = 500
+
+// RunBlobGC removes content blobs that no work or template refers to until
+// ctx is done. Uploads are never cleaned up inline: a blob is stored before
+// its work is inserted, so a concurrent check could see it unreferenced and
+// delete it from under the upload. The sweep only removes blobs older than
+// the grace period instead.
+func (h *Handler) RunBlobGC(ctx context.Context, cfg config.BlobStorage) {
+	if cfg.GCInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.GCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		removed, err := h.sweepBlobs(ctx, time.Now().Add(-cfg.GCGrace))
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to sweep unreferenced blobs", "err", err)
+		}
+		if removed > 0 {
+			slog.Info("removed unreferenced blobs", "count", removed)
+		}
+	}
+}
+
+// sweepBlobs deletes the content blobs stored before cutoff that nothing
+// refers to and returns how many it deleted.
+func (h *Handler) sweepBlobs(ctx context.Context, cutoff time.Time) (int, error) {
+	blobs, err := h.blobs.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	hashes := staleContent(blobs, cutoff)
+	removed := 0
+	for len(hashes) > 0 {
+		batch := hashes[:min(gcBatch, len(hashes))]
+		hashes = hashes[len(batch):]
+		used, err := h.repo.ReferencedContent(ctx, batch)
+		if err != nil {
+			return removed, err
+		}
+		for _, hash := range batch {
+			if used[hash] {
+				continue
+			}
+			if err := h.blobs.Delete(ctx, ContentKey(hash)); err != nil && !errors.Is(err, ErrBlobNotFound) {
+				slog.Warn("failed to delete unreferenced blob", "content_hash", hash, "err", err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// staleContent returns the hashes of the content blobs last written before
+// cutoff. Other blobs, such as rendered word clouds, are left alone.
+func staleContent(blobs []BlobInfo, cutoff time.Time) []string {
+	var hashes []string
+	for _, b := range blobs {
+		hash := path.Base(b.Key)
+		if !b.ModTime.Before(cutoff) || !isContentHash(hash) || ContentKey(hash) != b.Key {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func isContentHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
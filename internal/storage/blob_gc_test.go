@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleContent(t *testing.T) {
+	cutoff := time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)
+	old, fresh := cutoff.Add(-time.Hour), cutoff.Add(time.Hour)
+	a, b := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	tests := []struct {
+		name  string
+		blobs []BlobInfo
+		want  []string
+	}{
+		{name: "empty store", blobs: nil, want: nil},
+		{name: "old content", blobs: []BlobInfo{{Key: ContentKey(a), ModTime: old}}, want: []string{a}},
+		{name: "within the grace period", blobs: []BlobInfo{{Key: ContentKey(a), ModTime: fresh}}, want: nil},
+		{name: "exactly at the cutoff", blobs: []BlobInfo{{Key: ContentKey(a), ModTime: cutoff}}, want: nil},
+		{
+			name: "word clouds are not content",
+			blobs: []BlobInfo{
+				{Key: "wordclouds/" + ContentKey(a) + ".png", ModTime: old},
+				{Key: "wordclouds/" + ContentKey(a), ModTime: old},
+			},
+			want: nil,
+		},
+		{name: "misplaced content key", blobs: []BlobInfo{{Key: "ab/cd/" + b, ModTime: old}}, want: nil},
+		{name: "not a hash", blobs: []BlobInfo{{Key: "zz/zz/" + strings.Repeat("z", 64), ModTime: old}}, want: nil},
+		{
+			name:  "mixed",
+			blobs: []BlobInfo{{Key: ContentKey(a), ModTime: fresh}, {Key: ContentKey(b), ModTime: old}},
+			want:  []string{b},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := staleContent(tt.blobs, cutoff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("staleContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
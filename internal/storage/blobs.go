@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"HW_KPO3/internal/config"
+)
+
+const (
+	BlobBackendLocal = "local"
+	BlobBackendS3    = "s3"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore persists file contents under slash-separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+func OpenBlobStore(ctx context.Context, cfg config.BlobStorage, storagePath string) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BlobBackendLocal:
+		return NewLocalBlobStore(storagePath), nil
+	case BlobBackendS3:
+		store, err := NewS3BlobStore(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
+}
+
+// ContentKey returns the key under which content with the given SHA-256 is
+// stored, so byte-identical submissions share a single blob.
+func ContentKey(hash string) string {
+	return hash[:2] + "/" + hash[2:4] + "/" + hash
+}
+
+// PutContent spools r to a temporary file while hashing it and stores it
+// under its content key unless a blob with the same hash already exists.
+func PutContent(ctx context.Context, store BlobStore, r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp("", "blob-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("create tmp file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if err != nil {
+		return "", 0, fmt.Errorf("write blob: %w", err)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	key := ContentKey(hash)
+	if _, err := store.Stat(ctx, key); err == nil {
+		return hash, size, nil
+	} else if !errors.Is(err, ErrBlobNotFound) {
+		return "", 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("rewind blob: %w", err)
+	}
+	if err := store.Put(ctx, key, tmp, size); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSubmissionClosed is returned for submissions that arrive after the hard
+// cutoff when late submissions are rejected.
+var ErrSubmissionClosed = errors.New("submission is closed")
+
+const deadlineLayout = "2006-01-02 15:04:05"
+
+var deadlineLayouts = []string{
+	deadlineLayout,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+}
+
+// Location returns the task's time zone, falling back to UTC for tasks
+// created before time zones were recorded.
+func (t *Task) Location() *time.Location {
+	if t.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DeadlineAt returns the deadline as an absolute instant. The stored deadline
+// is a wall-clock time in the task's time zone.
+func (t *Task) DeadlineAt() (time.Time, bool) {
+	if t.Deadline == nil {
+		return time.Time{}, false
+	}
+	d := *t.Deadline
+	return time.Date(d.Year(), d.Month(), d.Day(), d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), t.Location()), true
+}
+
+// Lateness reports whether a submission made at the given time is late and by
+// how much it missed the deadline. Submissions within the grace period are
+// not late.
+func (t *Task) Lateness(at time.Time) (bool, time.Duration) {
+	deadline, ok := t.DeadlineAt()
+	if !ok || !at.After(deadline.Add(t.GracePeriod)) {
+		return false, 0
+	}
+	return true, at.Sub(deadline).Truncate(time.Second)
+}
+
+// CheckCutoff rejects a submission made after the deadline, the grace period
+// and the configured hard cutoff have all passed.
+func (t *Task) CheckCutoff(at time.Time, hardCutoff time.Duration) error {
+	deadline, ok := t.DeadlineAt()
+	if !ok {
+		return nil
+	}
+	if at.After(deadline.Add(t.GracePeriod + hardCutoff)) {
+		return fmt.Errorf("%w: deadline for %q has passed", ErrSubmissionClosed, t.Title)
+	}
+	return nil
+}
+
+// parseDeadline reads a wall-clock deadline in loc. RFC 3339 timestamps with
+// an explicit offset are converted to loc first.
+func parseDeadline(v string, loc *time.Location) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+	}
+	for _, layout := range deadlineLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		// A bare date means the end of that day.
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("deadline must be RFC 3339 or %q", deadlineLayout)
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func wallClock(v string) *time.Time {
+	t, err := time.Parse(deadlineLayout, v)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func utc(v string) time.Time {
+	return *wallClock(v)
+}
+
+func TestParseDeadline(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		in      string
+		loc     *time.Location
+		want    string
+		wantErr bool
+	}{
+		{"wall clock", "2024-05-01 18:00:00", berlin, "2024-05-01 18:00:00", false},
+		{"T separator without seconds", "2024-05-01T18:00", berlin, "2024-05-01 18:00:00", false},
+		{"surrounding spaces", "  2024-05-01 18:00  ", time.UTC, "2024-05-01 18:00:00", false},
+		{"bare date is end of day", "2024-05-01", berlin, "2024-05-01 23:59:59", false},
+		{"bare date on DST switch", "2024-03-31", berlin, "2024-03-31 23:59:59", false},
+		{"offset converted to winter time", "2024-01-15T12:00:00Z", berlin, "2024-01-15 13:00:00", false},
+		{"offset converted to summer time", "2024-07-15T12:00:00Z", berlin, "2024-07-15 14:00:00", false},
+		{"offset just after spring forward", "2024-03-31T01:30:00Z", berlin, "2024-03-31 03:30:00", false},
+		{"offset on second pass of fall back", "2024-10-27T01:30:00Z", berlin, "2024-10-27 02:30:00", false},
+		{"empty", "", berlin, "", true},
+		{"garbage", "next friday", berlin, "", true},
+		{"bad month", "2024-13-01 10:00", berlin, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDeadline(tt.in, tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDeadline(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDeadline(%q): %v", tt.in, err)
+			}
+			if s := got.Format(deadlineLayout); s != tt.want {
+				t.Errorf("parseDeadline(%q) = %s, want %s", tt.in, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestLateness(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     Task
+		at       time.Time
+		wantLate bool
+		wantBy   time.Duration
+	}{
+		{"no deadline", Task{}, utc("2030-01-01 00:00:00"), false, 0},
+		{"on the deadline", Task{Deadline: wallClock("2024-05-01 18:00:00")}, utc("2024-05-01 18:00:00"), false, 0},
+		{"a second late", Task{Deadline: wallClock("2024-05-01 18:00:00")}, utc("2024-05-01 18:00:01"), true, time.Second},
+		{"sub-second lateness truncated", Task{Deadline: wallClock("2024-05-01 18:00:00")}, utc("2024-05-01 18:00:00").Add(1500 * time.Millisecond), true, time.Second},
+		{"within grace", Task{Deadline: wallClock("2024-05-01 18:00:00"), GracePeriod: time.Hour}, utc("2024-05-01 19:00:00"), false, 0},
+		{"after grace counts from deadline", Task{Deadline: wallClock("2024-05-01 18:00:00"), GracePeriod: time.Hour}, utc("2024-05-01 19:00:01"), true, time.Hour + time.Second},
+		{"unknown zone falls back to UTC", Task{Deadline: wallClock("2024-05-01 18:00:00"), Timezone: "Mars/Olympus"}, utc("2024-05-01 18:00:01"), true, time.Second},
+		// Europe/Berlin is UTC+1 before 2024-03-31 02:00 and UTC+2 after it.
+		{"deadline in summer time", Task{Deadline: wallClock("2024-03-31 23:59:59"), Timezone: "Europe/Berlin"}, utc("2024-03-31 22:00:00"), true, time.Second},
+		{"deadline in winter time", Task{Deadline: wallClock("2024-03-30 23:59:59"), Timezone: "Europe/Berlin"}, utc("2024-03-30 22:30:00"), false, 0},
+		{"grace across spring forward is absolute", Task{Deadline: wallClock("2024-03-30 12:00:00"), Timezone: "Europe/Berlin", GracePeriod: 24 * time.Hour}, utc("2024-03-31 11:00:00"), false, 0},
+		{"after grace across spring forward", Task{Deadline: wallClock("2024-03-30 12:00:00"), Timezone: "Europe/Berlin", GracePeriod: 24 * time.Hour}, utc("2024-03-31 11:00:01"), true, 24*time.Hour + time.Second},
+		{"deadline after fall back", Task{Deadline: wallClock("2024-10-27 12:00:00"), Timezone: "Europe/Berlin"}, utc("2024-10-27 11:00:01"), true, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			late, by := tt.task.Lateness(tt.at)
+			if late != tt.wantLate || by != tt.wantBy {
+				t.Errorf("Lateness(%v) = %v, %v; want %v, %v", tt.at, late, by, tt.wantLate, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestCheckCutoff(t *testing.T) {
+	deadline := wallClock("2024-03-30 23:00:00")
+	tests := []struct {
+		name       string
+		task       Task
+		at         time.Time
+		hardCutoff time.Duration
+		wantErr    bool
+	}{
+		{"no deadline", Task{}, utc("2030-01-01 00:00:00"), 0, false},
+		{"before deadline", Task{Deadline: deadline}, utc("2024-03-30 22:59:59"), 0, false},
+		{"on deadline", Task{Deadline: deadline}, utc("2024-03-30 23:00:00"), 0, false},
+		{"after deadline without cutoff", Task{Deadline: deadline}, utc("2024-03-30 23:00:01"), 0, true},
+		{"within grace and cutoff", Task{Deadline: deadline, GracePeriod: time.Hour}, utc("2024-03-31 00:30:00"), time.Hour, false},
+		{"after grace and cutoff", Task{Deadline: deadline, GracePeriod: time.Hour}, utc("2024-03-31 01:00:01"), time.Hour, true},
+		// 23:00 CET is 22:00Z; two hours of cutoff end at 00:00Z, which is
+		// 02:00 wall clock in Berlin on the night the clocks jump to 03:00.
+		{"cutoff across spring forward open", Task{Deadline: deadline, Timezone: "Europe/Berlin"}, utc("2024-03-31 00:00:00"), 2 * time.Hour, false},
+		{"cutoff across spring forward closed", Task{Deadline: deadline, Timezone: "Europe/Berlin"}, utc("2024-03-31 00:00:01"), 2 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.task.CheckCutoff(tt.at, tt.hardCutoff)
+			if tt.wantErr {
+				if !errors.Is(err, ErrSubmissionClosed) {
+					t.Errorf("CheckCutoff(%v) = %v, want ErrSubmissionClosed", tt.at, err)
+				}
+			} else if err != nil {
+				t.Errorf("CheckCutoff(%v) = %v, want nil", tt.at, err)
+			}
+		})
+	}
+}
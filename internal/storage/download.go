@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) GetWorkFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	work, err := h.repo.GetWork(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to get work", "err", err)
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if work.ContentHash == "" {
+		http.Error(w, "work has no stored file", http.StatusNotFound)
+		return
+	}
+
+	f, err := h.blobs.Get(r.Context(), ContentKey(work.ContentHash))
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) {
+			slog.Error("blob is missing", "work_id", work.ID, "hash", work.ContentHash)
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to open blob", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	disposition := "attachment"
+	if r.URL.Query().Get("disposition") == "inline" {
+		disposition = "inline"
+	}
+	if work.FileName != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": work.FileName})
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("ETag", `"`+work.ContentHash+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if work.MimeType != "" {
+		w.Header().Set("Content-Type", work.MimeType)
+	}
+	http.ServeContent(w, r, work.FileName, work.UploadedAt, f)
+}
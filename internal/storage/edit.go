@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const (
+	actorHeader  = "X-Actor"
+	defaultActor = "anonymous"
+)
+
+type updateWorkRequest struct {
+	StudentID *int64 `json:"student_id"`
+	TaskID    *int64 `json:"task_id"`
+}
+
+func requestActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get(actorHeader)); actor != "" {
+		return actor
+	}
+	return defaultActor
+}
+
+func (h *Handler) UpdateWork(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req updateWorkRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		slog.Error("failed to decode request", "err", err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.StudentID == nil && req.TaskID == nil {
+		http.Error(w, "student_id or task_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.StudentID != nil {
+		if _, err := h.repo.GetStudent(r.Context(), *req.StudentID); err != nil {
+			if errors.Is(err, ErrStudentNotFound) {
+				http.Error(w, "unknown student_id", http.StatusBadRequest)
+				return
+			}
+			slog.Error("failed to get student", "err", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.TaskID != nil {
+		if _, err := h.repo.GetTask(r.Context(), *req.TaskID); err != nil {
+			if errors.Is(err, ErrTaskNotFound) {
+				http.Error(w, "unknown task_id", http.StatusBadRequest)
+				return
+			}
+			slog.Error("failed to get task", "err", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	patch := WorkPatch{StudentID: req.StudentID, TaskID: req.TaskID}
+
+	work, err := h.repo.UpdateWork(r.Context(), id, patch, requestActor(r))
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to update work", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newWorkResponse(work))
+}
+
+func (h *Handler) DeleteWork(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.DeleteWork(r.Context(), id, requestActor(r)); err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to delete work", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetWorkAudit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	entries, err := h.repo.ListWorkAudit(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list work audit", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, entries)
+}
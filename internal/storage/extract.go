@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Text extraction statuses stored in works.text_status.
+const (
+	TextStatusPending = "pending"
+	TextStatusDone    = "done"
+	TextStatusFailed  = "failed"
+	TextStatusSkipped = "skipped"
+)
+
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+	FormatODT  = "odt"
+	FormatRTF  = "rtf"
+	FormatText = "txt"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrNoText            = errors.New("no text found")
+)
+
+// Extracted is the text of a document. Hidden is the text the document keeps
+// from the reader: hidden, white or microscopic runs and PDF text drawn
+// invisibly. Nobody reading the work sees it, so it is not part of Text; it
+// is kept for the analysis service to flag.
+type Extracted struct {
+	Text   string
+	Hidden string
+}
+
+// extractors turn the raw file of a given format into text. The result is
+// normalised by ExtractText afterwards. Formats that cannot hide text return
+// none.
+var extractors = map[string]func([]byte) (Extracted, error){
+	FormatPDF:  extractPDF,
+	FormatDOCX: extractDOCX,
+	FormatODT:  visibleOnly(extractODT),
+	FormatRTF:  extractRTF,
+	FormatText: visibleOnly(decodePlainText),
+}
+
+func visibleOnly(extract func([]byte) (string, error)) func([]byte) (Extracted, error) {
+	return func(data []byte) (Extracted, error) {
+		text, err := extract(data)
+		return Extracted{Text: text}, err
+	}
+}
+
+// DetectFormat picks the document format from the file signature, falling
+// back to the MIME type and the file extension.
+func DetectFormat(data []byte, mimeType, name string) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(data, []byte(`{\rtf`)):
+		return FormatRTF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return detectZipFormat(data)
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case mimeType == "application/pdf" || ext == ".pdf":
+		return FormatPDF
+	case mimeType == "application/rtf" || mimeType == "text/rtf" || ext == ".rtf":
+		return FormatRTF
+	case strings.HasPrefix(mimeType, "text/") || isPlainTextExt(ext):
+		return FormatText
+	}
+	// Files uploaded without a useful type still count as text when they
+	// decode cleanly and contain no binary control bytes.
+	if looksLikeText(data) {
+		return FormatText
+	}
+	return ""
+}
+
+func isPlainTextExt(ext string) bool {
+	switch ext {
+	case ".txt", ".text", ".md", ".csv",
+		".go", ".py", ".java", ".c", ".h", ".cpp", ".cc", ".cxx", ".hpp", ".hh":
+		return true
+	}
+	return false
+}
+
+func looksLikeText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	sample := data
+	if len(sample) > 8<<10 {
+		sample = sample[:8<<10]
+	}
+	for _, b := range sample {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtractText converts a stored file to normalised UTF-8 text.
+func ExtractText(data []byte, mimeType, name string) (*Extracted, error) {
+	format := DetectFormat(data, mimeType, name)
+	extract, ok := extractors[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, mimeType)
+	}
+	raw, err := extract(data)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", format, err)
+	}
+	normalize := NormalizeText
+	if format == FormatText {
+		// Source code and plain text keep their layout: indentation is
+		// structure in Python and blank lines are what a diff shows.
+		normalize = CleanText
+	}
+	text := &Extracted{Text: normalize(raw.Text), Hidden: normalize(raw.Hidden)}
+	if strings.TrimSpace(text.Text) == "" {
+		return nil, fmt.Errorf("extract %s: %w", format, ErrNoText)
+	}
+	return text, nil
+}
+
+var (
+	blankRun = regexp.MustCompile(`[ \t\f\v\x{00A0}\x{2000}-\x{200A}\x{202F}\x{205F}\x{3000}]+`)
+	emptyRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// CleanText drops invalid UTF-8 and byte order marks, unifies line breaks
+// and converts text to NFC. Everything else, indentation and blank lines
+// included, is kept as written.
+func CleanText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u2028", "\n", "\u2029", "\n", "\uFEFF", "").Replace(s)
+	return norm.NFC.String(s)
+}
+
+// NormalizeText cleans a document's text like CleanText, drops control
+// characters and soft hyphens and collapses runs of blanks so that the same
+// document always yields the same text. Other invisible characters are kept
+// for the analysis service to inspect.
+func NormalizeText(s string) string {
+	s = CleanText(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\u00AD':
+			return -1
+		case unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, s)
+	s = blankRun.ReplaceAllString(s, " ")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	s = strings.Join(lines, "\n")
+	s = emptyRun.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// decodePlainText honours UTF-8 and UTF-16 byte order marks and otherwise
+// assumes UTF-8, falling back to Windows-1251 which is what most Russian
+// text files that are not UTF-8 use.
+func decodePlainText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true), nil
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+	decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	docxMainPart = "word/document.xml"
+	odtMainPart  = "content.xml"
+	odtMimeType  = "application/vnd.oasis.opendocument.text"
+
+	// maxOfficePartSize caps how much XML is inflated from a single part so a
+	// zip bomb cannot exhaust memory.
+	maxOfficePartSize = 256 << 20
+)
+
+func detectZipFormat(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case docxMainPart:
+			return FormatDOCX
+		case "mimetype":
+			if content, err := readZipFile(f); err == nil && strings.TrimSpace(string(content)) == odtMimeType {
+				return FormatODT
+			}
+		}
+	}
+	return ""
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, maxOfficePartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxOfficePartSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	return content, nil
+}
+
+func readZipPart(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.Name == name {
+			return readZipFile(f)
+		}
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+// extractDOCX reads the paragraphs of the main document part. Text runs are
+// w:t elements; tabs, breaks and paragraph ends become whitespace. Runs whose
+// properties hide them (w:vanish, white colour, a size of 1pt or less) go to
+// the hidden text.
+func extractDOCX(data []byte) (Extracted, error) {
+	part, err := readZipPart(data, docxMainPart)
+	if err != nil {
+		return Extracted{}, err
+	}
+	const ns = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+	var visible, hidden strings.Builder
+	out := &visible
+	inText, inRunProps := false, false
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Extracted{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != ns {
+				continue
+			}
+			switch t.Name.Local {
+			case "r":
+				out = &visible
+			case "rPr":
+				inRunProps = true
+			case "vanish", "webHidden", "color", "sz":
+				if inRunProps && docxHides(t) {
+					out = &hidden
+				}
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space != ns {
+				continue
+			}
+			switch t.Name.Local {
+			case "rPr":
+				inRunProps = false
+			case "t":
+				inText = false
+			case "r":
+				if out == &hidden {
+					hidden.WriteByte(' ')
+				}
+				out = &visible
+			case "p":
+				visible.WriteByte('\n')
+				if hidden.Len() > 0 {
+					hidden.WriteByte('\n')
+				}
+			case "tc":
+				visible.WriteByte('\t')
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	return Extracted{Text: visible.String(), Hidden: hidden.String()}, nil
+}
+
+// docxHides reports whether a run property keeps the run from the reader.
+// Paragraph mark properties (w:pPr/w:rPr) are reset by the next run, so only
+// run properties count.
+func docxHides(prop xml.StartElement) bool {
+	val, hasVal := "", false
+	for _, attr := range prop.Attr {
+		if attr.Name.Local == "val" {
+			val, hasVal = attr.Value, true
+		}
+	}
+	switch prop.Name.Local {
+	case "vanish", "webHidden":
+		return !hasVal || (val != "0" && val != "false" && val != "off")
+	case "color":
+		return strings.EqualFold(val, "FFFFFF")
+	case "sz":
+		// Half-points.
+		size, err := strconv.Atoi(val)
+		return err == nil && size <= 2
+	}
+	return false
+}
+
+// extractODT reads the body of an OpenDocument text. Paragraphs and headings
+// end with a line break; text:s, text:tab and text:line-break stand for
+// spaces, tabs and breaks.
+func extractODT(data []byte) (string, error) {
+	part, err := readZipPart(data, odtMainPart)
+	if err != nil {
+		return "", err
+	}
+	const (
+		nsOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+		nsText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	)
+
+	var sb strings.Builder
+	inBody := false
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == nsOffice && t.Name.Local == "body" {
+				inBody = true
+			}
+			if !inBody || t.Name.Space != nsText {
+				continue
+			}
+			switch t.Name.Local {
+			case "s":
+				count := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 {
+							count = n
+						}
+					}
+				}
+				sb.WriteString(strings.Repeat(" ", count))
+			case "tab":
+				sb.WriteByte('\t')
+			case "line-break":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space == nsOffice && t.Name.Local == "body" {
+				inBody = false
+			}
+			if inBody && t.Name.Space == nsText && (t.Name.Local == "p" || t.Name.Local == "h") {
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inBody {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// buildZip packs the given parts into an in-memory archive.
+func buildZip(parts map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(content))
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func buildDOCX(body string) []byte {
+	return buildZip(map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0"?><Types/>`,
+		docxMainPart: `<?xml version="1.0" encoding="UTF-8"?>` +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			body + `</w:body></w:document>`,
+	})
+}
+
+func TestExtractDOCX(t *testing.T) {
+	tests := []struct {
+		name    string
+		docx    []byte
+		text    string
+		hidden  string
+		wantErr bool
+	}{
+		{
+			name: "paragraphs and runs",
+			docx: buildDOCX(`<w:p><w:r><w:t>Первый </w:t></w:r><w:r><w:t>абзац</w:t></w:r></w:p><w:p><w:r><w:t>Второй</w:t></w:r></w:p>`),
+			text: "Первый абзац\nВторой",
+		},
+		{
+			name: "tabs and breaks",
+			docx: buildDOCX(`<w:p><w:r><w:t>a</w:t><w:tab/><w:t>b</w:t><w:br/><w:t>c</w:t></w:r></w:p>`),
+			text: "a b\nc",
+		},
+		{
+			name: "table cells",
+			docx: buildDOCX(`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>x</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>y</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`),
+			text: "x\ny",
+		},
+		{
+			name:   "vanished run",
+			docx:   buildDOCX(`<w:p><w:r><w:t>Shown</w:t></w:r><w:r><w:rPr><w:vanish/></w:rPr><w:t>secret</w:t></w:r><w:r><w:t> text</w:t></w:r></w:p>`),
+			text:   "Shown text",
+			hidden: "secret",
+		},
+		{
+			name: "vanish switched off",
+			docx: buildDOCX(`<w:p><w:r><w:rPr><w:vanish w:val="false"/></w:rPr><w:t>visible</w:t></w:r></w:p>`),
+			text: "visible",
+		},
+		{
+			name:   "white and microscopic runs",
+			docx:   buildDOCX(`<w:p><w:r><w:t>Body</w:t></w:r><w:r><w:rPr><w:color w:val="ffffff"/></w:rPr><w:t>white</w:t></w:r><w:r><w:rPr><w:sz w:val="2"/></w:rPr><w:t>tiny</w:t></w:r><w:r><w:rPr><w:sz w:val="24"/></w:rPr><w:t> end</w:t></w:r></w:p>`),
+			text:   "Body end",
+			hidden: "white tiny",
+		},
+		{
+			name: "paragraph mark properties do not hide the next run",
+			docx: buildDOCX(`<w:p><w:pPr><w:rPr><w:vanish/></w:rPr></w:pPr><w:r><w:t>kept</w:t></w:r></w:p>`),
+			text: "kept",
+		},
+		{
+			name:    "empty body",
+			docx:    buildDOCX(`<w:p/>`),
+			wantErr: true,
+		},
+		{
+			name:    "malformed XML",
+			docx:    buildDOCX(`<w:p><w:r><w:t>open`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText(tt.docx, "application/octet-stream", "work.docx")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractText = %q, want error", got.Text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got.Text != tt.text {
+				t.Errorf("Text = %q, want %q", got.Text, tt.text)
+			}
+			if got.Hidden != tt.hidden {
+				t.Errorf("Hidden = %q, want %q", got.Hidden, tt.hidden)
+			}
+		})
+	}
+}
+
+func TestExtractDOCXWithoutDocumentPart(t *testing.T) {
+	data := buildZip(map[string]string{"word/styles.xml": "<w:styles/>"})
+	if _, err := extractDOCX(data); err == nil {
+		t.Fatal("extractDOCX succeeded without word/document.xml")
+	}
+}
@@ -0,0 +1,803 @@
+package storage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// This is a deliberately small PDF reader: it understands indirect objects,
+// object streams, FlateDecode and ToUnicode CMaps, which covers documents
+// produced by office suites and LaTeX. Encrypted PDFs and scanned images are
+// reported as failures rather than guessed at.
+
+type pdfObject struct {
+	dict   string
+	stream []byte
+}
+
+type pdfDocument struct {
+	objects map[int]*pdfObject
+}
+
+type pdfFont struct {
+	cmap     map[string]string
+	codeSize int
+}
+
+var (
+	pdfObjectStart = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfRef         = regexp.MustCompile(`^\s*(\d+)\s+\d+\s+R`)
+	pdfStreamStart = regexp.MustCompile(`stream\r?\n`)
+	pdfRefs        = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfNamedRef    = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	pdfEncrypt     = regexp.MustCompile(`/Encrypt\s+\d+\s+\d+\s+R`)
+)
+
+func extractPDF(data []byte) (Extracted, error) {
+	if pdfEncrypt.Match(data) {
+		return Extracted{}, errors.New("encrypted PDF is not supported")
+	}
+	doc := parsePDF(data)
+	if len(doc.objects) == 0 {
+		return Extracted{}, errors.New("no PDF objects found")
+	}
+
+	var visible, hidden strings.Builder
+	for _, page := range doc.pages() {
+		fonts := doc.pageFonts(page)
+		for _, content := range doc.pageContents(page) {
+			text, hiddenText := doc.contentText(content, fonts)
+			visible.WriteString(text)
+			visible.WriteByte('\n')
+			if hiddenText != "" {
+				hidden.WriteString(hiddenText)
+				hidden.WriteByte('\n')
+			}
+		}
+		visible.WriteString("\n")
+	}
+	return Extracted{Text: visible.String(), Hidden: hidden.String()}, nil
+}
+
+func parsePDF(data []byte) *pdfDocument {
+	doc := &pdfDocument{objects: map[int]*pdfObject{}}
+	matches := pdfObjectStart.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		body := data[m[1]:end]
+		head := body
+		if e := bytes.Index(head, []byte("endobj")); e >= 0 {
+			head = head[:e]
+		}
+		obj := &pdfObject{dict: string(head)}
+		if loc := pdfStreamStart.FindIndex(head); loc != nil && bytes.Contains(head[:loc[0]], []byte("<<")) {
+			obj.dict = string(head[:loc[0]])
+			raw := body[loc[1]:]
+			// Prefer a direct /Length: binary data may contain "endstream".
+			if n := pdfInt(obj.dict, "/Length"); n > 0 && n <= len(raw) && !pdfRef.MatchString(obj.dict[indexPDFKey(obj.dict, "/Length")+len("/Length"):]) {
+				raw = raw[:n]
+			} else if e := bytes.LastIndex(raw, []byte("endstream")); e >= 0 {
+				raw = bytes.TrimRight(raw[:e], "\r\n")
+			}
+			obj.stream = decodePDFStream(obj.dict, raw)
+		}
+		doc.objects[num] = obj
+	}
+
+	// Objects packed into object streams have no "obj" header of their own.
+	for _, obj := range doc.objects {
+		if !strings.Contains(obj.dict, "/ObjStm") || obj.stream == nil {
+			continue
+		}
+		n, first := pdfInt(obj.dict, "/N"), pdfInt(obj.dict, "/First")
+		if first <= 0 || first > len(obj.stream) {
+			continue
+		}
+		header := strings.Fields(string(obj.stream[:first]))
+		for k := 0; k+1 < len(header) && k/2 < n; k += 2 {
+			num, err1 := strconv.Atoi(header[k])
+			off, err2 := strconv.Atoi(header[k+1])
+			if err1 != nil || err2 != nil || off < 0 {
+				continue
+			}
+			end := len(obj.stream)
+			if k+3 < len(header) {
+				if next, err := strconv.Atoi(header[k+3]); err == nil && first+next <= end {
+					end = first + next
+				}
+			}
+			// A damaged header may point past the stream or past the next
+			// object; such entries are skipped rather than guessed at.
+			if first+off > end {
+				continue
+			}
+			if _, exists := doc.objects[num]; !exists {
+				doc.objects[num] = &pdfObject{dict: string(obj.stream[first+off : end])}
+			}
+		}
+	}
+	return doc
+}
+
+func decodePDFStream(dict string, raw []byte) []byte {
+	if !strings.Contains(dict, "/Filter") {
+		return raw
+	}
+	if !strings.Contains(dict, "/FlateDecode") {
+		// Image and other filters carry no text.
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	// Truncated or slightly damaged streams still yield useful text, so
+	// keep whatever was inflated before an error.
+	out, _ := io.ReadAll(io.LimitReader(zr, maxOfficePartSize))
+	return out
+}
+
+func pdfInt(dict, key string) int {
+	i := indexPDFKey(dict, key)
+	if i < 0 {
+		return 0
+	}
+	fields := strings.Fields(dict[i+len(key):])
+	if len(fields) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimRight(fields[0], "/>]"))
+	return n
+}
+
+// value returns the raw text following key in dict, resolving it if it is an
+// indirect reference.
+func (d *pdfDocument) value(dict, key string) string {
+	idx := indexPDFKey(dict, key)
+	if idx < 0 {
+		return ""
+	}
+	rest := dict[idx+len(key):]
+	if m := pdfRef.FindStringSubmatch(rest); m != nil {
+		num, _ := strconv.Atoi(m[1])
+		if obj, ok := d.objects[num]; ok {
+			return obj.dict
+		}
+		return ""
+	}
+	return rest
+}
+
+// indexPDFKey finds key as a whole name, so /Font does not match /FontFile.
+func indexPDFKey(dict, key string) int {
+	from := 0
+	for {
+		i := strings.Index(dict[from:], key)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(key)
+		if end >= len(dict) || !isPDFNameChar(dict[end]) {
+			return i
+		}
+		from = end
+	}
+}
+
+func isPDFNameChar(c byte) bool {
+	return isASCIILetter(c) || isASCIIDigit(c) || c == '_' || c == '.' || c == '-' || c == '+'
+}
+
+func refNumbers(s string) []int {
+	var nums []int
+	for _, m := range pdfRefs.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[1])
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// pages returns page object numbers in document order by walking the page
+// tree from the catalog. Page dictionaries found outside the tree are
+// appended so a damaged tree does not lose text.
+func (d *pdfDocument) pages() []int {
+	var ordered []int
+	seen := map[int]bool{}
+	var walk func(num int)
+	walk = func(num int) {
+		obj, ok := d.objects[num]
+		if !ok || seen[num] {
+			return
+		}
+		seen[num] = true
+		if isPDFType(obj.dict, "/Page") {
+			ordered = append(ordered, num)
+			return
+		}
+		kids := d.value(obj.dict, "/Kids")
+		if end := strings.Index(kids, "]"); end >= 0 {
+			kids = kids[:end]
+		}
+		for _, kid := range refNumbers(kids) {
+			walk(kid)
+		}
+	}
+	for _, obj := range d.objects {
+		if isPDFType(obj.dict, "/Catalog") {
+			if refs := refNumbers(firstToken(d.rawValue(obj.dict, "/Pages"))); len(refs) > 0 {
+				walk(refs[0])
+			}
+		}
+	}
+	for num, obj := range d.objects {
+		if !seen[num] && isPDFType(obj.dict, "/Page") {
+			ordered = append(ordered, num)
+		}
+	}
+	return ordered
+}
+
+func isPDFType(dict, typ string) bool {
+	i := indexPDFKey(dict, "/Type")
+	if i < 0 {
+		return false
+	}
+	rest := strings.TrimLeft(dict[i+len("/Type"):], " \r\n\t")
+	return strings.HasPrefix(rest, typ) && (len(rest) == len(typ) || !isPDFNameChar(rest[len(typ)]))
+}
+
+func firstToken(s string) string {
+	if i := strings.IndexAny(s, "/>]"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func (d *pdfDocument) pageContents(page int) [][]byte {
+	obj := d.objects[page]
+	i := indexPDFKey(obj.dict, "/Contents")
+	if i < 0 {
+		return nil
+	}
+	rest := strings.TrimLeft(obj.dict[i+len("/Contents"):], " \r\n\t")
+	var refs []int
+	if strings.HasPrefix(rest, "[") {
+		refs = refNumbers(rest[:strings.Index(rest, "]")+1])
+	} else {
+		refs = refNumbers(firstToken(rest))
+		// A single reference may point at an array of content streams.
+		if len(refs) == 1 {
+			if arr, ok := d.objects[refs[0]]; ok && arr.stream == nil && strings.Contains(arr.dict, "[") {
+				refs = refNumbers(arr.dict)
+			}
+		}
+	}
+	var contents [][]byte
+	for _, ref := range refs {
+		if obj, ok := d.objects[ref]; ok && obj.stream != nil {
+			contents = append(contents, obj.stream)
+		}
+	}
+	return contents
+}
+
+// pageFonts maps the font resource names of a page (inherited from parent
+// page tree nodes if needed) to their decoders.
+func (d *pdfDocument) pageFonts(page int) map[string]*pdfFont {
+	fonts := map[string]*pdfFont{}
+	num := page
+	for depth := 0; depth < 32; depth++ {
+		obj, ok := d.objects[num]
+		if !ok {
+			break
+		}
+		if resources := d.value(obj.dict, "/Resources"); resources != "" {
+			if fontDict := d.value(resources, "/Font"); fontDict != "" {
+				for name, ref := range pdfNameRefs(fontDict) {
+					if _, exists := fonts[name]; !exists {
+						fonts[name] = d.font(ref)
+					}
+				}
+				return fonts
+			}
+		}
+		parents := refNumbers(firstToken(d.rawValue(obj.dict, "/Parent")))
+		if len(parents) == 0 {
+			break
+		}
+		num = parents[0]
+	}
+	return fonts
+}
+
+func (d *pdfDocument) rawValue(dict, key string) string {
+	i := indexPDFKey(dict, key)
+	if i < 0 {
+		return ""
+	}
+	return dict[i+len(key):]
+}
+
+// pdfNameRefs reads "/F1 5 0 R /F2 7 0 R" pairs from the first dictionary in s.
+func pdfNameRefs(s string) map[string]int {
+	if start := strings.Index(s, "<<"); start >= 0 {
+		s = s[start+2:]
+	}
+	if end := strings.Index(s, ">>"); end >= 0 {
+		s = s[:end]
+	}
+	refs := map[string]int{}
+	for _, m := range pdfNamedRef.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[2])
+		refs[m[1]] = n
+	}
+	return refs
+}
+
+func (d *pdfDocument) font(num int) *pdfFont {
+	obj, ok := d.objects[num]
+	if !ok {
+		return nil
+	}
+	refs := refNumbers(firstToken(d.rawValue(obj.dict, "/ToUnicode")))
+	if len(refs) == 0 {
+		return nil
+	}
+	cmapObj, ok := d.objects[refs[0]]
+	if !ok || cmapObj.stream == nil {
+		return nil
+	}
+	return parseToUnicode(cmapObj.stream)
+}
+
+var (
+	cmapCodespace = regexp.MustCompile(`begincodespacerange\s*<([0-9A-Fa-f]+)>`)
+	cmapBfchar    = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBfrange   = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapHex       = regexp.MustCompile(`<([0-9A-Fa-f]*)>|\[([^\]]*)\]`)
+)
+
+func parseToUnicode(stream []byte) *pdfFont {
+	s := string(stream)
+	font := &pdfFont{cmap: map[string]string{}, codeSize: 1}
+	if m := cmapCodespace.FindStringSubmatch(s); m != nil {
+		font.codeSize = max(1, len(m[1])/2)
+	}
+	for _, block := range cmapBfchar.FindAllStringSubmatch(s, -1) {
+		items := cmapHex.FindAllStringSubmatch(block[1], -1)
+		for i := 0; i+1 < len(items); i += 2 {
+			font.cmap[strings.ToUpper(items[i][1])] = utf16Hex(items[i+1][1])
+		}
+	}
+	for _, block := range cmapBfrange.FindAllStringSubmatch(s, -1) {
+		items := cmapHex.FindAllStringSubmatch(block[1], -1)
+		for i := 0; i+2 < len(items); i += 3 {
+			lo, err1 := strconv.ParseUint(items[i][1], 16, 32)
+			hi, err2 := strconv.ParseUint(items[i+1][1], 16, 32)
+			if err1 != nil || err2 != nil || hi < lo || hi-lo > 0xFFFF {
+				continue
+			}
+			width := len(items[i][1])
+			if items[i+2][2] != "" {
+				// [<dst1> <dst2> ...] lists one destination per code.
+				dsts := cmapHex.FindAllStringSubmatch(items[i+2][2], -1)
+				for k := 0; k < len(dsts) && lo+uint64(k) <= hi; k++ {
+					font.cmap[hexCode(lo+uint64(k), width)] = utf16Hex(dsts[k][1])
+				}
+				continue
+			}
+			dst := []rune(utf16Hex(items[i+2][1]))
+			if len(dst) == 0 {
+				continue
+			}
+			for code := lo; code <= hi; code++ {
+				mapped := append([]rune{}, dst...)
+				mapped[len(mapped)-1] += rune(code - lo)
+				font.cmap[hexCode(code, width)] = string(mapped)
+			}
+		}
+	}
+	return font
+}
+
+func hexCode(code uint64, width int) string {
+	s := strings.ToUpper(strconv.FormatUint(code, 16))
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
+
+func utf16Hex(h string) string {
+	var units []uint16
+	for i := 0; i+4 <= len(h); i += 4 {
+		v, err := strconv.ParseUint(h[i:i+4], 16, 16)
+		if err != nil {
+			return ""
+		}
+		units = append(units, uint16(v))
+	}
+	if len(h)%4 == 2 {
+		if v, err := strconv.ParseUint(h[len(h)-2:], 16, 8); err == nil {
+			units = append(units, uint16(v))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func (f *pdfFont) decode(raw []byte) string {
+	if f == nil || len(f.cmap) == 0 {
+		return decodePDFDocString(raw)
+	}
+	var sb strings.Builder
+	for i := 0; i+f.codeSize <= len(raw); i += f.codeSize {
+		code := strings.ToUpper(hexBytes(raw[i : i+f.codeSize]))
+		if s, ok := f.cmap[code]; ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+func hexBytes(b []byte) string {
+	const digits = "0123456789ABCDEF"
+	out := make([]byte, 0, len(b)*2)
+	for _, c := range b {
+		out = append(out, digits[c>>4], digits[c&0x0F])
+	}
+	return string(out)
+}
+
+// decodePDFDocString handles strings without a ToUnicode map: UTF-16BE with
+// a byte order mark, or single-byte text treated as Latin-1.
+func decodePDFDocString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		return decodeUTF16(raw[2:], true)
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// pdfPaint is the part of the graphics state that decides whether shown text
+// can be seen: the text rendering mode (3 draws nothing) and whether the fill
+// colour is white.
+type pdfPaint struct {
+	invisible bool
+	white     bool
+}
+
+// contentText interprets the text operators of a content stream. A move to
+// another baseline becomes a line break and large negative kerning in TJ
+// arrays becomes a space; runs positioned on the same baseline are joined.
+// Text drawn invisibly or in white is returned separately as hidden text.
+func (d *pdfDocument) contentText(content []byte, fonts map[string]*pdfFont) (string, string) {
+	var sb, hidden strings.Builder
+	var operands []any
+	var font *pdfFont
+	var lineY float64
+	var paint pdfPaint
+	var saved []pdfPaint
+	haveLine := false
+	moveTo := func(y float64) {
+		if haveLine && math.Abs(y-lineY) > 1 {
+			sb.WriteByte('\n')
+		}
+		lineY, haveLine = y, true
+	}
+	show := func(s string) {
+		if paint.invisible || paint.white {
+			hidden.WriteString(s)
+			return
+		}
+		sb.WriteString(s)
+	}
+	number := func(i int) (float64, bool) {
+		if i < 0 || i >= len(operands) {
+			return 0, false
+		}
+		v, ok := operands[i].(float64)
+		return v, ok
+	}
+
+	lx := &pdfLexer{data: content}
+	for {
+		tok, ok := lx.next()
+		if !ok {
+			break
+		}
+		op, isOp := tok.(pdfOperator)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = fonts[string(name)]
+				}
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].([]byte); ok {
+					show(font.decode(s))
+				}
+			}
+		case "'", "\"":
+			sb.WriteByte('\n')
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].([]byte); ok {
+					show(font.decode(s))
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if arr, ok := operands[len(operands)-1].([]any); ok {
+					for _, item := range arr {
+						switch v := item.(type) {
+						case []byte:
+							show(font.decode(v))
+						case float64:
+							if v < -200 {
+								show(" ")
+							}
+						}
+					}
+				}
+			}
+		case "T*":
+			sb.WriteByte('\n')
+		case "Td", "TD":
+			if ty, ok := number(len(operands) - 1); ok {
+				moveTo(lineY + ty)
+			}
+		case "Tm":
+			if y, ok := number(len(operands) - 1); ok {
+				moveTo(y)
+			}
+		case "Tr":
+			if mode, ok := number(len(operands) - 1); ok {
+				paint.invisible = mode == 3
+			}
+		case "g":
+			paint.white = isPDFWhite(operands, 1)
+		case "rg":
+			paint.white = isPDFWhite(operands, 3)
+		case "k":
+			if len(operands) >= 4 {
+				c, _ := number(len(operands) - 4)
+				m, _ := number(len(operands) - 3)
+				y, _ := number(len(operands) - 2)
+				k, _ := number(len(operands) - 1)
+				paint.white = c == 0 && m == 0 && y == 0 && k == 0
+			}
+		case "q":
+			saved = append(saved, paint)
+		case "Q":
+			if n := len(saved); n > 0 {
+				paint, saved = saved[n-1], saved[:n-1]
+			}
+		case "ET":
+			if hidden.Len() > 0 {
+				hidden.WriteByte(' ')
+			}
+		case "BI":
+			lx.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+	return sb.String(), hidden.String()
+}
+
+// isPDFWhite reports whether the last n operands are a white gray or RGB
+// fill colour, i.e. all 1.
+func isPDFWhite(operands []any, n int) bool {
+	if len(operands) < n {
+		return false
+	}
+	for _, op := range operands[len(operands)-n:] {
+		if v, ok := op.(float64); !ok || v < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+type pdfOperator string
+
+type pdfName string
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (lx *pdfLexer) skipSpace() {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		if c == '%' {
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' && c != '\f' && c != 0 {
+			return
+		}
+		lx.pos++
+	}
+}
+
+func (lx *pdfLexer) skipInlineImage() {
+	if i := bytes.Index(lx.data[lx.pos:], []byte("EI")); i >= 0 {
+		lx.pos += i + 2
+	} else {
+		lx.pos = len(lx.data)
+	}
+}
+
+// next returns a number (float64), string ([]byte), name, array ([]any),
+// dictionary marker or operator.
+func (lx *pdfLexer) next() (any, bool) {
+	lx.skipSpace()
+	if lx.pos >= len(lx.data) {
+		return nil, false
+	}
+	c := lx.data[lx.pos]
+	switch {
+	case c == '(':
+		return lx.literalString(), true
+	case c == '<' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<':
+		lx.pos += 2
+		return pdfOperator("<<"), true
+	case c == '>' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '>':
+		lx.pos += 2
+		return pdfOperator(">>"), true
+	case c == '<':
+		return lx.hexString(), true
+	case c == '[':
+		lx.pos++
+		var arr []any
+		for {
+			lx.skipSpace()
+			if lx.pos >= len(lx.data) {
+				return arr, true
+			}
+			if lx.data[lx.pos] == ']' {
+				lx.pos++
+				return arr, true
+			}
+			item, ok := lx.next()
+			if !ok {
+				return arr, true
+			}
+			arr = append(arr, item)
+		}
+	case c == '/':
+		start := lx.pos + 1
+		lx.pos++
+		for lx.pos < len(lx.data) && !isPDFDelimiter(lx.data[lx.pos]) {
+			lx.pos++
+		}
+		return pdfName(lx.data[start:lx.pos]), true
+	case c == '+' || c == '-' || c == '.' || isASCIIDigit(c):
+		start := lx.pos
+		lx.pos++
+		for lx.pos < len(lx.data) && (isASCIIDigit(lx.data[lx.pos]) || lx.data[lx.pos] == '.') {
+			lx.pos++
+		}
+		v, _ := strconv.ParseFloat(string(lx.data[start:lx.pos]), 64)
+		return v, true
+	default:
+		start := lx.pos
+		lx.pos++
+		for lx.pos < len(lx.data) && !isPDFDelimiter(lx.data[lx.pos]) {
+			lx.pos++
+		}
+		return pdfOperator(lx.data[start:lx.pos]), true
+	}
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (lx *pdfLexer) literalString() []byte {
+	lx.pos++ // (
+	var out []byte
+	depth := 1
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return out
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7'; k++ {
+						v = v*8 + int(lx.data[lx.pos]-'0')
+						lx.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (lx *pdfLexer) hexString() []byte {
+	lx.pos++ // <
+	var digits []byte
+	for lx.pos < len(lx.data) && lx.data[lx.pos] != '>' {
+		c := lx.data[lx.pos]
+		if isASCIIDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			digits = append(digits, c)
+		}
+		lx.pos++
+	}
+	lx.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
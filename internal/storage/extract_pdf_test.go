@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+// cyrillicCMap maps two-byte codes 1-6 to "Привет" and 0x10-0x12 to "абв"
+// through a range.
+const cyrillicCMap = `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+6 beginbfchar
+<0001> <041F> <0002> <0440> <0003> <0438>
+<0004> <0432> <0005> <0435> <0006> <0442>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0430>
+endbfrange
+endcmap`
+
+// buildPDF assembles a one-page document around a content stream. The font
+// F1 gets a ToUnicode map when cmap is set.
+func buildPDF(content, cmap string, flate bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	object := func(num int, dict string, stream []byte) {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", num, dict)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+	}
+	object(1, "<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>", nil)
+	object(3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>", nil)
+
+	stream, filter := []byte(content), ""
+	if flate {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		_, _ = zw.Write(stream)
+		_ = zw.Close()
+		stream, filter = z.Bytes(), " /Filter /FlateDecode"
+	}
+	object(4, fmt.Sprintf("<< /Length %d%s >>", len(stream), filter), stream)
+	if cmap == "" {
+		object(5, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>", nil)
+	} else {
+		object(5, "<< /Type /Font /Subtype /Type0 /ToUnicode 6 0 R >>", nil)
+		object(6, fmt.Sprintf("<< /Length %d >>", len(cmap)), []byte(cmap))
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		pdf     []byte
+		text    string
+		hidden  string
+		wantErr bool
+	}{
+		{
+			name: "single Tj",
+			pdf:  buildPDF("BT /F1 12 Tf 72 720 Td (Hello world) Tj ET", "", false),
+			text: "Hello world",
+		},
+		{
+			name: "flate compressed stream",
+			pdf:  buildPDF("BT /F1 12 Tf 72 720 Td (Compressed text) Tj ET", "", true),
+			text: "Compressed text",
+		},
+		{
+			name: "moving to another baseline breaks the line",
+			pdf:  buildPDF("BT /F1 12 Tf 72 720 Td (First) Tj 0 -14 Td (Second) Tj ET", "", false),
+			text: "First\nSecond",
+		},
+		{
+			name: "runs on one baseline are joined",
+			pdf:  buildPDF("BT /F1 12 Tf 72 720 Td (Sim) Tj 20 0 Td (ilar) Tj ET", "", false),
+			text: "Similar",
+		},
+		{
+			name: "large kerning is a space, small is not",
+			pdf:  buildPDF("BT /F1 12 Tf [(Ker) -50 (ning) -300 (gap)] TJ ET", "", false),
+			text: "Kerning gap",
+		},
+		{
+			name: "escapes in literal strings",
+			pdf:  buildPDF(`BT /F1 12 Tf (f\(x\) \\ y) Tj ET`, "", false),
+			text: `f(x) \ y`,
+		},
+		{
+			name: "UTF-16BE string with byte order mark",
+			pdf:  buildPDF("BT /F1 12 Tf <FEFF041F04400438043204350442> Tj ET", "", false),
+			text: "Привет",
+		},
+		{
+			name: "ToUnicode map with bfchar and bfrange",
+			pdf:  buildPDF("BT /F1 12 Tf 72 720 Td <000100020003000400050006> Tj 0 -14 Td <001000110012> Tj ET", cyrillicCMap, true),
+			text: "Привет\nабв",
+		},
+		{
+			name:   "invisible rendering mode is hidden",
+			pdf:    buildPDF("BT /F1 12 Tf (Shown) Tj 3 Tr (secret) Tj 0 Tr ( again) Tj ET", "", false),
+			text:   "Shown again",
+			hidden: "secret",
+		},
+		{
+			name:   "white fill is hidden until the state is restored",
+			pdf:    buildPDF("BT /F1 12 Tf (Black) Tj ET q 1 1 1 rg BT /F1 12 Tf (white) Tj ET Q BT /F1 12 Tf ( text) Tj ET", "", false),
+			text:   "Black text",
+			hidden: "white",
+		},
+		{
+			name:   "white gray and CMYK fills",
+			pdf:    buildPDF("BT /F1 12 Tf 1 g (gray) Tj ET BT 0 0 0 0 k (cmyk) Tj ET BT 0 g (dark) Tj ET", "", false),
+			text:   "dark",
+			hidden: "gray cmyk",
+		},
+		{
+			name:    "encrypted",
+			pdf:     []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R /Encrypt 2 0 R >>\n"),
+			wantErr: true,
+		},
+		{
+			name:    "no objects",
+			pdf:     []byte("%PDF-1.4\n%%EOF\n"),
+			wantErr: true,
+		},
+		{
+			name:    "page without text",
+			pdf:     buildPDF("0 0 m 10 10 l S", "", false),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText(tt.pdf, "application/pdf", "work.pdf")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractText = %q, want error", got.Text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got.Text != tt.text {
+				t.Errorf("Text = %q, want %q", got.Text, tt.text)
+			}
+			if got.Hidden != tt.hidden {
+				t.Errorf("Hidden = %q, want %q", got.Hidden, tt.hidden)
+			}
+		})
+	}
+}
+
+// withObjectStream adds object 9, an object stream with the given header and
+// body, to a document made by buildPDF.
+func withObjectStream(pdf []byte, n int, header, body string) []byte {
+	stream := header + body
+	obj := fmt.Sprintf("9 0 obj\n<< /Type /ObjStm /N %d /First %d /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+		n, len(header), len(stream), stream)
+	i := bytes.LastIndex(pdf, []byte("trailer"))
+	return append(append(append([]byte{}, pdf[:i]...), obj...), pdf[i:]...)
+}
+
+func TestExtractPDFObjectStreams(t *testing.T) {
+	const body = "<< /A 1 >> << /B 2 >>"
+	tests := []struct {
+		name   string
+		n      int
+		header string
+		want   map[int]string
+	}{
+		{name: "valid header", n: 2, header: "10 0 11 11 ", want: map[int]string{10: "<< /A 1 >> ", 11: "<< /B 2 >>"}},
+		{name: "negative offset", n: 2, header: "10 -5 11 11 ", want: map[int]string{11: "<< /B 2 >>"}},
+		{name: "next offset before the current one", n: 2, header: "10 11 11 3 ", want: map[int]string{11: "/A 1 >> << /B 2 >>"}},
+		{name: "offset past the stream", n: 1, header: "10 999 ", want: map[int]string{}},
+		{name: "next offset past the stream", n: 2, header: "10 0 11 999 ", want: map[int]string{10: body}},
+		{name: "garbage", n: 3, header: "x -1 -2 y 10 ", want: map[int]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := withObjectStream(buildPDF("BT /F1 12 Tf (Hello) Tj ET", "", false), tt.n, tt.header, body)
+			doc := parsePDF(pdf)
+			for _, num := range []int{10, 11} {
+				obj, ok := doc.objects[num]
+				want, wantOK := tt.want[num]
+				if ok != wantOK || (ok && obj.dict != want) {
+					t.Errorf("object %d = %v, want %q (present %v)", num, obj, want, wantOK)
+				}
+			}
+			got, err := extractPDF(pdf)
+			if err != nil {
+				t.Fatalf("extractPDF: %v", err)
+			}
+			if NormalizeText(got.Text) != "Hello" {
+				t.Errorf("Text = %q, want %q", got.Text, "Hello")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// rtfSkippedDestinations hold metadata or binary data rather than body text.
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "header": true, "footer": true,
+	"headerl": true, "headerr": true, "footerl": true, "footerr": true,
+	"listtable": true, "listoverridetable": true, "revtbl": true, "rsidtbl": true,
+	"generator": true, "themedata": true, "colorschememapping": true,
+	"datastore": true, "latentstyles": true, "xmlnstbl": true, "filetbl": true,
+}
+
+var rtfCodepages = map[int]encoding.Encoding{
+	866:   charmap.CodePage866,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	10007: charmap.MacintoshCyrillic,
+	20866: charmap.KOI8R,
+}
+
+type rtfGroup struct {
+	skip        bool
+	unicodeSkip int
+	hidden      bool // \v
+	tiny        bool // \fs of 1pt or less
+}
+
+// extractRTF walks the RTF groups, keeping plain text, \'hh bytes decoded in
+// the document code page and \uN characters, and dropping destinations that
+// are not part of the body. Hidden (\v) and microscopic text goes to the
+// hidden text.
+func extractRTF(data []byte) (Extracted, error) {
+	if !strings.HasPrefix(string(data), `{\rtf`) {
+		return Extracted{}, errors.New("missing RTF header")
+	}
+
+	var visible, hidden strings.Builder
+	var pending []byte // \'hh bytes waiting to be decoded together
+	codepage := encoding.Encoding(charmap.Windows1252)
+	stack := []rtfGroup{{unicodeSkip: 1}}
+	skipChars := 0
+
+	inHidden := false
+	out := func() *strings.Builder {
+		if g := stack[len(stack)-1]; g.hidden || g.tiny {
+			inHidden = true
+			return &hidden
+		}
+		if inHidden {
+			// Keep separate hidden runs apart.
+			hidden.WriteByte(' ')
+			inHidden = false
+		}
+		return &visible
+	}
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if decoded, err := codepage.NewDecoder().Bytes(pending); err == nil {
+			out().Write(decoded)
+		}
+		pending = pending[:0]
+	}
+	emit := func(s string) {
+		flush()
+		if !stack[len(stack)-1].skip {
+			out().WriteString(s)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		cur := &stack[len(stack)-1]
+		switch c {
+		case '{':
+			flush()
+			stack = append(stack, *cur)
+			skipChars = 0
+		case '}':
+			flush()
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			skipChars = 0
+		case '\\':
+			if i+1 >= len(data) {
+				break
+			}
+			next := data[i+1]
+			switch {
+			case next == '\'':
+				if i+3 < len(data) {
+					if b, err := strconv.ParseUint(string(data[i+2:i+4]), 16, 8); err == nil {
+						if skipChars > 0 {
+							skipChars--
+						} else if !cur.skip {
+							pending = append(pending, byte(b))
+						}
+					}
+				}
+				i += 3
+			case next == '*':
+				// Unknown destinations marked with \* are ignorable.
+				cur.skip = true
+				i++
+			case isASCIILetter(next):
+				j := i + 1
+				for j < len(data) && isASCIILetter(data[j]) {
+					j++
+				}
+				word := string(data[i+1 : j])
+				k := j
+				if k < len(data) && (data[k] == '-' || isASCIIDigit(data[k])) {
+					k++
+					for k < len(data) && isASCIIDigit(data[k]) {
+						k++
+					}
+				}
+				param, hasParam := 0, k > j
+				if hasParam {
+					param, _ = strconv.Atoi(string(data[j:k]))
+				}
+				if k < len(data) && data[k] == ' ' {
+					k++
+				}
+				i = k - 1
+				// Pending bytes belong to the formatting before this word.
+				flush()
+
+				switch {
+				case rtfSkippedDestinations[word]:
+					cur.skip = true
+				case word == "ansicpg":
+					if enc, ok := rtfCodepages[param]; ok {
+						codepage = enc
+					}
+				case word == "uc":
+					cur.unicodeSkip = param
+				case word == "v":
+					cur.hidden = !hasParam || param != 0
+				case word == "fs":
+					// Half-points.
+					cur.tiny = hasParam && param <= 2
+				case word == "plain":
+					cur.hidden, cur.tiny = false, false
+				case word == "u":
+					if param < 0 {
+						param += 65536
+					}
+					emit(string(rune(param)))
+					skipChars = cur.unicodeSkip
+				case word == "par" || word == "line" || word == "sect" || word == "page":
+					emit("\n")
+				case word == "tab" || word == "cell":
+					emit("\t")
+				case word == "row":
+					emit("\n")
+				case word == "emdash":
+					emit("—")
+				case word == "endash":
+					emit("–")
+				case word == "lquote" || word == "rquote":
+					emit("'")
+				case word == "ldblquote" || word == "rdblquote":
+					emit("\"")
+				case word == "bin" && hasParam:
+					i += param
+				}
+			default:
+				// Escaped symbols: \\, \{, \}, \~ (non-breaking space) and
+				// \- (optional hyphen).
+				switch next {
+				case '\\', '{', '}':
+					emit(string(next))
+				case '~':
+					emit(" ")
+				case '\n', '\r':
+					emit("\n")
+				}
+				i++
+			}
+		case '\r', '\n':
+		default:
+			if skipChars > 0 {
+				skipChars--
+				continue
+			}
+			if cur.skip {
+				continue
+			}
+			if c >= 0x80 {
+				pending = append(pending, c)
+				continue
+			}
+			emit(string(c))
+		}
+	}
+	flush()
+	return Extracted{Text: visible.String(), Hidden: hidden.String()}, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,91 @@
+package storage
+
+import "testing"
+
+func TestExtractRTF(t *testing.T) {
+	tests := []struct {
+		name    string
+		rtf     string
+		text    string
+		hidden  string
+		wantErr bool
+	}{
+		{
+			name: "paragraphs",
+			rtf:  `{\rtf1\ansi Hello\par World}`,
+			text: "Hello\nWorld",
+		},
+		{
+			name: "hex bytes in the document code page",
+			rtf:  `{\rtf1\ansi\ansicpg1251 \'cf\'f0\'e8\'e2\'e5\'f2}`,
+			text: "Привет",
+		},
+		{
+			name: "unicode characters skip their fallback",
+			rtf:  `{\rtf1\ansi\uc1 \u1055?\u1088?\u1080?}`,
+			text: "При",
+		},
+		{
+			name: "negative unicode parameter",
+			rtf:  `{\rtf1\ansi\uc1 \u-1279?}`,
+			text: "\uFB01",
+		},
+		{
+			name: "metadata destinations are skipped",
+			rtf:  `{\rtf1\ansi{\fonttbl{\f0 Times New Roman;}}{\colortbl;\red0\green0\blue0;}{\*\generator Word;}Body}`,
+			text: "Body",
+		},
+		{
+			name: "escaped symbols",
+			rtf:  `{\rtf1 a\{b\}c\\d\~e}`,
+			text: "a{b}c\\d e",
+		},
+		{
+			name: "nested groups restore formatting",
+			rtf:  `{\rtf1 one {\b bold {\i both}} two}`,
+			text: "one bold both two",
+		},
+		{
+			name:   "hidden text",
+			rtf:    `{\rtf1 Shown {\v secret} text {\v0 visible}}`,
+			text:   "Shown text visible",
+			hidden: "secret",
+		},
+		{
+			name:   "microscopic text",
+			rtf:    `{\rtf1 Big{\fs2 tiny}{\fs24  normal}\fs1 dot\plain  end}`,
+			text:   "Big normal end",
+			hidden: "tiny dot",
+		},
+		{
+			name:    "missing header",
+			rtf:     `{\pict 0000}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty document",
+			rtf:     `{\rtf1\ansi{\fonttbl{\f0 Arial;}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText([]byte(tt.rtf), "application/rtf", "work.rtf")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractText = %q, want error", got.Text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got.Text != tt.text {
+				t.Errorf("Text = %q, want %q", got.Text, tt.text)
+			}
+			if got.Hidden != tt.hidden {
+				t.Errorf("Hidden = %q, want %q", got.Hidden, tt.hidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		mimeType string
+		file     string
+		want     string
+	}{
+		{"pdf signature wins over the name", []byte("%PDF-1.7\n"), "application/octet-stream", "work.txt", FormatPDF},
+		{"rtf signature", []byte(`{\rtf1 x}`), "", "work.doc", FormatRTF},
+		{"docx archive", buildDOCX(`<w:p/>`), "", "work.bin", FormatDOCX},
+		{"odt archive", buildZip(map[string]string{"mimetype": odtMimeType}), "", "work.zip", FormatODT},
+		{"unknown archive", buildZip(map[string]string{"a.txt": "a"}), "", "work.docx", ""},
+		{"pdf by mime type", []byte{0, 1, 2}, "application/pdf", "", FormatPDF},
+		{"source file by extension", []byte{0, 1, 2}, "", "main.GO", FormatText},
+		{"untyped text", []byte("plain words"), "", "upload", FormatText},
+		{"untyped binary", []byte{'a', 0, 'b'}, "", "upload", ""},
+		{"empty file", nil, "", "upload", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.data, tt.mimeType, tt.file); got != tt.want {
+				t.Errorf("DetectFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"line breaks", "a\r\nb\rc\u2028d\u2029e", "a\nb\nc\nd\ne"},
+		{"byte order marks", "\uFEFFtext\uFEFF", "text"},
+		{"invalid UTF-8", "ok\xff\xfe!", "ok!"},
+		{"NFC", "е\u0308ж", "ёж"},
+		{"layout is kept", "def f():\n\n    return  1\t# x\n", "def f():\n\n    return  1\t# x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CleanText(tt.in); got != tt.want {
+				t.Errorf("CleanText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"only blanks", " \t\u00A0\n\n", ""},
+		{"blank runs collapse", "a \t\u00A0\u2003 b", "a b"},
+		{"lines are trimmed", "  one  \n\ttwo\t", "one\ntwo"},
+		{"empty lines collapse to one", "a\n\n\n\n\nb", "a\n\nb"},
+		{"soft hyphens are dropped", "пере\u00ADнос", "перенос"},
+		{"control characters become spaces", "a\x00b\x07c", "a b c"},
+		{"zero-width characters are kept", "a\u200Bb", "a\u200Bb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.in); got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTextPlain(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		file    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "source code keeps indentation and blank lines",
+			data: []byte("def f():\r\n    if x:\r\n        return 1\r\n\r\n\r\n\r\nprint(f())\r\n"),
+			file: "main.py",
+			want: "def f():\n    if x:\n        return 1\n\n\n\nprint(f())\n",
+		},
+		{
+			name: "UTF-8 byte order mark",
+			data: append([]byte{0xEF, 0xBB, 0xBF}, "текст"...),
+			file: "a.txt",
+			want: "текст",
+		},
+		{
+			name: "UTF-16LE",
+			data: []byte{0xFF, 0xFE, 0x1F, 0x04, 0x40, 0x04, 'x', 0},
+			file: "a.txt",
+			want: "Прx",
+		},
+		{
+			name: "Windows-1251 fallback",
+			data: []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2},
+			file: "a.txt",
+			want: "Привет",
+		},
+		{
+			name:    "whitespace only",
+			data:    []byte(" \n\t\n"),
+			file:    "a.txt",
+			wantErr: ErrNoText,
+		},
+		{
+			name:    "unsupported format",
+			data:    []byte{0x89, 'P', 'N', 'G', 0},
+			file:    "scan.png",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractText(tt.data, "", tt.file)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ExtractText error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractText: %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("Text = %q, want %q", got.Text, tt.want)
+			}
+		})
+	}
+}
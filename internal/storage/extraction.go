@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// extractionBatch is how many pending works the worker takes at a time.
+	extractionBatch = 16
+	// extractionPollInterval is how often the worker looks for pending works
+	// it was not woken for, e.g. ones left by a previous run.
+	extractionPollInterval = 30 * time.Second
+)
+
+// RunExtraction extracts the text of uploaded works in the background until
+// ctx is done. An upload only stores the file and leaves the work pending, so
+// a large PDF does not hold the request. The queue is the works table itself:
+// works still pending after a restart are picked up again.
+func (h *Handler) RunExtraction(ctx context.Context) {
+	ticker := time.NewTicker(extractionPollInterval)
+	defer ticker.Stop()
+	for {
+		works, err := h.repo.ListPendingTextWorks(ctx, extractionBatch)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to list works pending extraction", "err", err)
+		}
+		for i := range works {
+			if ctx.Err() != nil {
+				return
+			}
+			h.extractWorkText(ctx, &works[i])
+		}
+		if len(works) == extractionBatch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.extractions:
+		case <-ticker.C:
+		}
+	}
+}
+
+// queueExtraction wakes the extraction worker. It never blocks: a wake-up
+// that is already due covers this work too.
+func (h *Handler) queueExtraction() {
+	select {
+	case h.extractions <- struct{}{}:
+	default:
+	}
+}
+
+// ListPendingTextWorks returns up to limit works whose text has not been
+// extracted yet, oldest first.
+func (r *Repository) ListPendingTextWorks(ctx context.Context, limit int) ([]Work, error) {
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.text_status = $1 AND w.deleted_at IS NULL
+	ORDER BY w.id
+	LIMIT $2;`
+
+	rows, err := r.pool.Query(ctx, query, TextStatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending text works: %w", err)
+	}
+	defer rows.Close()
+
+	var works []Work
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list pending text works: %w", err)
+		}
+		works = append(works, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pending text works: %w", err)
+	}
+	return works, nil
+}
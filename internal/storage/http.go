@@ -1,39 +1,146 @@
 package storage
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+
+	"HW_KPO3/internal/config"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
 type Handler struct {
-	repo *Repository
+	repo        *Repository
+	blobs       BlobStore
+	submissions config.Submissions
+	extractions chan struct{}
 }
 
-func NewHandler(repo *Repository) *Handler {
+func NewHandler(repo *Repository, blobs BlobStore, submissions config.Submissions) *Handler {
 	return &Handler{
-		repo: repo,
+		repo:        repo,
+		blobs:       blobs,
+		submissions: submissions,
+		extractions: make(chan struct{}, 1),
 	}
 }
 
 type createWorkRequest struct {
-	Student  string `json:"student"`
-	Task     string `json:"task"`
-	FilePath string `json:"file_path"`
+	StudentID int64  `json:"student_id"`
+	TaskID    int64  `json:"task_id"`
+	FilePath  string `json:"file_path"`
 }
 
 type workResponse struct {
+	ID             int64           `json:"id"`
+	StudentID      int64           `json:"student_id"`
+	Student        string          `json:"student"`
+	TaskID         int64           `json:"task_id"`
+	Task           string          `json:"task"`
+	Version        int             `json:"version"`
+	FilePath       string          `json:"file_path"`
+	FileName       string          `json:"file_name"`
+	FileSize       int64           `json:"file_size"`
+	MimeType       string          `json:"mime_type"`
+	ContentHash    string          `json:"content_hash"`
+	IsLate         bool            `json:"is_late"`
+	LateBy         string          `json:"late_by"`
+	TextStatus     string          `json:"text_status"`
+	TextError      string          `json:"text_error"`
+	IdenticalWorks []identicalWork `json:"identical_works"`
+	UploadedAt     string          `json:"uploaded_at"`
+}
+
+type identicalWork struct {
 	ID         int64  `json:"id"`
 	Student    string `json:"student"`
 	Task       string `json:"task"`
-	FilePath   string `json:"file_path"`
 	UploadedAt string `json:"uploaded_at"`
 }
 
+func newWorkResponse(work *Work) *workResponse {
+	return &workResponse{
+		ID:             work.ID,
+		StudentID:      work.StudentID,
+		Student:        work.Student,
+		TaskID:         work.TaskID,
+		Task:           work.Task,
+		Version:        work.Version,
+		FilePath:       work.FilePath,
+		FileName:       work.FileName,
+		FileSize:       work.FileSize,
+		MimeType:       work.MimeType,
+		ContentHash:    work.ContentHash,
+		IsLate:         work.IsLate,
+		LateBy:         work.LateBy.String(),
+		TextStatus:     work.TextStatus,
+		TextError:      work.TextError,
+		IdenticalWorks: []identicalWork{},
+		UploadedAt:     work.UploadedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func newIdenticalWorks(works []Work) []identicalWork {
+	identical := make([]identicalWork, 0, len(works))
+	for _, work := range works {
+		identical = append(identical, identicalWork{
+			ID:         work.ID,
+			Student:    work.Student,
+			Task:       work.Task,
+			UploadedAt: work.UploadedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return identical
+}
+
+// resolveSubmission checks that the student and task referenced by work exist,
+// fills in their names and judges the submission against the task deadline.
+// It writes the error response itself and reports whether the caller may
+// continue.
+func (h *Handler) resolveSubmission(w http.ResponseWriter, r *http.Request, work *Work) bool {
+	student, err := h.repo.GetStudent(r.Context(), work.StudentID)
+	if err != nil {
+		if errors.Is(err, ErrStudentNotFound) {
+			http.Error(w, "unknown student_id", http.StatusBadRequest)
+			return false
+		}
+		slog.Error("failed to get student", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	task, err := h.repo.GetTask(r.Context(), work.TaskID)
+	if err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			http.Error(w, "unknown task_id", http.StatusBadRequest)
+			return false
+		}
+		slog.Error("failed to get task", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	work.Student, work.Task = student.Name, task.Title
+
+	work.UploadedAt = time.Now().UTC()
+	if h.submissions.RejectLate {
+		if err := task.CheckCutoff(work.UploadedAt, h.submissions.HardCutoff); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return false
+		}
+	}
+	work.IsLate, work.LateBy = task.Lateness(work.UploadedAt)
+	return true
+}
+
 func (h *Handler) CreateWork(w http.ResponseWriter, r *http.Request) {
+	if isMultipart(r) {
+		h.uploadWork(w, r)
+		return
+	}
+
 	var req createWorkRequest
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		slog.Error("failed to decode request", "err", err)
@@ -41,27 +148,26 @@ func (h *Handler) CreateWork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Student == "" || req.Task == "" || req.FilePath == "" {
-		http.Error(w, "student, task and file_path are required", http.StatusBadRequest)
+	if req.StudentID <= 0 || req.TaskID <= 0 || req.FilePath == "" {
+		http.Error(w, "student_id, task_id and file_path are required", http.StatusBadRequest)
 		return
 	}
 	work := &Work{
-		Student:  req.Student,
-		Task:     req.Task,
-		FilePath: req.FilePath,
+		StudentID:  req.StudentID,
+		TaskID:     req.TaskID,
+		FilePath:   req.FilePath,
+		TextStatus: TextStatusSkipped,
+		TextError:  noFileError,
+	}
+	if !h.resolveSubmission(w, r, work) {
+		return
 	}
 	if err := h.repo.CreateWork(r.Context(), work); err != nil {
 		slog.Error("failed to create work", "err", err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
-	response := &workResponse{
-		ID:         work.ID,
-		Student:    work.Student,
-		Task:       work.Task,
-		FilePath:   work.FilePath,
-		UploadedAt: work.UploadedAt.Format("2006-01-02 15:04:05"),
-	}
+	response := newWorkResponse(work)
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, response)
 }
@@ -83,13 +189,14 @@ func (h *Handler) GetWork(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-	response := &workResponse{
-		ID:         work.ID,
-		Student:    work.Student,
-		Task:       work.Task,
-		FilePath:   work.FilePath,
-		UploadedAt: work.UploadedAt.Format("2006-01-02 15:04:05"),
+	identical, err := h.repo.FindIdenticalWorks(r.Context(), work.ContentHash, work.ID)
+	if err != nil {
+		slog.Error("failed to find identical works", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
+	response := newWorkResponse(work)
+	response.IdenticalWorks = newIdenticalWorks(identical)
 	render.Status(r, http.StatusOK)
 	render.JSON(w, r, response)
 }
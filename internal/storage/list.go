@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	SortByID         = "id"
+	SortByUploadedAt = "uploaded_at"
+	SortByStudent    = "student"
+	SortByTask       = "task"
+
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type WorkSort struct {
+	Field string
+	Desc  bool
+}
+
+func (s WorkSort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+type WorkCursor struct {
+	Value any
+	ID    int64
+}
+
+type WorkFilter struct {
+	StudentID    int64
+	TaskID       int64
+	UploadedFrom time.Time
+	UploadedTo   time.Time
+	Sort         WorkSort
+	After        *WorkCursor
+	Limit        int
+}
+
+type listWorksResponse struct {
+	Items      []*workResponse `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// cursorPayload is the JSON behind the opaque base64 cursor. It remembers the
+// sort it was issued for so it cannot be replayed against another ordering.
+type cursorPayload struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+func (h *Handler) ListWorks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := WorkFilter{Limit: defaultListLimit}
+
+	var err error
+	if v := q.Get("student_id"); v != "" {
+		if filter.StudentID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "invalid student_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("task_id"); v != "" {
+		if filter.TaskID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "invalid task_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.Sort, err = parseWorkSort(q.Get("sort")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxListLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxListLimit), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("uploaded_from"); v != "" {
+		if filter.UploadedFrom, _, err = parseTimeParam(v); err != nil {
+			http.Error(w, "invalid uploaded_from", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("uploaded_to"); v != "" {
+		var dateOnly bool
+		if filter.UploadedTo, dateOnly, err = parseTimeParam(v); err != nil {
+			http.Error(w, "invalid uploaded_to", http.StatusBadRequest)
+			return
+		}
+		if dateOnly {
+			filter.UploadedTo = filter.UploadedTo.AddDate(0, 0, 1)
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		if filter.After, err = decodeWorkCursor(v, filter.Sort); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pageFilter := filter
+	pageFilter.Limit = filter.Limit + 1
+	works, err := h.repo.ListWorks(r.Context(), pageFilter)
+	if err != nil {
+		slog.Error("failed to list works", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := &listWorksResponse{Items: make([]*workResponse, 0, len(works))}
+	if len(works) > filter.Limit {
+		works = works[:filter.Limit]
+		response.NextCursor = encodeWorkCursor(works[len(works)-1], filter.Sort)
+	}
+	for i := range works {
+		response.Items = append(response.Items, newWorkResponse(&works[i]))
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+func parseWorkSort(v string) (WorkSort, error) {
+	if v == "" {
+		return WorkSort{Field: SortByUploadedAt, Desc: true}, nil
+	}
+	sort := WorkSort{Field: strings.TrimPrefix(v, "-"), Desc: strings.HasPrefix(v, "-")}
+	switch sort.Field {
+	case SortByID, SortByUploadedAt, SortByStudent, SortByTask:
+		return sort, nil
+	default:
+		return WorkSort{}, errors.New("sort must be one of id, uploaded_at, student, task (prefix with - for descending)")
+	}
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a plain date and
+// reports which one it got. Times are converted to UTC to match uploaded_at.
+func parseTimeParam(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t.UTC(), false, nil
+}
+
+func encodeWorkCursor(work Work, sort WorkSort) string {
+	payload := cursorPayload{Sort: sort.String(), ID: work.ID}
+	switch sort.Field {
+	case SortByUploadedAt:
+		payload.Value = work.UploadedAt.Format(time.RFC3339Nano)
+	case SortByStudent:
+		payload.Value = work.Student
+	case SortByTask:
+		payload.Value = work.Task
+	}
+	raw, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeWorkCursor(v string, sort WorkSort) (*WorkCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Sort != sort.String() {
+		return nil, errors.New("cursor was issued for a different sort")
+	}
+
+	cursor := &WorkCursor{ID: payload.ID}
+	switch sort.Field {
+	case SortByUploadedAt:
+		t, err := time.Parse(time.RFC3339Nano, payload.Value)
+		if err != nil {
+			return nil, err
+		}
+		cursor.Value = t
+	case SortByStudent, SortByTask:
+		cursor.Value = payload.Value
+	}
+	return cursor, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestWorkCursorRoundTrip(t *testing.T) {
+	uploaded := time.Date(2024, 3, 31, 1, 30, 0, 123456789, time.UTC)
+	work := Work{ID: 42, Student: "Ёлкина  Анна", Task: "Лаба #1 / \"sort\"", UploadedAt: uploaded}
+
+	tests := []struct {
+		name  string
+		sort  WorkSort
+		value any
+	}{
+		{"id", WorkSort{Field: SortByID}, nil},
+		{"id descending", WorkSort{Field: SortByID, Desc: true}, nil},
+		{"uploaded_at keeps nanoseconds", WorkSort{Field: SortByUploadedAt, Desc: true}, uploaded},
+		{"student with multi-byte name", WorkSort{Field: SortByStudent}, work.Student},
+		{"task with quotes", WorkSort{Field: SortByTask, Desc: true}, work.Task},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeWorkCursor(encodeWorkCursor(work, tt.sort), tt.sort)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if cursor.ID != work.ID {
+				t.Errorf("ID = %d, want %d", cursor.ID, work.ID)
+			}
+			if got, ok := cursor.Value.(time.Time); ok {
+				if !got.Equal(tt.value.(time.Time)) {
+					t.Errorf("Value = %v, want %v", got, tt.value)
+				}
+			} else if cursor.Value != tt.value {
+				t.Errorf("Value = %#v, want %#v", cursor.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodeWorkCursorRejects(t *testing.T) {
+	work := Work{ID: 7, Student: "Иванов", UploadedAt: time.Now()}
+	byStudent := WorkSort{Field: SortByStudent}
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   WorkSort
+	}{
+		{"empty", "", byStudent},
+		{"not base64", "!!!", byStudent},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"student","id":1}`)), byStudent},
+		{"not json", encode("student:1"), byStudent},
+		{"other field", encodeWorkCursor(work, byStudent), WorkSort{Field: SortByTask}},
+		{"other direction", encodeWorkCursor(work, byStudent), WorkSort{Field: SortByStudent, Desc: true}},
+		{"bad time", encode(`{"s":"uploaded_at","v":"yesterday","id":1}`), WorkSort{Field: SortByUploadedAt}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeWorkCursor(tt.cursor, tt.sort); err == nil {
+				t.Errorf("decodeWorkCursor(%q) = %+v, want error", tt.cursor, cursor)
+			}
+		})
+	}
+}
+
+func TestParseWorkSort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    WorkSort
+		wantErr bool
+	}{
+		{"", WorkSort{Field: SortByUploadedAt, Desc: true}, false},
+		{"id", WorkSort{Field: SortByID}, false},
+		{"-student", WorkSort{Field: SortByStudent, Desc: true}, false},
+		{"task", WorkSort{Field: SortByTask}, false},
+		{"--task", WorkSort{}, true},
+		{"size", WorkSort{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseWorkSort(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWorkSort(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
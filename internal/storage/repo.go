@@ -2,8 +2,14 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,28 +23,457 @@ func NewRepository(pool *pgxpool.Pool) *Repository {
 	}
 }
 
+// CreateWork stores a new version of the (student, task) submission. The
+// version number is the next one after every earlier version, including
+// deleted ones, so numbers are never reused.
 func (r *Repository) CreateWork(ctx context.Context, work *Work) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create work: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if work.Version, err = nextVersion(ctx, tx, work.StudentID, work.TaskID); err != nil {
+		return fmt.Errorf("create work: %w", err)
+	}
+
+	if work.UploadedAt.IsZero() {
+		work.UploadedAt = time.Now().UTC()
+	}
+	if work.TextStatus == "" {
+		work.TextStatus = TextStatusPending
+	}
 	const query = `
-	INSERT INTO works (student, task, file_path)
-	VALUES ($1, $2, $3)
+	INSERT INTO works (student_id, task_id, version, file_path, file_name, file_size, mime_type, content_hash, is_late, late_by_seconds, text_status, text_error, uploaded_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	RETURNING id, uploaded_at;`
 
-	row := r.pool.QueryRow(ctx, query, work.Student, work.Task, work.FilePath)
+	row := tx.QueryRow(ctx, query, work.StudentID, work.TaskID, work.Version, work.FilePath, work.FileName, work.FileSize, work.MimeType, work.ContentHash,
+		work.IsLate, int64(work.LateBy/time.Second), work.TextStatus, work.TextError, work.UploadedAt)
 	if err := row.Scan(&work.ID, &work.UploadedAt); err != nil {
 		return fmt.Errorf("create work: %w", err)
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("create work: %w", err)
+	}
 	return nil
 }
 
-func (r *Repository) GetWork(ctx context.Context, id int64) (*Work, error) {
+// nextVersion serialises concurrent submissions of the same student and task
+// with a transaction-scoped advisory lock and returns the next version number.
+// The lock key is a 64-bit hash of both ids, so ids beyond the int range do
+// not overflow it.
+func nextVersion(ctx context.Context, tx pgx.Tx, studentID, taskID int64) (int, error) {
+	const lock = `
+	SELECT pg_advisory_xact_lock(hashtextextended($1::text || ':' || $2::text, 0));`
+	if _, err := tx.Exec(ctx, lock, studentID, taskID); err != nil {
+		return 0, fmt.Errorf("lock submission: %w", err)
+	}
+
 	const query = `
-	Select id, student, task, file_path, uploaded_at FROM works  WHERE id = $1;`
+	SELECT COALESCE(MAX(version), 0) + 1 FROM works WHERE student_id = $1 AND task_id = $2;`
+	var version int
+	if err := tx.QueryRow(ctx, query, studentID, taskID).Scan(&version); err != nil {
+		return 0, fmt.Errorf("next version: %w", err)
+	}
+	return version, nil
+}
 
-	row := r.pool.QueryRow(ctx, query, id)
+// Works are always read together with the names of their student and task.
+const (
+	workColumns = `w.id, w.student_id, s.name, w.task_id, t.title, w.version, w.file_path, w.file_name, w.file_size, w.mime_type, w.content_hash, w.is_late, w.late_by_seconds, w.text_status, w.text_error, w.uploaded_at`
+	workFrom    = `works w
+	JOIN students s ON s.id = w.student_id
+	JOIN tasks t ON t.id = w.task_id`
+)
+
+var workSortColumns = map[string]string{
+	SortByUploadedAt: "w.uploaded_at",
+	SortByStudent:    "s.name",
+	SortByTask:       "t.title",
+}
+
+func scanWork(row pgx.Row) (*Work, error) {
 	var w Work
+	var lateSeconds int64
+	if err := row.Scan(&w.ID, &w.StudentID, &w.Student, &w.TaskID, &w.Task, &w.Version, &w.FilePath, &w.FileName, &w.FileSize, &w.MimeType, &w.ContentHash, &w.IsLate, &lateSeconds, &w.TextStatus, &w.TextError, &w.UploadedAt); err != nil {
+		return nil, err
+	}
+	w.LateBy = time.Duration(lateSeconds) * time.Second
+	return &w, nil
+}
+
+func (r *Repository) GetWork(ctx context.Context, id int64) (*Work, error) {
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.id = $1 AND w.deleted_at IS NULL;`
 
-	if err := row.Scan(&w.ID, &w.Student, &w.Task, &w.FilePath, &w.UploadedAt); err != nil {
+	w, err := scanWork(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkNotFound
+		}
 		return nil, fmt.Errorf("get work: %w", err)
 	}
-	return &w, nil
+	return w, nil
+}
+
+// ListWorks returns up to filter.Limit works matching the filter, ordered by
+// the requested sort key with id as a tie-breaker. Pagination is keyset-based:
+// filter.After holds the sort key and id of the last row of the previous page.
+func (r *Repository) ListWorks(ctx context.Context, filter WorkFilter) ([]Work, error) {
+	conds := []string{"w.deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.StudentID != 0 {
+		conds = append(conds, "w.student_id = "+arg(filter.StudentID))
+	}
+	if filter.TaskID != 0 {
+		conds = append(conds, "w.task_id = "+arg(filter.TaskID))
+	}
+	if !filter.UploadedFrom.IsZero() {
+		conds = append(conds, "w.uploaded_at >= "+arg(filter.UploadedFrom))
+	}
+	if !filter.UploadedTo.IsZero() {
+		conds = append(conds, "w.uploaded_at < "+arg(filter.UploadedTo))
+	}
+
+	cmp, dir := ">", "ASC"
+	if filter.Sort.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	column, byColumn := workSortColumns[filter.Sort.Field]
+	if filter.After != nil {
+		if !byColumn {
+			conds = append(conds, "w.id "+cmp+" "+arg(filter.After.ID))
+		} else {
+			conds = append(conds, "("+column+", w.id) "+cmp+" ("+arg(filter.After.Value)+", "+arg(filter.After.ID)+")")
+		}
+	}
+
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE ` + strings.Join(conds, " AND ") + `
+	ORDER BY `
+	if byColumn {
+		query += column + " " + dir + ", "
+	}
+	query += "w.id " + dir + `
+	LIMIT ` + arg(filter.Limit) + `;`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list works: %w", err)
+	}
+	defer rows.Close()
+
+	var works []Work
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list works: %w", err)
+		}
+		works = append(works, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list works: %w", err)
+	}
+	return works, nil
+}
+
+// ReferencedContent returns those of the hashes that a work, withdrawn ones
+// included, or a template refers to.
+func (r *Repository) ReferencedContent(ctx context.Context, hashes []string) (map[string]bool, error) {
+	const query = `
+	SELECT content_hash FROM works WHERE content_hash = ANY($1)
+	UNION
+	SELECT content_hash FROM task_templates WHERE content_hash = ANY($1);`
+
+	rows, err := r.pool.Query(ctx, query, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("check content references: %w", err)
+	}
+	defer rows.Close()
+	used := map[string]bool{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("check content references: %w", err)
+		}
+		used[hash] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("check content references: %w", err)
+	}
+	return used, nil
+}
+
+func (r *Repository) FindIdenticalWorks(ctx context.Context, hash string, excludeID int64) ([]Work, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.content_hash = $1 AND w.id <> $2 AND w.deleted_at IS NULL
+	ORDER BY w.uploaded_at, w.id;`
+
+	rows, err := r.pool.Query(ctx, query, hash, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("find identical works: %w", err)
+	}
+	defer rows.Close()
+
+	var works []Work
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("find identical works: %w", err)
+		}
+		works = append(works, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("find identical works: %w", err)
+	}
+	return works, nil
+}
+
+// UpdateWork applies patch to a live work and records the changed fields in
+// works_audit within the same transaction.
+func (r *Repository) UpdateWork(ctx context.Context, id int64, patch WorkPatch, actor string) (*Work, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.id = $1 AND w.deleted_at IS NULL
+	FOR UPDATE OF w;`
+	work, err := scanWork(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkNotFound
+		}
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+
+	changes := map[string]FieldChange{}
+	studentID, taskID := work.StudentID, work.TaskID
+	if patch.StudentID != nil && *patch.StudentID != studentID {
+		changes["student_id"] = FieldChange{Old: strconv.FormatInt(studentID, 10), New: strconv.FormatInt(*patch.StudentID, 10)}
+		studentID = *patch.StudentID
+	}
+	if patch.TaskID != nil && *patch.TaskID != taskID {
+		changes["task_id"] = FieldChange{Old: strconv.FormatInt(taskID, 10), New: strconv.FormatInt(*patch.TaskID, 10)}
+		taskID = *patch.TaskID
+	}
+	if len(changes) == 0 {
+		return work, nil
+	}
+
+	// Moving a work to another student or task makes it the newest version
+	// of that submission.
+	version, err := nextVersion(ctx, tx, studentID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	changes["version"] = FieldChange{Old: strconv.Itoa(work.Version), New: strconv.Itoa(version)}
+
+	// Lateness is judged against the deadline of the task the work belongs to.
+	isLate, lateBy := work.IsLate, work.LateBy
+	if taskID != work.TaskID {
+		task, err := scanTask(tx.QueryRow(ctx, `
+		SELECT `+taskColumns+` FROM tasks WHERE id = $1;`, taskID))
+		if err != nil {
+			return nil, fmt.Errorf("update work: %w", err)
+		}
+		if isLate, lateBy = task.Lateness(work.UploadedAt); isLate != work.IsLate {
+			changes["is_late"] = FieldChange{Old: strconv.FormatBool(work.IsLate), New: strconv.FormatBool(isLate)}
+		}
+	}
+
+	const update = `
+	UPDATE works SET student_id = $2, task_id = $3, version = $4, is_late = $5, late_by_seconds = $6 WHERE id = $1;`
+	if _, err := tx.Exec(ctx, update, id, studentID, taskID, version, isLate, int64(lateBy/time.Second)); err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	if err := insertAudit(ctx, tx, id, AuditActionUpdate, actor, changes); err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	reload := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.id = $1;`
+	if work, err = scanWork(tx.QueryRow(ctx, reload, id)); err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("update work: %w", err)
+	}
+	return work, nil
+}
+
+// DeleteWork soft-deletes a work by setting deleted_at and records it in
+// works_audit. The row and its blob are kept.
+func (r *Repository) DeleteWork(ctx context.Context, id int64, actor string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("delete work: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const query = `
+	UPDATE works SET deleted_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL;`
+	tag, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete work: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkNotFound
+	}
+	if err := insertAudit(ctx, tx, id, AuditActionDelete, actor, map[string]FieldChange{}); err != nil {
+		return fmt.Errorf("delete work: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("delete work: %w", err)
+	}
+	return nil
+}
+
+func insertAudit(ctx context.Context, tx pgx.Tx, workID int64, action, actor string, changes map[string]FieldChange) error {
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	const query = `
+	INSERT INTO works_audit (work_id, action, actor, changes)
+	VALUES ($1, $2, $3, $4);`
+	_, err = tx.Exec(ctx, query, workID, action, actor, raw)
+	return err
+}
+
+func (r *Repository) ListWorkAudit(ctx context.Context, workID int64) ([]AuditEntry, error) {
+	const query = `
+	SELECT id, work_id, action, actor, changes, created_at
+	FROM works_audit
+	WHERE work_id = $1
+	ORDER BY created_at, id;`
+
+	rows, err := r.pool.Query(ctx, query, workID)
+	if err != nil {
+		return nil, fmt.Errorf("list work audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.WorkID, &e.Action, &e.Actor, &e.Changes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list work audit: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list work audit: %w", err)
+	}
+	return entries, nil
+}
+
+// ListVersions returns the live versions of the submission identified by
+// student and task, oldest first.
+func (r *Repository) ListVersions(ctx context.Context, studentID, taskID int64) ([]Work, error) {
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.student_id = $1 AND w.task_id = $2 AND w.deleted_at IS NULL
+	ORDER BY w.version;`
+
+	rows, err := r.pool.Query(ctx, query, studentID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("list versions: %w", err)
+	}
+	defer rows.Close()
+
+	var works []Work
+	for rows.Next() {
+		w, err := scanWork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list versions: %w", err)
+		}
+		works = append(works, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list versions: %w", err)
+	}
+	return works, nil
+}
+
+func (r *Repository) GetLatestVersion(ctx context.Context, studentID, taskID int64) (*Work, error) {
+	query := `
+	SELECT ` + workColumns + ` FROM ` + workFrom + `
+	WHERE w.student_id = $1 AND w.task_id = $2 AND w.deleted_at IS NULL
+	ORDER BY w.version DESC
+	LIMIT 1;`
+
+	w, err := scanWork(r.pool.QueryRow(ctx, query, studentID, taskID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkNotFound
+		}
+		return nil, fmt.Errorf("get latest version: %w", err)
+	}
+	return w, nil
+}
+
+// SaveWorkText records the outcome of text extraction for a work. The text
+// itself is kept in work_texts so listings do not have to carry it.
+func (r *Repository) SaveWorkText(ctx context.Context, text *WorkText) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("save work text: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const update = `
+	UPDATE works SET text_status = $2, text_error = $3 WHERE id = $1;`
+	if _, err := tx.Exec(ctx, update, text.WorkID, text.Status, text.Error); err != nil {
+		return fmt.Errorf("save work text: %w", err)
+	}
+	const upsert = `
+	INSERT INTO work_texts (work_id, content, hidden_content, extracted_at)
+	VALUES ($1, $2, $3, NOW())
+	ON CONFLICT (work_id) DO UPDATE
+	SET content = EXCLUDED.content, hidden_content = EXCLUDED.hidden_content, extracted_at = EXCLUDED.extracted_at
+	RETURNING extracted_at;`
+	var extractedAt time.Time
+	if err := tx.QueryRow(ctx, upsert, text.WorkID, text.Text, text.Hidden).Scan(&extractedAt); err != nil {
+		return fmt.Errorf("save work text: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("save work text: %w", err)
+	}
+	text.ExtractedAt = &extractedAt
+	return nil
+}
+
+func (r *Repository) GetWorkText(ctx context.Context, workID int64) (*WorkText, error) {
+	const query = `
+	SELECT w.id, w.text_status, w.text_error, COALESCE(x.content, ''), COALESCE(x.hidden_content, ''), x.extracted_at
+	FROM works w
+	LEFT JOIN work_texts x ON x.work_id = w.id
+	WHERE w.id = $1 AND w.deleted_at IS NULL;`
+
+	var text WorkText
+	err := r.pool.QueryRow(ctx, query, workID).Scan(&text.WorkID, &text.Status, &text.Error, &text.Text, &text.Hidden, &text.ExtractedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkNotFound
+		}
+		return nil, fmt.Errorf("get work text: %w", err)
+	}
+	return &text, nil
 }
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrStudentNotFound = errors.New("student not found")
+	ErrConflict        = errors.New("conflict")
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type Student struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type studentRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+type studentResponse struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newStudentResponse(s *Student) *studentResponse {
+	return &studentResponse{
+		ID:        s.ID,
+		Name:      s.Name,
+		Email:     s.Email,
+		CreatedAt: s.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// pgError maps constraint violations to ErrConflict so handlers can answer
+// 409 instead of 500.
+func pgError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == pgUniqueViolation || pgErr.Code == pgForeignKeyViolation) {
+		return fmt.Errorf("%s: %w: %s", op, ErrConflict, pgErr.Message)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+func (r *Repository) CreateStudent(ctx context.Context, s *Student) error {
+	const query = `
+	INSERT INTO students (name, email)
+	VALUES ($1, $2)
+	RETURNING id, created_at;`
+
+	if err := r.pool.QueryRow(ctx, query, s.Name, s.Email).Scan(&s.ID, &s.CreatedAt); err != nil {
+		return pgError("create student", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetStudent(ctx context.Context, id int64) (*Student, error) {
+	const query = `
+	SELECT id, name, email, created_at FROM students WHERE id = $1;`
+
+	var s Student
+	if err := r.pool.QueryRow(ctx, query, id).Scan(&s.ID, &s.Name, &s.Email, &s.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStudentNotFound
+		}
+		return nil, fmt.Errorf("get student: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *Repository) ListStudents(ctx context.Context, search string) ([]Student, error) {
+	const query = `
+	SELECT id, name, email, created_at FROM students
+	WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%'
+	ORDER BY name, id;`
+
+	rows, err := r.pool.Query(ctx, query, search)
+	if err != nil {
+		return nil, fmt.Errorf("list students: %w", err)
+	}
+	defer rows.Close()
+
+	var students []Student
+	for rows.Next() {
+		var s Student
+		if err := rows.Scan(&s.ID, &s.Name, &s.Email, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list students: %w", err)
+		}
+		students = append(students, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list students: %w", err)
+	}
+	return students, nil
+}
+
+func (r *Repository) UpdateStudent(ctx context.Context, s *Student) error {
+	const query = `
+	UPDATE students SET name = $2, email = $3 WHERE id = $1;`
+
+	tag, err := r.pool.Exec(ctx, query, s.ID, s.Name, s.Email)
+	if err != nil {
+		return pgError("update student", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStudentNotFound
+	}
+	return nil
+}
+
+// DeleteStudent removes a student that has no works. Students referenced by
+// works (including soft-deleted ones) are rejected with ErrConflict.
+func (r *Repository) DeleteStudent(ctx context.Context, id int64) error {
+	const query = `
+	DELETE FROM students WHERE id = $1;`
+
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return pgError("delete student", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStudentNotFound
+	}
+	return nil
+}
+
+func (h *Handler) CreateStudent(w http.ResponseWriter, r *http.Request) {
+	var req studentRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		slog.Error("failed to decode request", "err", err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	s := &Student{}
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Email != nil {
+		s.Email = strings.TrimSpace(*req.Email)
+	}
+	if s.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CreateStudent(r.Context(), s); err != nil {
+		writeEntityError(w, "failed to create student", err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, newStudentResponse(s))
+}
+
+func (h *Handler) ListStudents(w http.ResponseWriter, r *http.Request) {
+	students, err := h.repo.ListStudents(r.Context(), strings.TrimSpace(r.URL.Query().Get("q")))
+	if err != nil {
+		slog.Error("failed to list students", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	response := make([]*studentResponse, 0, len(students))
+	for i := range students {
+		response = append(response, newStudentResponse(&students[i]))
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	s, err := h.repo.GetStudent(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, "failed to get student", err)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newStudentResponse(s))
+}
+
+func (h *Handler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req studentRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		slog.Error("failed to decode request", "err", err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	s, err := h.repo.GetStudent(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, "failed to get student", err)
+		return
+	}
+	if req.Name != nil {
+		if s.Name = strings.TrimSpace(*req.Name); s.Name == "" {
+			http.Error(w, "name must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Email != nil {
+		s.Email = strings.TrimSpace(*req.Email)
+	}
+	if err := h.repo.UpdateStudent(r.Context(), s); err != nil {
+		writeEntityError(w, "failed to update student", err)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newStudentResponse(s))
+}
+
+func (h *Handler) DeleteStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.DeleteStudent(r.Context(), id); err != nil {
+		writeEntityError(w, "failed to delete student", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeEntityError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrStudentNotFound), errors.Is(err, ErrTaskNotFound):
+		http.Error(w, "Not Found", http.StatusNotFound)
+	case errors.Is(err, ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error(msg, "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
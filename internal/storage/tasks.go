@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrTaskNotFound = errors.New("task not found")
+
+// Task is an assignment. Deadline, when set, is a wall-clock time in
+// Timezone; submissions within GracePeriod after it are not late.
+type Task struct {
+	ID          int64         `json:"id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Deadline    *time.Time    `json:"deadline"`
+	GracePeriod time.Duration `json:"grace_period"`
+	Timezone    string        `json:"timezone"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type taskRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Deadline    *string `json:"deadline"`
+	GracePeriod *string `json:"grace_period"`
+	Timezone    *string `json:"timezone"`
+}
+
+type taskResponse struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Deadline    string `json:"deadline"`
+	DeadlineAt  string `json:"deadline_at"`
+	GracePeriod string `json:"grace_period"`
+	Timezone    string `json:"timezone"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func newTaskResponse(t *Task) *taskResponse {
+	response := &taskResponse{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		GracePeriod: t.GracePeriod.String(),
+		Timezone:    t.Timezone,
+		CreatedAt:   t.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if deadline, ok := t.DeadlineAt(); ok {
+		response.Deadline = t.Deadline.Format(deadlineLayout)
+		response.DeadlineAt = deadline.Format(time.RFC3339)
+	}
+	return response
+}
+
+// apply copies the fields present in the request onto t. An empty deadline
+// removes it.
+func (req *taskRequest) apply(t *Task) error {
+	if req.Title != nil {
+		if t.Title = strings.TrimSpace(*req.Title); t.Title == "" {
+			return errors.New("title must not be empty")
+		}
+	}
+	if req.Description != nil {
+		t.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Timezone != nil {
+		tz := strings.TrimSpace(*req.Timezone)
+		if tz == "" {
+			tz = "UTC"
+		}
+		if _, err := time.LoadLocation(tz); err != nil {
+			return errors.New("unknown timezone")
+		}
+		t.Timezone = tz
+	}
+	if req.GracePeriod != nil {
+		grace, err := time.ParseDuration(strings.TrimSpace(*req.GracePeriod))
+		if err != nil || grace < 0 {
+			return errors.New("grace_period must be a non-negative duration such as 15m or 24h")
+		}
+		t.GracePeriod = grace
+	}
+	if req.Deadline != nil {
+		if strings.TrimSpace(*req.Deadline) == "" {
+			t.Deadline = nil
+		} else {
+			deadline, err := parseDeadline(*req.Deadline, t.Location())
+			if err != nil {
+				return err
+			}
+			t.Deadline = &deadline
+		}
+	}
+	return nil
+}
+
+const taskColumns = `id, title, description, deadline, grace_period_seconds, timezone, created_at`
+
+func scanTask(row pgx.Row) (*Task, error) {
+	var t Task
+	var graceSeconds int64
+	if err := row.Scan(&t.ID, &t.Title, &t.Description, &t.Deadline, &graceSeconds, &t.Timezone, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.GracePeriod = time.Duration(graceSeconds) * time.Second
+	return &t, nil
+}
+
+func (r *Repository) CreateTask(ctx context.Context, t *Task) error {
+	if t.Timezone == "" {
+		t.Timezone = "UTC"
+	}
+	const query = `
+	INSERT INTO tasks (title, description, deadline, grace_period_seconds, timezone)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at;`
+
+	row := r.pool.QueryRow(ctx, query, t.Title, t.Description, t.Deadline, int64(t.GracePeriod/time.Second), t.Timezone)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return pgError("create task", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetTask(ctx context.Context, id int64) (*Task, error) {
+	query := `
+	SELECT ` + taskColumns + ` FROM tasks WHERE id = $1;`
+
+	t, err := scanTask(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	return t, nil
+}
+
+func (r *Repository) ListTasks(ctx context.Context, search string) ([]Task, error) {
+	query := `
+	SELECT ` + taskColumns + ` FROM tasks
+	WHERE $1 = '' OR title ILIKE '%' || $1 || '%'
+	ORDER BY title, id;`
+
+	rows, err := r.pool.Query(ctx, query, search)
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list tasks: %w", err)
+		}
+		tasks = append(tasks, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func (r *Repository) UpdateTask(ctx context.Context, t *Task) error {
+	const query = `
+	UPDATE tasks
+	SET title = $2, description = $3, deadline = $4, grace_period_seconds = $5, timezone = $6
+	WHERE id = $1;`
+
+	tag, err := r.pool.Exec(ctx, query, t.ID, t.Title, t.Description, t.Deadline, int64(t.GracePeriod/time.Second), t.Timezone)
+	if err != nil {
+		return pgError("update task", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// DeleteTask removes a task that has no works. Tasks referenced by works
+// (including soft-deleted ones) are rejected with ErrConflict.
+func (r *Repository) DeleteTask(ctx context.Context, id int64) error {
+	const query = `
+	DELETE FROM tasks WHERE id = $1;`
+
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return pgError("delete task", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		slog.Error("failed to decode request", "err", err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Title == nil {
+		http.Error(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	t := &Task{Timezone: "UTC"}
+	if err := req.apply(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.CreateTask(r.Context(), t); err != nil {
+		writeEntityError(w, "failed to create task", err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, newTaskResponse(t))
+}
+
+func (h *Handler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.repo.ListTasks(r.Context(), strings.TrimSpace(r.URL.Query().Get("q")))
+	if err != nil {
+		slog.Error("failed to list tasks", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	response := make([]*taskResponse, 0, len(tasks))
+	for i := range tasks {
+		response = append(response, newTaskResponse(&tasks[i]))
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	t, err := h.repo.GetTask(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, "failed to get task", err)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newTaskResponse(t))
+}
+
+func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req taskRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		slog.Error("failed to decode request", "err", err)
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	t, err := h.repo.GetTask(r.Context(), id)
+	if err != nil {
+		writeEntityError(w, "failed to get task", err)
+		return
+	}
+	if err := req.apply(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.UpdateTask(r.Context(), t); err != nil {
+		writeEntityError(w, "failed to update task", err)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newTaskResponse(t))
+}
+
+func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.DeleteTask(r.Context(), id); err != nil {
+		writeEntityError(w, "failed to delete task", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
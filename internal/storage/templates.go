@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Template is a file a teacher hands out with a task, such as skeleton code
+// or the assignment text. Every submission of the task contains it, so the
+// analysis service excludes its content before comparing works.
+type Template struct {
+	ID          int64
+	TaskID      int64
+	FileName    string
+	FileSize    int64
+	MimeType    string
+	ContentHash string
+	TextStatus  string
+	TextError   string
+	Text        string
+	CreatedAt   time.Time
+}
+
+type templateResponse struct {
+	ID         int64  `json:"id"`
+	TaskID     int64  `json:"task_id"`
+	FileName   string `json:"file_name"`
+	FileSize   int64  `json:"file_size"`
+	MimeType   string `json:"mime_type"`
+	TextStatus string `json:"text_status"`
+	TextError  string `json:"text_error"`
+	Chars      int    `json:"chars"`
+	Text       string `json:"text"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func newTemplateResponse(t *Template) *templateResponse {
+	return &templateResponse{
+		ID:         t.ID,
+		TaskID:     t.TaskID,
+		FileName:   t.FileName,
+		FileSize:   t.FileSize,
+		MimeType:   t.MimeType,
+		TextStatus: t.TextStatus,
+		TextError:  t.TextError,
+		Chars:      utf8.RuneCountInString(t.Text),
+		Text:       t.Text,
+		CreatedAt:  t.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func (r *Repository) CreateTemplate(ctx context.Context, t *Template) error {
+	const query = `
+	INSERT INTO task_templates (task_id, file_name, file_size, mime_type, content_hash, text_status, text_error, content)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at;`
+
+	row := r.pool.QueryRow(ctx, query, t.TaskID, t.FileName, t.FileSize, t.MimeType, t.ContentHash, t.TextStatus, t.TextError, t.Text)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return pgError("create template", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListTemplates(ctx context.Context, taskID int64) ([]Template, error) {
+	const query = `
+	SELECT id, task_id, file_name, file_size, mime_type, content_hash, text_status, text_error, content, created_at
+	FROM task_templates
+	WHERE task_id = $1
+	ORDER BY id;`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		var t Template
+		if err := rows.Scan(&t.ID, &t.TaskID, &t.FileName, &t.FileSize, &t.MimeType, &t.ContentHash,
+			&t.TextStatus, &t.TextError, &t.Text, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list templates: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (r *Repository) DeleteTemplate(ctx context.Context, taskID, id int64) error {
+	const query = `
+	DELETE FROM task_templates WHERE id = $1 AND task_id = $2;`
+
+	tag, err := r.pool.Exec(ctx, query, id, taskID)
+	if err != nil {
+		return fmt.Errorf("delete template: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// UploadTemplates stores every file of a multipart request as a template of
+// the task. Text is extracted right away; a file whose text cannot be
+// extracted is kept with the failure, as works are.
+func (h *Handler) UploadTemplates(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if !isMultipart(r) {
+		http.Error(w, "multipart/form-data with one or more file parts is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.repo.GetTask(r.Context(), taskID); err != nil {
+		writeEntityError(w, "failed to get task", err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "invalid multipart request", http.StatusBadRequest)
+		return
+	}
+	response := []*templateResponse{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.Error("failed to read multipart part", "err", err)
+			http.Error(w, "invalid multipart request", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			_ = part.Close()
+			continue
+		}
+		file, err := h.saveUpload(r.Context(), part)
+		_ = part.Close()
+		if err != nil {
+			slog.Error("failed to save upload", "err", err)
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		t := &Template{
+			TaskID:      taskID,
+			FileName:    file.Name,
+			FileSize:    file.Size,
+			MimeType:    file.MimeType,
+			ContentHash: file.ContentHash,
+			TextStatus:  TextStatusDone,
+		}
+		if text, err := h.readText(r.Context(), file.ContentHash, file.MimeType, file.Name); err != nil {
+			slog.Warn("template text extraction failed", "task_id", taskID, "file", file.Name, "err", err)
+			t.TextStatus, t.TextError = TextStatusFailed, err.Error()
+		} else {
+			t.Text = text.Text
+		}
+		if err := h.repo.CreateTemplate(r.Context(), t); err != nil {
+			writeEntityError(w, "failed to create template", err)
+			return
+		}
+		response = append(response, newTemplateResponse(t))
+	}
+	if len(response) == 0 {
+		http.Error(w, "at least one file is required", http.StatusBadRequest)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.repo.GetTask(r.Context(), taskID); err != nil {
+		writeEntityError(w, "failed to get task", err)
+		return
+	}
+	templates, err := h.repo.ListTemplates(r.Context(), taskID)
+	if err != nil {
+		slog.Error("failed to list templates", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	response := make([]*templateResponse, 0, len(templates))
+	for i := range templates {
+		response = append(response, newTemplateResponse(&templates[i]))
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "template_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid template_id", http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.DeleteTemplate(r.Context(), taskID, id); err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to delete template", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const noFileError = "work has no uploaded file"
+
+type workTextResponse struct {
+	WorkID      int64  `json:"work_id"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	Chars       int    `json:"chars"`
+	Text        string `json:"text"`
+	HiddenChars int    `json:"hidden_chars"`
+	HiddenText  string `json:"hidden_text"`
+	ExtractedAt string `json:"extracted_at"`
+}
+
+func newWorkTextResponse(text *WorkText) *workTextResponse {
+	response := &workTextResponse{
+		WorkID:      text.WorkID,
+		Status:      text.Status,
+		Error:       text.Error,
+		Chars:       utf8.RuneCountInString(text.Text),
+		Text:        text.Text,
+		HiddenChars: utf8.RuneCountInString(text.Hidden),
+		HiddenText:  text.Hidden,
+	}
+	if text.ExtractedAt != nil {
+		response.ExtractedAt = text.ExtractedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+// extractWorkText runs the extraction stage for a stored work and records
+// the result. Failures are stored as the work's text status, never returned,
+// so an unreadable file does not undo the upload. An extraction cut short by
+// ctx records nothing, so a pending work stays pending.
+func (h *Handler) extractWorkText(ctx context.Context, work *Work) *WorkText {
+	result := &WorkText{WorkID: work.ID, Status: TextStatusDone}
+	if work.ContentHash == "" {
+		result.Status, result.Error = TextStatusSkipped, noFileError
+	} else if text, err := h.readWorkText(ctx, work); ctx.Err() != nil {
+		return &WorkText{WorkID: work.ID, Status: work.TextStatus, Error: work.TextError}
+	} else if err != nil {
+		slog.Warn("text extraction failed", "work_id", work.ID, "err", err)
+		result.Status, result.Error = TextStatusFailed, err.Error()
+	} else {
+		result.Text, result.Hidden = text.Text, text.Hidden
+	}
+
+	if err := h.repo.SaveWorkText(ctx, result); err != nil {
+		slog.Error("failed to save work text", "work_id", work.ID, "err", err)
+		return result
+	}
+	work.TextStatus, work.TextError = result.Status, result.Error
+	return result
+}
+
+// readWorkText extracts the text of a work's file. A panic in an extractor
+// fails this work only: extraction runs in the background worker, and one
+// malformed upload must not take the storage service down with it.
+func (h *Handler) readWorkText(ctx context.Context, work *Work) (text *Extracted, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("text extraction panicked", "work_id", work.ID, "panic", p, "stack", string(debug.Stack()))
+			text, err = nil, fmt.Errorf("extract text: %v", p)
+		}
+	}()
+	return h.readText(ctx, work.ContentHash, work.MimeType, work.FileName)
+}
+
+// readText extracts the text of a stored file.
+func (h *Handler) readText(ctx context.Context, contentHash, mimeType, fileName string) (*Extracted, error) {
+	f, err := h.blobs.Get(ctx, ContentKey(contentHash))
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return ExtractText(data, mimeType, fileName)
+}
+
+func (h *Handler) GetWorkText(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	text, err := h.repo.GetWorkText(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get work text", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newWorkTextResponse(text))
+}
+
+// ExtractWorkText re-runs extraction, e.g. for works stored before the
+// extraction stage existed.
+func (h *Handler) ExtractWorkText(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	work, err := h.repo.GetWork(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get work", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	text := h.extractWorkText(r.Context(), work)
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newWorkTextResponse(text))
+}
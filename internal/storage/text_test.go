@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestReadWorkTextRecoversFromPanic(t *testing.T) {
+	ctx := context.Background()
+	blobs := NewLocalBlobStore(t.TempDir())
+	hash, _, err := PutContent(ctx, blobs, bytes.NewReader([]byte("plain text")))
+	if err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	saved := extractors[FormatText]
+	extractors[FormatText] = func([]byte) (Extracted, error) { panic("malformed file") }
+	t.Cleanup(func() { extractors[FormatText] = saved })
+
+	h := &Handler{blobs: blobs}
+	text, err := h.readWorkText(ctx, &Work{ID: 1, ContentHash: hash, FileName: "work.txt"})
+	if err == nil {
+		t.Fatalf("readWorkText = %+v, want an error", text)
+	}
+	if text != nil {
+		t.Errorf("readWorkText returned text %+v with the error", text)
+	}
+}
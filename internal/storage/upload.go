@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	maxUploadSize    = 64 << 20
+	maxFormValueSize = 1 << 10
+)
+
+type uploadedFile struct {
+	Path        string
+	Name        string
+	Size        int64
+	MimeType    string
+	ContentHash string
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+func (h *Handler) uploadWork(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "invalid multipart request", http.StatusBadRequest)
+		return
+	}
+
+	var studentID, taskID int64
+	var file *uploadedFile
+	var work *Work
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.Error("failed to read multipart part", "err", err)
+			http.Error(w, "invalid multipart request", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "student_id", "task_id":
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+			if err != nil {
+				http.Error(w, "invalid multipart request", http.StatusBadRequest)
+				return
+			}
+			id, err := strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+			if err != nil {
+				http.Error(w, "invalid "+part.FormName(), http.StatusBadRequest)
+				return
+			}
+			if part.FormName() == "student_id" {
+				studentID = id
+			} else {
+				taskID = id
+			}
+		case "file":
+			if file != nil {
+				http.Error(w, "only one file is allowed", http.StatusBadRequest)
+				return
+			}
+			// When the ids come first the submission is checked before the
+			// file is streamed to the blob store, so a rejected upload never
+			// stores anything.
+			if studentID > 0 && taskID > 0 {
+				work = &Work{StudentID: studentID, TaskID: taskID}
+				if !h.resolveSubmission(w, r, work) {
+					return
+				}
+			}
+			file, err = h.saveUpload(r.Context(), part)
+			if err != nil {
+				slog.Error("failed to save upload", "err", err)
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		_ = part.Close()
+	}
+
+	if studentID <= 0 || taskID <= 0 || file == nil {
+		http.Error(w, "student_id, task_id and file are required", http.StatusBadRequest)
+		return
+	}
+
+	if work == nil || work.StudentID != studentID || work.TaskID != taskID {
+		work = &Work{StudentID: studentID, TaskID: taskID}
+		if !h.resolveSubmission(w, r, work) {
+			return
+		}
+	}
+	work.FilePath, work.FileName, work.FileSize = file.Path, file.Name, file.Size
+	work.MimeType, work.ContentHash = file.MimeType, file.ContentHash
+	if err := h.repo.CreateWork(r.Context(), work); err != nil {
+		slog.Error("failed to create work", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.queueExtraction()
+	identical, err := h.repo.FindIdenticalWorks(r.Context(), work.ContentHash, work.ID)
+	if err != nil {
+		slog.Warn("failed to find identical works", "err", err)
+	}
+	response := newWorkResponse(work)
+	response.IdenticalWorks = newIdenticalWorks(identical)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) saveUpload(ctx context.Context, part *multipart.Part) (*uploadedFile, error) {
+	name := part.FileName()
+	if name == "" {
+		name = "upload"
+	}
+	br := bufio.NewReaderSize(part, 512)
+	head, _ := br.Peek(512)
+	hash, size, err := PutContent(ctx, h.blobs, br)
+	if err != nil {
+		return nil, err
+	}
+	return &uploadedFile{
+		Path:        ContentKey(hash),
+		Name:        name,
+		Size:        size,
+		MimeType:    detectMimeType(part.Header.Get("Content-Type"), name, head),
+		ContentHash: hash,
+	}, nil
+}
+
+func detectMimeType(headerType, name string, head []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(headerType); err == nil && mediaType != "application/octet-stream" {
+		return mediaType
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); byExt != "" {
+		if mediaType, _, err := mime.ParseMediaType(byExt); err == nil {
+			return mediaType
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return mediaType
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type versionsResponse struct {
+	StudentID     int64           `json:"student_id"`
+	Student       string          `json:"student"`
+	TaskID        int64           `json:"task_id"`
+	Task          string          `json:"task"`
+	LatestVersion int             `json:"latest_version"`
+	Items         []*workResponse `json:"items"`
+}
+
+func (h *Handler) GetWorkVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	work, err := h.repo.GetWork(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get work", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	versions, err := h.repo.ListVersions(r.Context(), work.StudentID, work.TaskID)
+	if err != nil {
+		slog.Error("failed to list versions", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := &versionsResponse{
+		StudentID: work.StudentID,
+		Student:   work.Student,
+		TaskID:    work.TaskID,
+		Task:      work.Task,
+		Items:     make([]*workResponse, 0, len(versions)),
+	}
+	for i := range versions {
+		response.Items = append(response.Items, newWorkResponse(&versions[i]))
+		response.LatestVersion = versions[i].Version
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+func (h *Handler) GetLatestWork(w http.ResponseWriter, r *http.Request) {
+	studentID, err := strconv.ParseInt(r.URL.Query().Get("student_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "student_id is required", http.StatusBadRequest)
+		return
+	}
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "task_id is required", http.StatusBadRequest)
+		return
+	}
+	work, err := h.repo.GetLatestVersion(r.Context(), studentID, taskID)
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get latest version", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, newWorkResponse(work))
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Image formats of the word cloud.
+const (
+	WordCloudPNG = "png"
+	WordCloudSVG = "svg"
+)
+
+const (
+	wordCloudWidth    = 800
+	wordCloudHeight   = 500
+	wordCloudMaxWords = 100
+	wordCloudMinSize  = 12
+	wordCloudMaxSize  = 72
+
+	// wordCloudPadding keeps words this many pixels apart.
+	wordCloudPadding = 4
+
+	// minCloudWordLen leaves out short words, mostly particles and
+	// abbreviations the stopword lists do not cover.
+	minCloudWordLen = 3
+
+	// wordCloudVersion is part of the cache key; bump it when the layout or
+	// the drawing changes so cached images are not served any more.
+	wordCloudVersion = 1
+)
+
+var wordCloudColors = []color.RGBA{
+	{0x1f, 0x4e, 0x79, 0xff},
+	{0xc0, 0x39, 0x2b, 0xff},
+	{0x27, 0x8c, 0x5a, 0xff},
+	{0x8e, 0x44, 0xad, 0xff},
+	{0xd3, 0x7a, 0x0c, 0xff},
+	{0x2c, 0x3e, 0x50, 0xff},
+}
+
+// stopwords are the Russian and English function words left out of the
+// cloud.
+var stopwords = func() map[string]bool {
+	const list = `
+	а без более бы был была были было быть в вам вас весь во вот все всего всех вы где да даже для до
+	его ее её если есть еще ещё же за здесь и из или им их к как какой когда который которая которые
+	кто ли либо между меня мне много может можно мы на над нам нас не него нее неё нет ни них но ну
+	о об однако он она они оно от очень по под при про с со так также такой там те тем то того тоже
+	той только том ты у уже хотя чего чем что чтобы эта эти это этого этой этом этот я
+	является являются которого которой которых могут свой своей своих себя менее всё всей всем
+	каждый другой других
+	a about above after again against all also am an and any are as at be because been before being
+	below between both but by can could did do does doing down during each few for from further had
+	has have having he her here hers herself him himself his how i if in into is it its itself just
+	me more most my myself no nor not now of off on once only or other our ours ourselves out over own
+	same she should so some such than that the their theirs them themselves then there these they this
+	those through to too under until up very was we were what when where which while who whom why will
+	with would you your yours yourself yourselves`
+	words := map[string]bool{}
+	for _, w := range strings.Fields(list) {
+		words[w] = true
+	}
+	return words
+}()
+
+// WordCount is a word of a work's text and how often it occurs.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// WordFrequencies counts the words of text, lower-cased, without stopwords,
+// numbers and words shorter than minCloudWordLen letters. It returns at most
+// limit words, the most frequent first.
+func WordFrequencies(text string, limit int) []WordCount {
+	counts := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(w)) >= minCloudWordLen && !stopwords[w] {
+			counts[w]++
+		}
+	}
+	words := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		words = append(words, WordCount{Word: w, Count: c})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Word < words[j].Word
+	})
+	if len(words) > limit {
+		words = words[:limit]
+	}
+	return words
+}
+
+// placedWord is a word laid out in the cloud: its font size, the left end
+// of its baseline and its colour.
+type placedWord struct {
+	Word  string
+	Size  int
+	X, Y  int
+	Color color.RGBA
+}
+
+var cloudFont = func() *opentype.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(fmt.Sprintf("parse word cloud font: %v", err))
+	}
+	return f
+}()
+
+// cloudFaces caches font faces by size while one cloud is drawn.
+type cloudFaces map[int]font.Face
+
+func (c cloudFaces) face(size int) font.Face {
+	if f, ok := c[size]; ok {
+		return f
+	}
+	f, err := opentype.NewFace(cloudFont, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		// The options are fixed and the font is built in.
+		panic(fmt.Sprintf("word cloud font face: %v", err))
+	}
+	c[size] = f
+	return f
+}
+
+func (c cloudFaces) close() {
+	for _, f := range c {
+		_ = f.Close()
+	}
+}
+
+// layoutWordCloud places the words, most frequent first, on an Archimedean
+// spiral from the centre of the image at the first spot where they overlap
+// nothing placed before. Font sizes grow with the square root of the count.
+// Words that find no room are left out. The layout depends only on the
+// words, so the PNG and the SVG of a text show the same cloud.
+func layoutWordCloud(words []WordCount, faces cloudFaces) []placedWord {
+	if len(words) == 0 {
+		return nil
+	}
+	maxCount, minCount := words[0].Count, words[len(words)-1].Count
+	var placed []placedWord
+	var boxes []image.Rectangle
+	bounds := image.Rect(0, 0, wordCloudWidth, wordCloudHeight)
+	for i, w := range words {
+		scale := 1.0
+		if maxCount > minCount {
+			scale = math.Sqrt(float64(w.Count-minCount) / float64(maxCount-minCount))
+		}
+		size := wordCloudMinSize + int(math.Round(scale*(wordCloudMaxSize-wordCloudMinSize)))
+		face := faces.face(size)
+		width := font.MeasureString(face, w.Word).Ceil()
+		metrics := face.Metrics()
+		ascent, height := metrics.Ascent.Ceil(), (metrics.Ascent + metrics.Descent).Ceil()
+
+		for t := 0.0; ; t += 0.05 {
+			radius := 3 * t
+			if radius > wordCloudWidth {
+				break
+			}
+			cx := wordCloudWidth/2 + int(radius*math.Cos(t)*wordCloudWidth/wordCloudHeight)
+			cy := wordCloudHeight/2 + int(radius*math.Sin(t))
+			box := image.Rect(cx-width/2, cy-height/2, cx-width/2+width, cy-height/2+height)
+			if !box.In(bounds) || overlapsAnyBox(box.Inset(-wordCloudPadding), boxes) {
+				continue
+			}
+			boxes = append(boxes, box)
+			placed = append(placed, placedWord{Word: w.Word, Size: size, X: box.Min.X, Y: box.Min.Y + ascent,
+				Color: wordCloudColors[i%len(wordCloudColors)]})
+			break
+		}
+	}
+	return placed
+}
+
+func overlapsAnyBox(box image.Rectangle, boxes []image.Rectangle) bool {
+	for _, b := range boxes {
+		if box.Overlaps(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderWordCloud draws the cloud of the words as a PNG or an SVG image.
+func RenderWordCloud(words []WordCount, format string) ([]byte, error) {
+	faces := cloudFaces{}
+	defer faces.close()
+	placed := layoutWordCloud(words, faces)
+
+	var buf bytes.Buffer
+	switch format {
+	case WordCloudPNG:
+		img := image.NewRGBA(image.Rect(0, 0, wordCloudWidth, wordCloudHeight))
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+		for _, p := range placed {
+			d := font.Drawer{Dst: img, Src: image.NewUniform(p.Color), Face: faces.face(p.Size), Dot: fixed.P(p.X, p.Y)}
+			d.DrawString(p.Word)
+		}
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("encode png: %w", err)
+		}
+	case WordCloudSVG:
+		fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+			wordCloudWidth, wordCloudHeight, wordCloudWidth, wordCloudHeight)
+		buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/>` + "\n")
+		for _, p := range placed {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="Go, Arial, sans-serif" font-size="%d" fill="#%02x%02x%02x">`,
+				p.X, p.Y, p.Size, p.Color.R, p.Color.G, p.Color.B)
+			_ = xml.EscapeText(&buf, []byte(p.Word))
+			buf.WriteString("</text>\n")
+		}
+		buf.WriteString("</svg>\n")
+	default:
+		return nil, fmt.Errorf("unknown word cloud format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// wordCloudKey is the blob key of the cached cloud of a text. It follows the
+// text, so a re-extracted text gets a new cloud and works with the same text
+// share one.
+func wordCloudKey(text, format string) (string, string) {
+	sum := sha256.Sum256([]byte(strconv.Itoa(wordCloudVersion) + "\n" + text))
+	hash := hex.EncodeToString(sum[:])
+	return "wordclouds/" + ContentKey(hash) + "." + format, hash
+}
+
+// wordCloud returns the cached cloud of the text from the blob store, drawing
+// and storing it first if there is none. It returns nil if the text has no
+// words to draw.
+func (h *Handler) wordCloud(ctx context.Context, text, format string) ([]byte, error) {
+	key, _ := wordCloudKey(text, format)
+	f, err := h.blobs.Get(ctx, key)
+	if err == nil {
+		defer f.Close()
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(f); err != nil {
+			return nil, fmt.Errorf("read cached word cloud: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	if !errors.Is(err, ErrBlobNotFound) {
+		return nil, err
+	}
+
+	words := WordFrequencies(text, wordCloudMaxWords)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	data, err := RenderWordCloud(words, format)
+	if err != nil {
+		return nil, err
+	}
+	// A failed cache write only costs drawing the cloud again next time.
+	if err := h.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		slog.Warn("failed to cache word cloud", "key", key, "err", err)
+	}
+	return data, nil
+}
+
+func (h *Handler) GetWorkWordCloudPNG(w http.ResponseWriter, r *http.Request) {
+	h.serveWordCloud(w, r, WordCloudPNG)
+}
+
+func (h *Handler) GetWorkWordCloudSVG(w http.ResponseWriter, r *http.Request) {
+	h.serveWordCloud(w, r, WordCloudSVG)
+}
+
+// serveWordCloud answers with the word cloud of the work's extracted text.
+func (h *Handler) serveWordCloud(w http.ResponseWriter, r *http.Request, format string) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	text, err := h.repo.GetWorkText(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrWorkNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get work text", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if text.Status != TextStatusDone {
+		http.Error(w, "work text is not extracted: "+text.Status, http.StatusConflict)
+		return
+	}
+
+	data, err := h.wordCloud(r.Context(), text.Text, format)
+	if err != nil {
+		slog.Error("failed to get word cloud", "work_id", id, "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.Error(w, "work text has no words to draw", http.StatusNotFound)
+		return
+	}
+
+	_, hash := wordCloudKey(text.Text, format)
+	contentType := "image/png"
+	if format == WordCloudSVG {
+		contentType = "image/svg+xml"
+	}
+	var modTime time.Time
+	if text.ExtractedAt != nil {
+		modTime = *text.ExtractedAt
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(data))
+}
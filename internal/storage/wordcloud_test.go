@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordFrequencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []WordCount
+	}{
+		{name: "empty", text: "", limit: 10, want: []WordCount{}},
+		{name: "only stopwords and short words", text: "и в на the of to ok да", limit: 10, want: []WordCount{}},
+		{
+			name:  "counts are case-insensitive",
+			text:  "Анализ анализ АНАЛИЗ текста",
+			limit: 10,
+			want:  []WordCount{{"анализ", 3}, {"текста", 1}},
+		},
+		{
+			name:  "ties are ordered alphabetically",
+			text:  "beta alpha gamma alpha beta",
+			limit: 10,
+			want:  []WordCount{{"alpha", 2}, {"beta", 2}, {"gamma", 1}},
+		},
+		{
+			name:  "numbers and punctuation split words",
+			text:  "2024: код-ревью, v2beta 42 ёлка!",
+			limit: 10,
+			want:  []WordCount{{"beta", 1}, {"код", 1}, {"ревью", 1}, {"ёлка", 1}},
+		},
+		{
+			name:  "length counts letters, not bytes",
+			text:  "мир мир дом cat",
+			limit: 10,
+			want:  []WordCount{{"мир", 2}, {"cat", 1}, {"дом", 1}},
+		},
+		{
+			name:  "limit keeps the most frequent",
+			text:  "один два два три три три",
+			limit: 2,
+			want:  []WordCount{{"три", 3}, {"два", 2}},
+		},
+		{
+			name:  "zero limit",
+			text:  "слово слово",
+			limit: 0,
+			want:  []WordCount{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WordFrequencies(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordFrequencies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,63 @@
 package storage
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrWorkNotFound = errors.New("work not found")
+
+const (
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
 
 type Work struct {
-	ID         int64     `json:"id"`
-	Student    string    `json:"student"`
-	Task       string    `json:"task"`
-	FilePath   string    `json:"file_path"`
-	UploadedAt time.Time `json:"uploaded_at"`
+	ID          int64         `json:"id"`
+	StudentID   int64         `json:"student_id"`
+	Student     string        `json:"student"`
+	TaskID      int64         `json:"task_id"`
+	Task        string        `json:"task"`
+	Version     int           `json:"version"`
+	FilePath    string        `json:"file_path"`
+	FileName    string        `json:"file_name"`
+	FileSize    int64         `json:"file_size"`
+	MimeType    string        `json:"mime_type"`
+	ContentHash string        `json:"content_hash"`
+	IsLate      bool          `json:"is_late"`
+	LateBy      time.Duration `json:"late_by"`
+	TextStatus  string        `json:"text_status"`
+	TextError   string        `json:"text_error"`
+	UploadedAt  time.Time     `json:"uploaded_at"`
+}
+
+// WorkText is the plain text extracted from a work's file. Hidden is the
+// text the file keeps from the reader, see Extracted.
+type WorkText struct {
+	WorkID      int64      `json:"work_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+	Text        string     `json:"text"`
+	Hidden      string     `json:"hidden"`
+	ExtractedAt *time.Time `json:"extracted_at"`
+}
+
+type WorkPatch struct {
+	StudentID *int64
+	TaskID    *int64
+}
+
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	WorkID    int64           `json:"work_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
 }